@@ -0,0 +1,45 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+)
+
+// ApplyOne runs the migration process like MigrateWithResult for a single
+// pending migration, for staged rollouts of risky changes: only name is
+// validated, tested on the shadow database and applied, and the other
+// pending migrations stay pending. name is the migration's file name, with or
+// without extension.
+//
+// It fails for migrations that don't exist, are already applied or are
+// skipped. With DisallowOutOfOrder, name must be the first pending migration,
+// since the ones before it could not be applied afterwards.
+func (m *Migrator) ApplyOne(ctx context.Context, name string) (*Result, error) {
+	one := *m
+	one.applyOnly = name
+	return one.run(ctx, nil)
+}
+
+// selectApplyOnly narrows the pending migrations of run to the one ApplyOne
+// applies.
+func (m *Migrator) selectApplyOnly(run *runState) error {
+	migration, err := m.pendingFile(run, m.applyOnly)
+	if err != nil {
+		return err
+	}
+
+	for i, pending := range run.pending {
+		if pending != migration {
+			continue
+		}
+		if i > 0 && m.disallowOutOfOrder {
+			return fmt.Errorf("cannot apply %s before pending migration %s with DisallowOutOfOrder", migration.Name, run.pending[0].Name)
+		}
+		if len(run.pending) > 1 {
+			m.log.Infof("✓ Applying only %s, leaving %d migrations pending", migration.Name, len(run.pending)-1)
+		}
+		run.pending = run.pending[i : i+1]
+		return nil
+	}
+	return fmt.Errorf("migration %s is not pending", migration.Name)
+}
@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/dsn"
+	"github.com/hasirciogluhq/migrator/internal/output"
+)
+
+// BackupFunc takes a backup of the target database. It runs after shadow
+// testing passes and before anything is applied to production; an error
+// aborts the run.
+type BackupFunc func(ctx context.Context) error
+
+// PgDumpOptions configures the built-in pg_dump backup.
+type PgDumpOptions struct {
+	// DatabaseURL is the database to dump. If empty, falls back to the
+	// DATABASE_URL env var.
+	DatabaseURL string
+
+	// Dir is the directory backups are written to, created if needed.
+	Dir string
+
+	// Binary is the pg_dump executable. Defaults to "pg_dump" on PATH.
+	Binary string
+
+	// Args are extra pg_dump arguments, e.g. "--schema-only".
+	Args []string
+
+	// DatabaseURLProvider resolves the database to dump when the backup
+	// runs, taking precedence over DatabaseURL.
+	DatabaseURLProvider DatabaseURLProvider
+
+	// CredentialsProvider supplies the password, replacing any in
+	// DatabaseURL, as with Options.CredentialsProvider.
+	CredentialsProvider CredentialsProvider
+}
+
+// PgDump returns a BackupFunc that writes a custom-format pg_dump of the
+// database to Dir as <database>_<timestamp>.dump. The password is passed via
+// PGPASSWORD rather than on the command line.
+func PgDump(opts PgDumpOptions) BackupFunc {
+	return func(ctx context.Context) error {
+		databaseURL := opts.DatabaseURL
+		if opts.DatabaseURLProvider != nil {
+			resolved, err := opts.DatabaseURLProvider.DatabaseURL(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to resolve database URL: %w", err)
+			}
+			databaseURL = resolved
+		}
+		if databaseURL == "" {
+			databaseURL = os.Getenv("DATABASE_URL")
+		}
+		if databaseURL == "" {
+			return fmt.Errorf("pg_dump backup requires DatabaseURL or DATABASE_URL")
+		}
+
+		parsed, err := dsn.Parse(databaseURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse database URL: %w", err)
+		}
+
+		if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+
+		name := parsed.Database()
+		if name == "" {
+			name = "database"
+		}
+		file := filepath.Join(opts.Dir, fmt.Sprintf("%s_%s.dump", name, time.Now().UTC().Format("20060102T150405Z")))
+
+		binary := opts.Binary
+		if binary == "" {
+			binary = "pg_dump"
+		}
+
+		conn, password := parsed.WithoutPassword()
+		if opts.CredentialsProvider != nil {
+			if password, err = opts.CredentialsProvider(ctx); err != nil {
+				return fmt.Errorf("failed to get database credentials: %w", err)
+			}
+		}
+		args := append([]string{"--format=custom", "--file=" + file, "--dbname=" + conn.String()}, opts.Args...)
+		cmd := exec.CommandContext(ctx, binary, args...)
+		cmd.Env = os.Environ()
+		if password != "" {
+			cmd.Env = append(cmd.Env, "PGPASSWORD="+password)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			os.Remove(file)
+			return fmt.Errorf("pg_dump failed: %w: %s", err, parsed.RedactError(fmt.Errorf("%s", strings.TrimSpace(stderr.String()))))
+		}
+
+		output.FromContext(ctx).Infof("💾 Backup written to %s", file)
+		return nil
+	}
+}
+
+// backup runs the configured backup hook before production apply.
+func (m *Migrator) backup(ctx context.Context, pending int) error {
+	if m.backupHook == nil || pending == 0 {
+		return nil
+	}
+
+	m.log.Infof("💾 Taking backup before applying migrations...")
+	start := time.Now()
+	if err := m.backupHook(output.NewContext(ctx, m.log)); err != nil {
+		return fmt.Errorf("backup failed, nothing was applied: %w", err)
+	}
+
+	m.log.Infof("✓ Backup completed in %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,186 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// BlockingMode controls what happens when long-running transactions hold
+// locks on tables that pending migrations alter. DDL waiting for such a lock
+// queues every later query on the table behind it, so an idle-in-transaction
+// session can turn a brief ALTER TABLE into an outage.
+type BlockingMode int
+
+const (
+	// BlockingWarn reports blocking transactions but applies anyway. This is
+	// the default.
+	BlockingWarn BlockingMode = iota
+
+	// BlockingWait waits, up to BlockingWaitTimeout, for blocking
+	// transactions to finish before applying.
+	BlockingWait
+
+	// BlockingTerminate terminates blocking backends with
+	// pg_terminate_backend before applying. Their transactions are rolled
+	// back, so only opt in when the applications using the database retry.
+	BlockingTerminate
+
+	// BlockingOff disables the check.
+	BlockingOff
+)
+
+// BlockingQuery is a transaction, open longer than BlockingThreshold, that
+// holds a lock on a table a pending migration alters.
+type BlockingQuery struct {
+	PID             int           `json:"pid"`
+	User            string        `json:"user"`
+	ApplicationName string        `json:"application_name"`
+	State           string        `json:"state"`
+	TransactionAge  time.Duration `json:"transaction_age"`
+	Table           string        `json:"table"`
+
+	// Query is the backend's current or, when idle, last query.
+	Query string `json:"query"`
+}
+
+func (q BlockingQuery) String() string {
+	return fmt.Sprintf("pid %d (%s, %s) has held a lock on %s for %s: %s",
+		q.PID, q.User, q.State, q.Table, q.TransactionAge.Round(time.Second), q.Query)
+}
+
+// BlockingQueriesError is returned with BlockingWait when blocking
+// transactions are still open after BlockingWaitTimeout.
+type BlockingQueriesError struct {
+	Queries []BlockingQuery
+}
+
+func (e *BlockingQueriesError) Error() string {
+	pids := make([]string, len(e.Queries))
+	for i, q := range e.Queries {
+		pids[i] = fmt.Sprintf("%d", q.PID)
+	}
+	return fmt.Sprintf("%d long-running transactions hold locks on tables the pending migrations alter (pids %s)",
+		len(e.Queries), strings.Join(pids, ", "))
+}
+
+// alteredTables returns the tables that statements of migrations lock, as
+// normalized names.
+func alteredTables(migrations []*validator.MigrationFile) []string {
+	seen := map[string]bool{}
+	var tables []string
+	for _, impact := range analyzeMigrations(migrations, 0) {
+		for _, stmt := range impact.Statements {
+			if stmt.Table != "" && !seen[stmt.Table] {
+				seen[stmt.Table] = true
+				tables = append(tables, strings.ToLower(stmt.Table))
+			}
+		}
+	}
+	return tables
+}
+
+// findBlockingQueries returns the transactions open longer than the threshold
+// that hold locks on any of tables, oldest first.
+func (m *Migrator) findBlockingQueries(ctx context.Context, tables []string) ([]BlockingQuery, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT DISTINCT a.pid, COALESCE(a.usename, ''), COALESCE(a.application_name, ''),
+			COALESCE(a.state, ''), EXTRACT(EPOCH FROM now() - a.xact_start)::float8,
+			l.relation::regclass::text, LEFT(COALESCE(a.query, ''), $3)
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.relation IN (SELECT to_regclass(t) FROM unnest($1::text[]) AS t)
+			AND a.pid <> pg_backend_pid()
+			AND a.xact_start < now() - make_interval(secs => $2)
+		ORDER BY 5 DESC
+	`, pq.Array(tables), m.blockingThreshold.Seconds(), maxSnippetLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for blocking transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []BlockingQuery
+	for rows.Next() {
+		var q BlockingQuery
+		var ageSeconds float64
+		if err := rows.Scan(&q.PID, &q.User, &q.ApplicationName, &q.State, &ageSeconds, &q.Table, &q.Query); err != nil {
+			return nil, fmt.Errorf("failed to scan blocking transaction: %w", err)
+		}
+		q.TransactionAge = time.Duration(ageSeconds * float64(time.Second))
+		queries = append(queries, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocking transactions: %w", err)
+	}
+
+	return queries, nil
+}
+
+// checkBlockingQueries looks for long-running transactions holding locks on
+// tables the pending migrations alter, and warns about, waits for or
+// terminates them according to the BlockingMode.
+func (m *Migrator) checkBlockingQueries(ctx context.Context, migrations []*validator.MigrationFile, result *Result) error {
+	if m.blockingMode == BlockingOff || len(migrations) == 0 {
+		return nil
+	}
+
+	tables := alteredTables(migrations)
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var deadline time.Time
+	for {
+		queries, err := m.findBlockingQueries(ctx, tables)
+		if err != nil {
+			if m.blockingMode == BlockingWarn {
+				result.warnf("%v", err)
+				return nil
+			}
+			return err
+		}
+		if len(queries) == 0 {
+			return nil
+		}
+
+		switch m.blockingMode {
+		case BlockingWarn:
+			for _, q := range queries {
+				result.warnf("Blocking transaction: %s", q)
+			}
+			return nil
+
+		case BlockingTerminate:
+			for _, q := range queries {
+				if _, err := m.db.ExecContext(ctx, "SELECT pg_terminate_backend($1)", q.PID); err != nil {
+					return fmt.Errorf("failed to terminate blocking backend %d: %w", q.PID, err)
+				}
+				result.warnf("Terminated blocking transaction: %s", q)
+			}
+			return nil
+
+		case BlockingWait:
+			if deadline.IsZero() {
+				deadline = time.Now().Add(m.blockingWaitTimeout)
+			}
+			if time.Now().After(deadline) {
+				return &BlockingQueriesError{Queries: queries}
+			}
+
+			m.log.Infof("⏳ Waiting for %d long-running transactions to release locks...", len(queries))
+			for _, q := range queries {
+				m.log.Debugf("   %s", q)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for blocking transactions: %w", ctx.Err())
+			case <-time.After(m.waitInterval):
+			}
+		}
+	}
+}
@@ -0,0 +1,627 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// BlueGreenSwitch selects how a blue/green deployment makes the new schema
+// live.
+type BlueGreenSwitch int
+
+const (
+	// SwitchSearchPath replaces the live schema by the new one in the
+	// database's default search_path (ALTER DATABASE ... SET search_path),
+	// keeping its other entries. New sessions use it, while existing
+	// sessions keep using the old schema until they reconnect. The old
+	// schema stays in place for a rollback.
+	SwitchSearchPath BlueGreenSwitch = iota
+
+	// SwitchRename renames the live schema to BlueGreen.RetiredSchema and the
+	// new schema to the live name in one transaction, so every session uses
+	// the new schema from its next statement.
+	SwitchRename
+)
+
+// BlueGreen configures a blue/green schema deployment, see
+// Migrator.DeployBlueGreen.
+type BlueGreen struct {
+	// Schema is the live schema, e.g. "app".
+	Schema string
+
+	// NewSchema is the schema the deployment builds, e.g. "app_v2". It must
+	// not exist yet.
+	NewSchema string
+
+	// Assertions are queries run against the new schema, first in
+	// search_path, before switching. Each must return a true first value, as
+	// with Options.VerifyQueries.
+	Assertions []string
+
+	// Switch selects how the new schema goes live. Defaults to SwitchSearchPath.
+	Switch BlueGreenSwitch
+
+	// RetiredSchema is the name SwitchRename gives the old live schema.
+	// Defaults to Schema + "_old".
+	RetiredSchema string
+}
+
+// DeployBlueGreen applies pending migrations to a copy of the live schema
+// and switches to it once they succeeded, for breaking schema changes with
+// near-zero downtime:
+//
+//  1. NewSchema is created and every applied migration is replayed into it
+//  2. the data of the live schema's tables is copied over, and sequences are
+//     advanced to match
+//  3. the pending migrations are applied to NewSchema and the Assertions run
+//  4. in one transaction, the pending migrations are recorded and the new
+//     schema is made live as selected by Switch
+//
+// The checks of Migrate run first, validation, policy, confirmation and
+// backup included, and the deployment reports like a run of Migrate: to the
+// notifiers, OnProgress and OnError.
+//
+// Until the switch the live schema is untouched, and on failure NewSchema is
+// dropped again. Writes to the live schema after its data was copied are not
+// carried over, so pause writers for the duration of the deployment.
+// Migrations must create objects unqualified, through search_path, to be
+// replayed into NewSchema.
+//
+// The tracking tables must live outside Schema, which either switch takes
+// out of use. After a SwitchSearchPath deployment, Options.SearchPath still
+// names Schema: update it to NewSchema for later runs.
+func (m *Migrator) DeployBlueGreen(ctx context.Context, deploy BlueGreen) (*Result, error) {
+	return m.execute(ctx, func(ctx context.Context, result *Result) error {
+		if deploy.Schema == "" || deploy.NewSchema == "" {
+			return fmt.Errorf("blue/green deployment requires Schema and NewSchema")
+		}
+		if deploy.Schema == deploy.NewSchema {
+			return fmt.Errorf("blue/green deployment needs a NewSchema other than %s", deploy.Schema)
+		}
+		if deploy.RetiredSchema == "" {
+			deploy.RetiredSchema = deploy.Schema + "_old"
+		}
+
+		m.shadowMu.Lock()
+		defer m.shadowMu.Unlock()
+
+		run, release, err := m.pinSession(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return run.deployBlueGreen(ctx, deploy, result)
+	})
+}
+
+// deployBlueGreen runs a blue/green deployment on a pinned session.
+func (m *Migrator) deployBlueGreen(ctx context.Context, deploy BlueGreen, result *Result) error {
+	m.progress(result, ProgressEvent{Phase: PhasePreflight})
+
+	if err := m.checkDatabaseIdentity(ctx); err != nil {
+		return err
+	}
+	if err := m.checkStandby(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	run, err := m.loadRunState(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The checks of Migrate; building the new schema stands in for shadow
+	// testing
+	if err := m.preflight(ctx, run, nil, false, result); err != nil {
+		return err
+	}
+	if len(run.pending) == 0 {
+		m.log.Infof("✓ All migrations are already applied")
+		return nil
+	}
+	if err := m.checkBlueGreenSchemas(ctx, deploy); err != nil {
+		return err
+	}
+	if err := m.preApply(ctx, run.pending, result); err != nil {
+		return err
+	}
+
+	if err := m.tracker.EnsureProgressTable(ctx); err != nil {
+		return err
+	}
+	progress := tracker.Progress{Name: "blue-green " + deploy.NewSchema}
+	step := func(name string) error {
+		progress.Step = name
+		return m.tracker.SaveProgress(ctx, progress)
+	}
+
+	newSchema := pq.QuoteIdentifier(deploy.NewSchema)
+	if _, err := m.db.ExecContext(ctx, "CREATE SCHEMA "+newSchema); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", deploy.NewSchema, err)
+	}
+	m.log.Infof("🏗️  Created schema %s", deploy.NewSchema)
+
+	// Leave nothing half-built behind; the live schema is untouched until the switch
+	switched := false
+	defer func() {
+		if switched {
+			return
+		}
+		if _, err := m.db.ExecContext(context.WithoutCancel(ctx), "DROP SCHEMA IF EXISTS "+newSchema+" CASCADE"); err != nil {
+			result.warnf("Failed to drop schema %s: %v", deploy.NewSchema, err)
+		} else {
+			m.log.Infof("🗑️  Dropped schema %s", deploy.NewSchema)
+		}
+	}()
+
+	// Step 1: rebuild the live schema's structure
+	if err := step("replay"); err != nil {
+		return err
+	}
+	target := m.tracker.WithSearchPath(newSchema + ", public")
+	files := make(map[string]*validator.MigrationFile, len(run.files))
+	for _, file := range run.files {
+		files[file.Name] = file
+	}
+	for _, name := range run.appliedNames() {
+		migration, err := m.replayMigration(ctx, files[name], name)
+		if err != nil {
+			return err
+		}
+		if _, err := target.Replay(ctx, migration); err != nil {
+			return fmt.Errorf("failed to replay migration %s into %s: %w", name, deploy.NewSchema, err)
+		}
+	}
+	m.log.Infof("✓ Replayed %d applied migrations into %s", len(run.appliedNames()), deploy.NewSchema)
+
+	// Step 2: copy the data
+	if err := step("copy"); err != nil {
+		return err
+	}
+	if err := m.copySchemaData(ctx, deploy.Schema, deploy.NewSchema); err != nil {
+		return err
+	}
+
+	// Step 3: apply the pending migrations and check the result
+	if err := step("migrate"); err != nil {
+		return err
+	}
+	var pending []tracker.Migration
+	for i, file := range run.pending {
+		m.progress(result, ProgressEvent{Phase: PhaseApply, Migration: file.Name, Index: i + 1, Total: len(run.pending)})
+		migration, err := file.Migration(0)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		rowCounts, err := target.Replay(ctx, migration)
+		if err != nil {
+			err = &migrationError{name: file.Name, content: file.Content, err: statementFailure(file.Name, err)}
+			return fmt.Errorf("failed to apply migration %s to %s: %w", file.Name, deploy.NewSchema, err)
+		}
+		pending = append(pending, migration)
+		result.Applied = append(result.Applied, AppliedMigration{
+			Name:       file.Name,
+			DurationMS: time.Since(start).Milliseconds(),
+			RowCounts:  newRowCounts(rowCounts),
+		})
+		m.log.Infof("✓ Applied %s to %s", file.Name, deploy.NewSchema)
+	}
+
+	if err := step("verify"); err != nil {
+		return err
+	}
+	if err := m.runAssertions(ctx, deploy); err != nil {
+		return err
+	}
+
+	// Step 4: go live
+	if err := step("switch"); err != nil {
+		return err
+	}
+	batch, err := m.tracker.NextBatch(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		pending[i].Batch = batch
+	}
+
+	var statements []string
+	switch deploy.Switch {
+	case SwitchRename:
+		statements = []string{
+			fmt.Sprintf("ALTER SCHEMA %s RENAME TO %s", pq.QuoteIdentifier(deploy.Schema), pq.QuoteIdentifier(deploy.RetiredSchema)),
+			fmt.Sprintf("ALTER SCHEMA %s RENAME TO %s", newSchema, pq.QuoteIdentifier(deploy.Schema)),
+		}
+	default:
+		var database string
+		if err := m.db.QueryRowContext(ctx, "SELECT current_database()").Scan(&database); err != nil {
+			return fmt.Errorf("failed to get current database: %w", err)
+		}
+		path, err := m.databaseSearchPath(ctx, database)
+		if err != nil {
+			return err
+		}
+		path = switchSearchPath(path, deploy.Schema, deploy.NewSchema)
+		statements = []string{fmt.Sprintf("ALTER DATABASE %s SET search_path = %s", pq.QuoteIdentifier(database), path)}
+	}
+	if err := m.tracker.RecordWith(ctx, pending, statements); err != nil {
+		return fmt.Errorf("failed to switch to schema %s: %w", deploy.NewSchema, err)
+	}
+	switched = true
+	result.Batch = batch
+
+	progress.Step = "switched"
+	progress.Completed = true
+	if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+		result.warnf("%v", err)
+	}
+
+	if deploy.Switch == SwitchRename {
+		m.log.Infof("✅ Schema %s is live as %s; the previous schema is kept as %s", deploy.NewSchema, deploy.Schema, deploy.RetiredSchema)
+	} else {
+		m.log.Infof("✅ Schema %s is live for new sessions; %s is kept for rollback", deploy.NewSchema, deploy.Schema)
+	}
+	return nil
+}
+
+// replayMigration prepares an applied migration for replay into the new
+// schema from its file, falling back to the content stored in the tracking
+// table when the file is gone, like shadow testing.
+func (m *Migrator) replayMigration(ctx context.Context, file *validator.MigrationFile, name string) (tracker.Migration, error) {
+	if file != nil {
+		return file.Migration(0)
+	}
+
+	stored, ok, err := m.tracker.GetContent(ctx, name)
+	if err != nil {
+		return tracker.Migration{}, fmt.Errorf("failed to read stored content of migration %s: %w", name, err)
+	}
+	if !ok {
+		return tracker.Migration{}, fmt.Errorf("failed to read migration %s: file not found and no stored content", name)
+	}
+	m.log.Debugf("  📦 Using stored content for %s (file not found)", name)
+	content := validator.Normalize(stored)
+
+	copies, err := validator.Copies(m.migrationsPath, name, content)
+	if err != nil {
+		return tracker.Migration{}, fmt.Errorf("failed to load data files of migration %s: %w", name, err)
+	}
+	isolation, err := validator.Isolation(content)
+	if err != nil {
+		return tracker.Migration{}, fmt.Errorf("invalid migration %s: %w", name, err)
+	}
+
+	return tracker.Migration{
+		Name:          name,
+		Content:       content,
+		Copies:        copies,
+		Isolation:     isolation,
+		NoTransaction: validator.NoTransaction(content),
+	}, nil
+}
+
+// checkBlueGreenSchemas fails early when the schemas of a deployment can't be
+// used as configured.
+func (m *Migrator) checkBlueGreenSchemas(ctx context.Context, deploy BlueGreen) error {
+	exists := func(schema string) (bool, error) {
+		var found bool
+		err := m.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schema).Scan(&found)
+		if err != nil {
+			return false, fmt.Errorf("failed to check schema %s: %w", schema, err)
+		}
+		return found, nil
+	}
+
+	if found, err := exists(deploy.Schema); err != nil {
+		return err
+	} else if !found {
+		return fmt.Errorf("schema %s does not exist", deploy.Schema)
+	}
+	if found, err := exists(deploy.NewSchema); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("schema %s already exists; drop it or choose another NewSchema", deploy.NewSchema)
+	}
+
+	if deploy.Switch == SwitchRename {
+		if found, err := exists(deploy.RetiredSchema); err != nil {
+			return err
+		} else if found {
+			return fmt.Errorf("schema %s already exists; drop it or choose another RetiredSchema", deploy.RetiredSchema)
+		}
+	}
+
+	// Either switch takes the live schema out of use, and the tracking
+	// tables aren't copied
+	var tracked bool
+	err := m.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2
+		)
+	`, deploy.Schema, tracker.MigrationsTable).Scan(&tracked)
+	if err != nil {
+		return fmt.Errorf("failed to locate tracking table: %w", err)
+	}
+	if tracked {
+		return fmt.Errorf("the tracking tables live in schema %s, which the switch would take out of use; keep them in another schema", deploy.Schema)
+	}
+	return nil
+}
+
+// databaseSearchPath returns the default search_path of database: its own
+// setting if it has one, otherwise the server's.
+func (m *Migrator) databaseSearchPath(ctx context.Context, database string) (string, error) {
+	var path string
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COALESCE(
+			(SELECT substr(s.setting, length('search_path=') + 1)
+			 FROM pg_db_role_setting r JOIN pg_database d ON d.oid = r.setdatabase, unnest(r.setconfig) s(setting)
+			 WHERE d.datname = $1 AND r.setrole = 0 AND s.setting LIKE 'search\_path=%'),
+			(SELECT boot_val FROM pg_settings WHERE name = 'search_path')
+		)
+	`, database).Scan(&path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get search_path of database %s: %w", database, err)
+	}
+	return path, nil
+}
+
+// switchSearchPath returns path with schema from replaced by schema to, or
+// with to prepended if path doesn't list from.
+func switchSearchPath(path, from, to string) string {
+	var entries []string
+	replaced := false
+	for _, entry := range strings.Split(path, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name := strings.ToLower(entry)
+		if strings.HasPrefix(entry, `"`) {
+			name = strings.ReplaceAll(strings.Trim(entry, `"`), `""`, `"`)
+		}
+		if name == from {
+			entry, replaced = pq.QuoteIdentifier(to), true
+		}
+		entries = append(entries, entry)
+	}
+	if !replaced {
+		entries = append([]string{pq.QuoteIdentifier(to)}, entries...)
+	}
+	return strings.Join(entries, ", ")
+}
+
+// copySchemaData copies the rows of every table of schema from into the table
+// of the same name in schema to, in one transaction, using the columns both
+// tables have. Referenced tables are copied first. Sequences of to are then
+// advanced to the values of their counterparts in from.
+func (m *Migrator) copySchemaData(ctx context.Context, from, to string) error {
+	tables, err := m.copyOrder(ctx, to)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SET CONSTRAINTS ALL DEFERRED"); err != nil {
+		return fmt.Errorf("failed to defer constraints: %w", err)
+	}
+
+	copied := 0
+	for _, table := range tables {
+		columns, err := m.commonColumns(ctx, from, to, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		list := ""
+		for i, column := range columns {
+			if i > 0 {
+				list += ", "
+			}
+			list += pq.QuoteIdentifier(column)
+		}
+		query := fmt.Sprintf("INSERT INTO %s.%s (%s) OVERRIDING SYSTEM VALUE SELECT %s FROM %s.%s",
+			pq.QuoteIdentifier(to), pq.QuoteIdentifier(table), list, list, pq.QuoteIdentifier(from), pq.QuoteIdentifier(table))
+		res, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to copy table %s: %w", table, err)
+		}
+		rows, _ := res.RowsAffected()
+		m.log.Debugf("  📥 Copied %d rows of %s", rows, table)
+		copied++
+	}
+
+	// Sequences aren't copied with the rows; continue where the live ones are
+	_, err = tx.ExecContext(ctx, `
+		SELECT setval(format('%I.%I', $2::text, s.sequencename), s.last_value)
+		FROM pg_sequences s
+		WHERE s.schemaname = $1 AND s.last_value IS NOT NULL
+			AND to_regclass(format('%I.%I', $2::text, s.sequencename)) IS NOT NULL
+	`, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to advance sequences: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit data copy: %w", err)
+	}
+
+	m.log.Infof("📥 Copied data of %d tables from %s to %s", copied, from, to)
+	return nil
+}
+
+// copyOrder returns the tables of schema, excluding partitions and the
+// tracking tables, ordered so that tables referenced by foreign keys come
+// before the tables referencing them.
+func (m *Migrator) copyOrder(ctx context.Context, schema string) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT c.relname
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+			AND c.relname NOT LIKE '\_go\_migrations%'
+		ORDER BY c.relname
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables of %s: %w", schema, err)
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	rows, err = m.db.QueryContext(ctx, `
+		SELECT src.relname, ref.relname
+		FROM pg_constraint con
+		JOIN pg_class src ON src.oid = con.conrelid
+		JOIN pg_class ref ON ref.oid = con.confrelid
+		JOIN pg_namespace n ON n.oid = src.relnamespace
+		WHERE con.contype = 'f' AND n.nspname = $1 AND ref.relnamespace = src.relnamespace
+			AND src.oid <> ref.oid
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys of %s: %w", schema, err)
+	}
+	defer rows.Close()
+
+	references := map[string][]string{}
+	for rows.Next() {
+		var table, referenced string
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		references[table] = append(references[table], referenced)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign keys: %w", err)
+	}
+
+	// Depth-first, so each table follows the tables it references; cycles
+	// are broken arbitrarily and rely on deferrable constraints
+	ordered := make([]string, 0, len(tables))
+	state := map[string]int{}
+	var visit func(table string)
+	visit = func(table string) {
+		if state[table] != 0 {
+			return
+		}
+		state[table] = 1
+		referenced := references[table]
+		sort.Strings(referenced)
+		for _, other := range referenced {
+			visit(other)
+		}
+		state[table] = 2
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+
+	return ordered, nil
+}
+
+// commonColumns returns the writable columns of table in schema to that the
+// table of the same name in schema from also has.
+func (m *Migrator) commonColumns(ctx context.Context, from, to, table string) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT t.column_name
+		FROM information_schema.columns t
+		JOIN information_schema.columns f
+			ON f.table_schema = $1 AND f.table_name = t.table_name AND f.column_name = t.column_name
+		WHERE t.table_schema = $2 AND t.table_name = $3 AND t.is_generated = 'NEVER'
+		ORDER BY t.ordinal_position
+	`, from, to, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// runAssertions runs the deployment's assertions with the new schema first in
+// search_path.
+func (m *Migrator) runAssertions(ctx context.Context, deploy BlueGreen) error {
+	if len(deploy.Assertions) == 0 {
+		return nil
+	}
+
+	var original string
+	if err := m.db.QueryRowContext(ctx, "SELECT current_setting('search_path')").Scan(&original); err != nil {
+		return fmt.Errorf("failed to get search_path: %w", err)
+	}
+	if _, err := m.db.ExecContext(ctx, "SELECT set_config('search_path', $1, false)", pq.QuoteIdentifier(deploy.NewSchema)+", public"); err != nil {
+		return fmt.Errorf("failed to set search_path: %w", err)
+	}
+	defer func() {
+		if _, err := m.db.ExecContext(context.WithoutCancel(ctx), "SELECT set_config('search_path', $1, false)", original); err != nil {
+			m.log.Warnf("Failed to restore search_path: %v", err)
+		}
+	}()
+
+	var failed []error
+	for _, query := range deploy.Assertions {
+		if err := m.runVerification(ctx, query); err != nil {
+			failed = append(failed, fmt.Errorf("assertion %q: %w", query, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("schema %s failed %d of %d assertions: %w", deploy.NewSchema, len(failed), len(deploy.Assertions), errors.Join(failed...))
+	}
+
+	m.log.Infof("✓ Schema %s passed %d assertions", deploy.NewSchema, len(deploy.Assertions))
+	return nil
+}
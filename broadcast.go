@@ -0,0 +1,56 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// SchemaChangePayload is the JSON document sent on Options.NotifyChannel after
+// a run applies migrations.
+type SchemaChangePayload struct {
+	// Version is the version of the last migration applied.
+	Version string `json:"version"`
+
+	// Applied lists the migrations applied by the run, in order. Omitted when
+	// the list would exceed PostgreSQL's notification payload limit.
+	Applied []string `json:"applied,omitempty"`
+}
+
+// maxNotifyPayload stays below PostgreSQL's 8000 byte NOTIFY payload limit.
+const maxNotifyPayload = 7999
+
+// broadcastSchemaChange sends a pg_notify on the configured channel describing
+// the migrations applied by this run. Failures are only logged, since the
+// migrations themselves have already been committed.
+func (m *Migrator) broadcastSchemaChange(ctx context.Context, result *Result) {
+	applied := result.Applied
+	if m.notifyChannel == "" || len(applied) == 0 {
+		return
+	}
+
+	payload := SchemaChangePayload{
+		Version: validator.VersionFromName(applied[len(applied)-1].Name),
+	}
+	for _, migration := range applied {
+		payload.Applied = append(payload.Applied, migration.Name)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		result.warnf("Failed to encode schema change notification: %v", err)
+		return
+	}
+	if len(body) > maxNotifyPayload {
+		payload.Applied = nil
+		body, _ = json.Marshal(payload)
+	}
+
+	if _, err := m.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", m.notifyChannel, string(body)); err != nil {
+		result.warnf("Failed to notify channel %s: %v", m.notifyChannel, err)
+		return
+	}
+
+	m.log.Infof("📣 Notified channel %s of schema version %s", m.notifyChannel, payload.Version)
+}
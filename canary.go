@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCanaryRejected is returned by MigrateCanary when the canary's
+// verification fails; the other shards are left untouched.
+var ErrCanaryRejected = errors.New("canary shard rejected")
+
+// Shard is one database of a sharded deployment.
+type Shard struct {
+	Name string
+	DB   DB
+
+	// DatabaseURL enables shadow database testing for the shard, see
+	// Options.DatabaseURL.
+	DatabaseURL string
+}
+
+// CanaryOptions configures MigrateCanary.
+type CanaryOptions struct {
+	// Options configures the migrator of every shard. DatabaseURL is taken
+	// from each Shard instead.
+	Options Options
+
+	// Verify, when set, is called after the canary is migrated and before
+	// any other shard is touched, e.g. to run smoke tests or watch error
+	// rates for a while. An error stops the rollout with ErrCanaryRejected.
+	Verify func(ctx context.Context, canary Shard, result *Result) error
+}
+
+// ShardResult is the outcome of migrating one shard.
+type ShardResult struct {
+	Shard  string
+	Result *Result
+	Err    error
+}
+
+// MigrateCanary migrates a canary shard first and, once it succeeded and
+// passed CanaryOptions.Verify, the remaining shards one at a time. The
+// rollout stops at the first failure, leaving later shards untouched.
+//
+// It returns the results of the shards attempted, in order, and the error of
+// the one that failed.
+func MigrateCanary(ctx context.Context, canary Shard, shards []Shard, opts CanaryOptions) ([]ShardResult, error) {
+	log := newLogger(opts.Options)
+
+	migrate := func(shard Shard) ShardResult {
+		shardOpts := opts.Options
+		shardOpts.DatabaseURL = shard.DatabaseURL
+		result, err := NewWithOptions(shard.DB, shardOpts).MigrateWithResult(ctx)
+		return ShardResult{Shard: shard.Name, Result: result, Err: err}
+	}
+
+	log.Infof("🐤 Migrating canary shard %s", canary.Name)
+	first := migrate(canary)
+	results := []ShardResult{first}
+	if first.Err != nil {
+		return results, fmt.Errorf("failed to migrate canary shard %s: %w", canary.Name, first.Err)
+	}
+
+	if opts.Verify != nil {
+		if err := opts.Verify(ctx, canary, first.Result); err != nil {
+			return results, fmt.Errorf("%w: %s: %w", ErrCanaryRejected, canary.Name, err)
+		}
+		log.Infof("✓ Canary shard %s verified", canary.Name)
+	}
+
+	for i, shard := range shards {
+		if err := ctx.Err(); err != nil {
+			return results, interrupted(ctx, err)
+		}
+
+		log.Infof("🚀 Migrating shard %s (%d/%d)", shard.Name, i+1, len(shards))
+		outcome := migrate(shard)
+		results = append(results, outcome)
+		if outcome.Err != nil {
+			return results, fmt.Errorf("failed to migrate shard %s after %d of %d shards: %w", shard.Name, i, len(shards), outcome.Err)
+		}
+	}
+
+	log.Infof("✅ Migrated canary and %d shards", len(shards))
+	return results, nil
+}
@@ -0,0 +1,128 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPendingMigrations is matched by the error of CheckPending when migration
+// files have not been applied yet.
+var ErrPendingMigrations = errors.New("migrations are pending")
+
+// ErrDrift is matched by the error of CheckPending when the database and the
+// migration files disagree: applied migrations whose files are missing or
+// were modified, or, with DisallowOutOfOrder, pending migrations that sort
+// before the latest applied one.
+var ErrDrift = errors.New("database has drifted from the migration files")
+
+// CheckError is returned by CheckPending when the database is not consistent
+// with the migration files. It matches ErrPendingMigrations and ErrDrift with
+// errors.Is as applicable, and *ChecksumError and *OutOfOrderError with
+// errors.As.
+type CheckError struct {
+	// Pending lists migration files that haven't been applied yet.
+	Pending []string
+
+	// OutOfOrder lists pending migrations sorting before LatestApplied. It is
+	// only filled with DisallowOutOfOrder, as Migrate applies them otherwise.
+	OutOfOrder    []string
+	LatestApplied string
+
+	// Missing lists applied migrations whose files no longer exist.
+	Missing []string
+
+	// ChecksumMismatches lists applied migrations whose files changed after
+	// they were applied.
+	ChecksumMismatches []ChecksumMismatch
+}
+
+// Drifted reports whether the check found drift, as opposed to migrations
+// that are merely pending.
+func (e *CheckError) Drifted() bool {
+	return len(e.OutOfOrder) > 0 || len(e.Missing) > 0 || len(e.ChecksumMismatches) > 0
+}
+
+func (e *CheckError) Error() string {
+	var problems []string
+	if len(e.Pending) > 0 {
+		problems = append(problems, fmt.Sprintf("%d pending: %s", len(e.Pending), strings.Join(e.Pending, ", ")))
+	}
+	if len(e.OutOfOrder) > 0 {
+		problems = append(problems, fmt.Sprintf("%d out of order before %s: %s",
+			len(e.OutOfOrder), e.LatestApplied, strings.Join(e.OutOfOrder, ", ")))
+	}
+	if len(e.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d applied but missing: %s", len(e.Missing), strings.Join(e.Missing, ", ")))
+	}
+	if len(e.ChecksumMismatches) > 0 {
+		names := make([]string, len(e.ChecksumMismatches))
+		for i, mismatch := range e.ChecksumMismatches {
+			names[i] = mismatch.Name
+		}
+		problems = append(problems, fmt.Sprintf("%d modified after being applied: %s",
+			len(e.ChecksumMismatches), strings.Join(names, ", ")))
+	}
+	return fmt.Sprintf("migrations are not consistent with the database: %s", strings.Join(problems, "; "))
+}
+
+func (e *CheckError) Unwrap() []error {
+	var errs []error
+	if len(e.Pending) > 0 {
+		errs = append(errs, ErrPendingMigrations)
+	}
+	if e.Drifted() {
+		errs = append(errs, ErrDrift)
+	}
+	if len(e.OutOfOrder) > 0 {
+		errs = append(errs, &OutOfOrderError{Migrations: e.OutOfOrder, LatestApplied: e.LatestApplied})
+	}
+	if len(e.ChecksumMismatches) > 0 {
+		errs = append(errs, &ChecksumError{Mismatches: e.ChecksumMismatches})
+	}
+	return errs
+}
+
+// CheckPending verifies that the database is consistent with the migration
+// files without applying anything, for CI gates that should fail a branch
+// whose migrations don't fit the target database. It returns nil when
+// everything is applied, and a *CheckError otherwise; ExitCode maps it to
+// ExitPending, or ExitDrift when it also found drift.
+//
+// Checksums are always compared, whether or not VerifyChecksums is set. Like
+// Status, it never creates the migrations table.
+func (m *Migrator) CheckPending(ctx context.Context) error {
+	m.log.Infof("🔍 Checking migrations against the database...")
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	checkErr := &CheckError{
+		Pending:            status.Pending,
+		Missing:            status.Missing,
+		ChecksumMismatches: status.ChecksumMismatches,
+	}
+
+	if m.disallowOutOfOrder {
+		for _, record := range status.Applied {
+			if record.Name > checkErr.LatestApplied {
+				checkErr.LatestApplied = record.Name
+			}
+		}
+		for _, name := range status.Pending {
+			if name < checkErr.LatestApplied {
+				checkErr.OutOfOrder = append(checkErr.OutOfOrder, name)
+			}
+		}
+	}
+
+	if len(checkErr.Pending) == 0 && !checkErr.Drifted() {
+		m.log.Infof("✅ Database is up to date with %d migrations", len(status.Applied)+len(status.Legacy))
+		return nil
+	}
+
+	return checkErr
+}
@@ -0,0 +1,116 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChunkedChange describes a batched UPDATE or DELETE over a large table.
+// Each batch is its own short transaction, so locks are held briefly and no
+// single statement runs into the migration timeout.
+type ChunkedChange struct {
+	// Name identifies the change in the progress table; a change with the
+	// same name resumes after the last processed key.
+	Name string
+
+	// Table is the table to change, optionally schema-qualified.
+	Table string
+
+	// Key is a unique, ordered column batches are taken by. Defaults to "id".
+	Key string
+
+	// Set is the SET clause of the UPDATE, e.g. "archived = true".
+	Set string
+
+	// Delete deletes the matching rows instead of updating them.
+	Delete bool
+
+	// Where optionally restricts the rows changed, e.g. "created_at < '2020-01-01'".
+	Where string
+
+	// BatchSize is the number of rows changed per batch. Defaults to 1000.
+	BatchSize int
+
+	// Pause is the time to wait between batches, giving replicas and
+	// autovacuum room to keep up.
+	Pause time.Duration
+}
+
+// RunChunked runs a batched UPDATE or DELETE and returns the total number of
+// rows changed. Progress is saved after each batch: calling it again with the
+// same Name resumes an interrupted change, and a completed change is a no-op.
+//
+// For migration files, the "-- migrator:chunked" directive applies a
+// single-statement migration in repeated batches instead.
+func (m *Migrator) RunChunked(ctx context.Context, change ChunkedChange) (int64, error) {
+	if change.Name == "" || change.Table == "" {
+		return 0, fmt.Errorf("chunked change requires Name and Table")
+	}
+	if change.Set == "" && !change.Delete {
+		return 0, fmt.Errorf("chunked change %s requires Set or Delete", change.Name)
+	}
+	if change.Set != "" && change.Delete {
+		return 0, fmt.Errorf("chunked change %s sets both Set and Delete", change.Name)
+	}
+	if change.Key == "" {
+		change.Key = "id"
+	}
+	if change.BatchSize <= 0 {
+		change.BatchSize = 1000
+	}
+
+	if err := m.tracker.EnsureProgressTable(ctx); err != nil {
+		return 0, err
+	}
+
+	progress, _, err := m.tracker.GetProgress(ctx, change.Name)
+	if err != nil {
+		return 0, err
+	}
+	if progress.Completed {
+		m.log.Infof("✓ Chunked change %s already completed (%d rows)", change.Name, progress.Rows)
+		return progress.Rows, nil
+	}
+	progress.Step = stepBackfill
+
+	table := quoteQualified(change.Table)
+	key := pq.QuoteIdentifier(change.Key)
+	where := "TRUE"
+	if change.Where != "" {
+		where = "(" + change.Where + ")"
+	}
+
+	operation := fmt.Sprintf("UPDATE %s SET %s", table, change.Set)
+	if change.Delete {
+		operation = fmt.Sprintf("DELETE FROM %s", table)
+	}
+
+	query := func(bound string) string {
+		return fmt.Sprintf(`
+			WITH batch AS (
+				SELECT %[2]s FROM %[1]s WHERE %[6]s AND %[3]s ORDER BY %[2]s LIMIT %[4]d
+			), changed AS (
+				%[5]s WHERE %[2]s IN (SELECT %[2]s FROM batch)
+				RETURNING %[2]s
+			)
+			SELECT COUNT(*), COALESCE(MAX(%[2]s)::text, '') FROM changed
+		`, table, key, where, change.BatchSize, operation, bound)
+	}
+
+	m.log.Infof("🧩 Running chunked change %s in batches of %d", change.Name, change.BatchSize)
+
+	if err := m.runBatches(ctx, &progress, query, key, change.Pause); err != nil {
+		return progress.Rows, fmt.Errorf("failed to run chunked change %s after %d rows: %w", change.Name, progress.Rows, err)
+	}
+
+	progress.Completed = true
+	if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+		return progress.Rows, err
+	}
+
+	m.log.Infof("✅ Chunked change %s completed (%d rows)", change.Name, progress.Rows)
+	return progress.Rows, nil
+}
@@ -0,0 +1,113 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/hasirciogluhq/migrator/internal/shadowdb"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// CloneOptions configures Clone.
+type CloneOptions struct {
+	// SampleRows copies up to this many rows of each table into the clone.
+	// Rows referencing rows that weren't copied are left out, so foreign
+	// keys hold. Zero clones the schema only.
+	SampleRows int
+
+	// Replace drops an existing clone of the same name first. Otherwise
+	// Clone fails when the database exists.
+	Replace bool
+}
+
+var cloneNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// Clone creates a database named name with the schema of the database, e.g.
+// for a pull request's preview environment, and returns its connection
+// string. The schema is built the way shadow testing builds it: the applied
+// migrations are replayed and recorded in the clone's tracking table, so
+// migrating the clone applies just the migrations of the branch.
+//
+//	dsn, err := m.Clone(ctx, "preview_pr_1234", migrator.CloneOptions{SampleRows: 1000})
+//
+// The clone is kept until DropClone drops it. name must be a lowercase
+// identifier. Cloning requires Options.DatabaseURL, like shadow testing.
+func (m *Migrator) Clone(ctx context.Context, name string, opts CloneOptions) (string, error) {
+	if err := m.checkCloneName(ctx, name); err != nil {
+		return "", err
+	}
+
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	exists, err := m.tracker.TableExists(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.New("failed to clone database: no migrations have been applied")
+	}
+
+	run, err := m.loadRunState(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := validator.LoadAll(ctx, run.files); err != nil {
+		return "", fmt.Errorf("failed to get migration files: %w", err)
+	}
+	skips, err := m.tracker.GetSkipped(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	m.shadowManager.SetExtensions(m.shadowExtensions(run.files))
+	dsn, err := m.shadowManager.Clone(ctx, m.tracker, name, run.appliedNames(), run.files, shadowdb.CloneOptions{
+		SampleRows: opts.SampleRows,
+		Replace:    opts.Replace,
+		Skipped:    skips,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to clone database: %w", err)
+	}
+
+	m.log.Infof("✅ Created clone %s", name)
+	return dsn, nil
+}
+
+// DropClone drops a database created by Clone. Databases not created by
+// Clone are refused; a clone that doesn't exist is not an error.
+func (m *Migrator) DropClone(ctx context.Context, name string) error {
+	if err := m.checkCloneName(ctx, name); err != nil {
+		return err
+	}
+
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	m.log.Infof("🗑️  Dropping clone %s", name)
+	if err := m.shadowManager.DropClone(ctx, name); err != nil {
+		return fmt.Errorf("failed to drop clone: %w", err)
+	}
+	return nil
+}
+
+// checkCloneName fails unless clones can be made and name can be used for one.
+func (m *Migrator) checkCloneName(ctx context.Context, name string) error {
+	if m.shadowManager == nil {
+		return errors.New("cloning requires Options.DatabaseURL")
+	}
+	if !cloneNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid clone name %q: use a lowercase identifier of at most 63 characters", name)
+	}
+
+	var current string
+	if err := m.db.QueryRowContext(ctx, "SELECT current_database()").Scan(&current); err != nil {
+		return fmt.Errorf("failed to get current database: %w", err)
+	}
+	if name == current {
+		return fmt.Errorf("invalid clone name %q: it is the migrated database", name)
+	}
+	return nil
+}
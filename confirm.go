@@ -0,0 +1,98 @@
+package migrator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ErrNotConfirmed is returned when a required confirmation is declined.
+var ErrNotConfirmed = errors.New("migration run was not confirmed")
+
+// ConfirmRequest describes what a run is about to apply, for a Confirm callback.
+type ConfirmRequest struct {
+	// Migrations lists the pending migrations in apply order.
+	Migrations []string
+
+	// Destructive is true when linting found error-severity problems, such as
+	// dropped tables or columns.
+	Destructive bool
+
+	// Findings and Impacts are the lint and impact reports for the migrations.
+	Findings []Finding
+	Impacts  []MigrationImpact
+}
+
+// ConfirmFunc decides whether pending migrations may be applied. It can prompt
+// an operator, check an approval token, or call an external approval service.
+type ConfirmFunc func(ctx context.Context, req ConfirmRequest) (bool, error)
+
+// TerminalConfirm returns a ConfirmFunc that lists the pending migrations on
+// out and proceeds only if the operator types "yes" on in.
+func TerminalConfirm(in io.Reader, out io.Writer) ConfirmFunc {
+	reader := bufio.NewReader(in)
+
+	return func(ctx context.Context, req ConfirmRequest) (bool, error) {
+		fmt.Fprintf(out, "About to apply %d migrations:\n", len(req.Migrations))
+		for _, name := range req.Migrations {
+			fmt.Fprintf(out, "  - %s\n", name)
+		}
+		if req.Destructive {
+			fmt.Fprintln(out, "⚠️  These migrations contain destructive changes:")
+			for _, f := range req.Findings {
+				if f.Severity == SeverityError {
+					fmt.Fprintf(out, "  %s\n", f)
+				}
+			}
+		}
+		fmt.Fprint(out, "Type 'yes' to continue: ")
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		return strings.TrimSpace(answer) == "yes", nil
+	}
+}
+
+// confirm asks for confirmation before migrations are applied when the
+// environment requires it. Without a Confirm callback a protected run fails
+// rather than applying on autopilot.
+func (m *Migrator) confirm(ctx context.Context, migrations []*validator.MigrationFile, result *Result) error {
+	if !m.requireConfirmation || len(migrations) == 0 {
+		return nil
+	}
+	if m.confirmFunc == nil {
+		return fmt.Errorf("%w: RequireConfirmation is set but no Confirm callback is configured", ErrNotConfirmed)
+	}
+
+	req := ConfirmRequest{
+		Migrations: make([]string, len(migrations)),
+		Findings:   result.LintFindings,
+		Impacts:    result.Impacts,
+	}
+	for i, migration := range migrations {
+		req.Migrations[i] = migration.Name
+	}
+	for _, f := range result.LintFindings {
+		if f.Severity == SeverityError {
+			req.Destructive = true
+		}
+	}
+
+	ok, err := m.confirmFunc(ctx, req)
+	if err != nil {
+		return fmt.Errorf("confirmation failed: %w", err)
+	}
+	if !ok {
+		return ErrNotConfirmed
+	}
+
+	m.log.Infof("✓ Migration run confirmed")
+	return nil
+}
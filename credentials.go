@@ -0,0 +1,54 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hasirciogluhq/migrator/internal/dsn"
+)
+
+// CredentialsProvider returns the password to open a new connection with,
+// e.g. an AWS RDS IAM authentication token:
+//
+//	Options{CredentialsProvider: func(ctx context.Context) (string, error) {
+//		return auth.BuildAuthToken(ctx, endpoint, region, user, creds)
+//	}}
+//
+// It is called for every connection, so it should cache tokens that stay
+// valid for a while.
+type CredentialsProvider func(ctx context.Context) (string, error)
+
+// OpenDB opens a connection pool to databaseURL that asks credentials for the
+// password of each new connection, so pooled connections can be opened after
+// the first token expired. It returns an error only for an invalid
+// databaseURL; connecting happens on first use, as with sql.Open.
+func OpenDB(databaseURL string, credentials CredentialsProvider) (*sql.DB, error) {
+	parsed, err := dsn.Parse(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	return sql.OpenDB(parsed.Connector(nil, dsn.Credentials(credentials))), nil
+}
+
+// DatabaseURLProvider resolves a connection string when a connection is
+// opened, for connection strings kept in a secrets manager. Implementations
+// are called for every connection and should cache the secret for a while.
+type DatabaseURLProvider interface {
+	DatabaseURL(ctx context.Context) (string, error)
+}
+
+// DatabaseURLFunc adapts a function to DatabaseURLProvider.
+type DatabaseURLFunc func(ctx context.Context) (string, error)
+
+// DatabaseURL calls f.
+func (f DatabaseURLFunc) DatabaseURL(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// OpenDBFrom opens a connection pool that asks urls for the connection
+// string of each new connection, and credentials, if not nil, for its
+// password. Errors resolving or parsing it are returned on first use.
+func OpenDBFrom(urls DatabaseURLProvider, credentials CredentialsProvider) *sql.DB {
+	return sql.OpenDB(dsn.NewConnector(nil, urls.DatabaseURL, dsn.Credentials(credentials)))
+}
@@ -0,0 +1,129 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+)
+
+// EnvironmentDiff compares the migrations applied to two databases, e.g.
+// staging and production. See Diff.
+type EnvironmentDiff struct {
+	// OnlyLeft lists migrations applied to the left database only, in apply order.
+	OnlyLeft []MigrationRecord `json:"only_left"`
+
+	// OnlyRight lists migrations applied to the right database only, in apply order.
+	OnlyRight []MigrationRecord `json:"only_right"`
+
+	// ChecksumMismatches lists migrations applied to both databases with
+	// different content.
+	ChecksumMismatches []DiffMismatch `json:"checksum_mismatches"`
+}
+
+// DiffMismatch is a migration applied to two databases with different content.
+type DiffMismatch struct {
+	Name  string `json:"name"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// InSync reports whether both databases applied the same migrations with the
+// same content.
+func (d *EnvironmentDiff) InSync() bool {
+	return len(d.OnlyLeft) == 0 && len(d.OnlyRight) == 0 && len(d.ChecksumMismatches) == 0
+}
+
+// Diff compares the tracking tables of two databases and reports migrations
+// applied to one but not the other, and migrations applied to both whose
+// recorded checksums differ. Records without a checksum, from before
+// checksums were recorded, are only compared by name.
+//
+// It only reads from the databases; a database without a tracking table has
+// no migrations applied.
+func Diff(ctx context.Context, left, right DB) (*EnvironmentDiff, error) {
+	leftRecords, err := appliedRecords(ctx, left)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read left database: %w", err)
+	}
+	rightRecords, err := appliedRecords(ctx, right)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read right database: %w", err)
+	}
+
+	diff := &EnvironmentDiff{
+		OnlyLeft:           []MigrationRecord{},
+		OnlyRight:          []MigrationRecord{},
+		ChecksumMismatches: []DiffMismatch{},
+	}
+
+	rightByName := make(map[string]tracker.Record, len(rightRecords))
+	for _, record := range rightRecords {
+		rightByName[record.Name] = record
+	}
+	leftByName := make(map[string]bool, len(leftRecords))
+
+	for _, record := range leftRecords {
+		leftByName[record.Name] = true
+		other, ok := rightByName[record.Name]
+		if !ok {
+			diff.OnlyLeft = append(diff.OnlyLeft, newMigrationRecord(record))
+			continue
+		}
+		if record.Checksum != "" && other.Checksum != "" && record.Checksum != other.Checksum {
+			diff.ChecksumMismatches = append(diff.ChecksumMismatches, DiffMismatch{
+				Name:  record.Name,
+				Left:  record.Checksum,
+				Right: other.Checksum,
+			})
+		}
+	}
+	for _, record := range rightRecords {
+		if !leftByName[record.Name] {
+			diff.OnlyRight = append(diff.OnlyRight, newMigrationRecord(record))
+		}
+	}
+
+	return diff, nil
+}
+
+// appliedRecords reads the applied migrations of db without creating the
+// tracking table.
+func appliedRecords(ctx context.Context, db DB) ([]tracker.Record, error) {
+	t := tracker.New(db)
+
+	exists, err := t.TableExists(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	return t.GetAppliedRecords(ctx)
+}
+
+// WriteText renders the diff as a human-readable table, naming the two
+// databases leftName and rightName.
+func (d *EnvironmentDiff) WriteText(w io.Writer, leftName, rightName string) error {
+	if d.InSync() {
+		_, err := fmt.Fprintf(w, "%s and %s are in sync\n", leftName, rightName)
+		return err
+	}
+
+	fmt.Fprintf(w, "Only in %s: %d  Only in %s: %d  Checksum mismatches: %d\n\n",
+		leftName, len(d.OnlyLeft), rightName, len(d.OnlyRight), len(d.ChecksumMismatches))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MIGRATION\tDIFFERENCE")
+	for _, record := range d.OnlyLeft {
+		fmt.Fprintf(tw, "%s\tonly in %s\n", record.Name, leftName)
+	}
+	for _, record := range d.OnlyRight {
+		fmt.Fprintf(tw, "%s\tonly in %s\n", record.Name, rightName)
+	}
+	for _, mismatch := range d.ChecksumMismatches {
+		fmt.Fprintf(tw, "%s\tchecksum %.12s in %s, %.12s in %s\n", mismatch.Name, mismatch.Left, leftName, mismatch.Right, rightName)
+	}
+
+	return tw.Flush()
+}
@@ -207,21 +207,26 @@ This separation ensures:
 
 # Known Limitations
 
-PostgreSQL enum operations require careful ordering. This is NOT a bug in migrator,
-but a PostgreSQL constraint that affects ALL migration tools:
+PostgreSQL enum operations require care. This is NOT a bug in migrator, but a
+PostgreSQL constraint that affects ALL migration tools: values can be added and
+renamed but not dropped, and an added value can't be used in the transaction
+that adds it.
 
-	-- ❌ WRONG ORDER - Will fail in production
+	-- ❌ WRONG - PostgreSQL has no DROP VALUE
 	ALTER TYPE status_enum DROP VALUE 'old_value';
-	UPDATE users SET status = 'new_value' WHERE status = 'old_value';
 
-	-- ✅ CORRECT ORDER
+	-- ✅ CORRECT - replace the type
 	UPDATE users SET status = 'new_value' WHERE status = 'old_value';
-	ALTER TYPE status_enum DROP VALUE 'old_value';
-
-Shadow database testing might pass the wrong order (no data in shadow DB), but
-production will fail (has rows with 'old_value'). This is true for Prisma,
-golang-migrate, and all migration tools. The solution: know your SQL, especially
-with enum operations.
+	CREATE TYPE status_enum_v2 AS ENUM ('new_value', 'archived');
+	ALTER TABLE users ALTER COLUMN status TYPE status_enum_v2 USING status::text::status_enum_v2;
+	DROP TYPE status_enum;
+	ALTER TYPE status_enum_v2 RENAME TO status_enum;
+
+The built-in "enum-order" lint rule reports DROP VALUE as an error before
+anything is applied, and warns about values written or filtered after they are
+renamed, or in the transaction that adds them (not in no-transaction
+migrations, which commit each statement); set LintMode to LintBlock to fail
+the run on errors.
 
 # Performance Considerations
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/hasirciogluhq/migrator"
 	_ "github.com/lib/pq"
@@ -45,12 +46,45 @@ func main() {
 	m := migrator.NewWithOptions(db, migrator.Options{
 		MigrationsPath: migrationsPath,
 		DatabaseURL:    databaseURL, // Explicit database URL for shadow DB testing
+
+		// On SIGTERM, let the migration in flight commit before stopping
+		ShutdownGracePeriod: 30 * time.Second,
 	})
 
 	// Alternative: Use default constructor with environment variables
 	// os.Setenv("MIGRATIONS_PATH", migrationsPath)
 	// m := migrator.New(db)
 
+	// "go run ./examples/basic check" only checks the database, e.g. as a CI
+	// gate: it exits 4 with pending migrations and 5 on drift
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		err := m.CheckPending(context.Background())
+		if err != nil {
+			log.Printf("Check failed: %v", err)
+		}
+		os.Exit(migrator.ExitCode(err))
+	}
+
+	// "go run ./examples/basic validate" checks the migrations without a
+	// shadow database or applying anything
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		if _, err := m.Validate(context.Background()); err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		return
+	}
+
+	// "go run ./examples/basic cleanup" drops shadow databases left behind by
+	// killed runs
+	if len(os.Args) > 1 && os.Args[1] == "cleanup" {
+		dropped, err := m.CleanupOrphans(context.Background(), time.Hour)
+		if err != nil {
+			log.Fatalf("Cleanup failed: %v", err)
+		}
+		log.Printf("Dropped %d orphaned shadow databases", len(dropped))
+		return
+	}
+
 	// Get current status before migration
 	applied, err := m.GetAppliedMigrations(context.Background())
 	if err != nil {
@@ -59,10 +93,15 @@ func main() {
 		log.Printf("Currently applied migrations: %d", len(applied))
 	}
 
-	// Run migrations
+	// Run migrations, stopping cleanly on SIGINT/SIGTERM
+	ctx, stop := migrator.SignalContext(context.Background())
+	defer stop()
+
 	log.Println("🚀 Starting migration process...")
-	if err := m.Migrate(context.Background()); err != nil {
-		log.Fatalf("Migration failed: %v", err)
+	if err := m.Migrate(ctx); err != nil {
+		log.Printf("Migration failed: %v", err)
+		stop()
+		os.Exit(migrator.ExitCode(err))
 	}
 
 	log.Println("✅ Migrations completed successfully!")
@@ -0,0 +1,119 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ExtensionError is returned when required extensions are not installed on
+// the target database.
+type ExtensionError struct {
+	Problems []string
+}
+
+func (e *ExtensionError) Error() string {
+	return "required extensions are missing:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+var createExtensionPattern = regexp.MustCompile(`^CREATE EXTENSION (?:IF NOT EXISTS )?"?([^\s";]+)"?`)
+
+// requiredExtensions returns the extensions declared by Options.RequiredExtensions
+// and "-- migrator:extension <name>" directives in migrations, sorted.
+func (m *Migrator) requiredExtensions(migrations []*validator.MigrationFile) []string {
+	set := make(map[string]bool)
+	for _, name := range m.requiredExtensionNames {
+		set[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, migration := range migrations {
+		for _, directive := range sqlparse.Directives(migration.Content) {
+			if directive.Key != "extension" {
+				continue
+			}
+			for _, name := range strings.FieldsFunc(directive.Value, func(r rune) bool { return r == ',' || r == ' ' }) {
+				set[strings.ToLower(name)] = true
+			}
+		}
+	}
+	delete(set, "")
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shadowExtensions returns the required extensions to install in a shadow
+// database or clone before replay. Extensions installed out-of-band must
+// exist first; those created by migrations are left to them.
+func (m *Migrator) shadowExtensions(migrations []*validator.MigrationFile) []string {
+	created := createdExtensions(migrations)
+	var preinstall []string
+	for _, name := range m.requiredExtensions(migrations) {
+		if !created[name] {
+			preinstall = append(preinstall, name)
+		}
+	}
+	return preinstall
+}
+
+// createdExtensions returns the extensions created by statements in migrations.
+func createdExtensions(migrations []*validator.MigrationFile) map[string]bool {
+	created := make(map[string]bool)
+	for _, migration := range migrations {
+		for _, stmt := range sqlparse.Split(migration.Content) {
+			if match := createExtensionPattern.FindStringSubmatch(sqlparse.Normalize(stmt.Text)); match != nil {
+				created[strings.ToLower(match[1])] = true
+			}
+		}
+	}
+	return created
+}
+
+// checkExtensions verifies that required extensions are installed on the
+// target database, unless a pending migration creates them.
+func (m *Migrator) checkExtensions(ctx context.Context, extensions []string, pending []*validator.MigrationFile) error {
+	if len(extensions) == 0 {
+		return nil
+	}
+
+	created := createdExtensions(pending)
+
+	var problems []string
+	for _, name := range extensions {
+		var installed sql.NullString
+		var available sql.NullString
+		err := m.db.QueryRowContext(ctx, `
+			SELECT
+				(SELECT extversion FROM pg_extension WHERE extname = $1),
+				(SELECT default_version FROM pg_available_extensions WHERE name = $1)
+		`, name).Scan(&installed, &available)
+		if err != nil {
+			return fmt.Errorf("failed to check extension %s: %w", name, err)
+		}
+
+		switch {
+		case installed.Valid || created[name] && available.Valid:
+			continue
+		case available.Valid:
+			problems = append(problems, fmt.Sprintf("%s is not installed: CREATE EXTENSION %s (or add it to a migration)", name, name))
+		default:
+			problems = append(problems, fmt.Sprintf("%s is not available on this server (install its package first)", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ExtensionError{Problems: problems}
+	}
+
+	m.log.Infof("✓ Required extensions present: %s", strings.Join(extensions, ", "))
+	return nil
+}
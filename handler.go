@@ -0,0 +1,81 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// StatusResponse is the JSON document served by StatusHandler.
+type StatusResponse struct {
+	// UpToDate is true when there are no pending migrations.
+	UpToDate bool `json:"up_to_date"`
+
+	// AppliedCount is the number of migrations recorded in the tracking table.
+	AppliedCount int `json:"applied_count"`
+
+	// Pending lists migration files that haven't been applied yet.
+	Pending []string `json:"pending"`
+
+	// LastApplied is the most recently applied migration, if any.
+	LastApplied *MigrationRecord `json:"last_applied"`
+
+	// MissingFiles lists applied migrations whose files no longer exist.
+	// A non-empty list means the next Migrate call will fail validation.
+	MissingFiles []string `json:"missing_files"`
+
+	// ChecksumMismatches lists applied migrations whose files were modified
+	// after being applied.
+	ChecksumMismatches []ChecksumMismatch `json:"checksum_mismatches"`
+}
+
+// StatusHandler returns an http.Handler that reports the migration state as JSON.
+//
+// The handler only reads from the database and never creates the tracking table,
+// so it can be mounted in any service sharing the database:
+//
+//	mux.Handle("/internal/migrations", migrator.StatusHandler(m))
+func StatusHandler(m *Migrator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		status, err := m.statusResponse(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, status)
+	})
+}
+
+// statusResponse collects the data served by StatusHandler.
+func (m *Migrator) statusResponse(ctx context.Context) (*StatusResponse, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &StatusResponse{
+		UpToDate:           len(status.Pending) == 0,
+		AppliedCount:       len(status.Applied),
+		Pending:            status.Pending,
+		MissingFiles:       status.Missing,
+		ChecksumMismatches: status.ChecksumMismatches,
+	}
+	if len(status.Applied) > 0 {
+		response.LastApplied = &status.Applied[len(status.Applied)-1]
+	}
+
+	return response, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,106 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// IsUpToDate reports whether every migration file has been applied to the database.
+//
+// Unlike GetPendingMigrations, it never creates the migrations table, so it is safe
+// to call from application replicas that only read the schema and never migrate it.
+func (m *Migrator) IsUpToDate(ctx context.Context) (bool, error) {
+	migrationFiles, err := m.validator.ListMigrationFiles(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	exists, err := m.tracker.TableExists(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	applied, err := m.appliedSet(ctx, migrationFiles, exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to find new migrations: %w", err)
+	}
+
+	return len(validator.NewMigrations(migrationFiles, applied)) == 0, nil
+}
+
+// Version returns the version of the latest applied migration, e.g. "003"
+// for "003_add_index.sql", or "" if none has been applied. Like
+// IsUpToDate, it never creates the migrations table.
+//
+// Applications can check it at startup to assert they run against the
+// schema they were built for.
+func (m *Migrator) Version(ctx context.Context) (string, error) {
+	record, err := m.LastApplied(ctx)
+	if err != nil || record == nil {
+		return "", err
+	}
+	return record.Version, nil
+}
+
+// LastApplied returns the record of the latest applied migration, or nil if
+// none has been applied. Migrations are applied in name order, so the latest
+// is the one with the largest name, even if an older migration was applied
+// out of order after it. Like IsUpToDate, it never creates the migrations
+// table.
+func (m *Migrator) LastApplied(ctx context.Context) (*MigrationRecord, error) {
+	exists, err := m.tracker.TableExists(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	records, err := m.tracker.GetAppliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *MigrationRecord
+	for _, r := range records {
+		if latest == nil || r.Name > latest.Name {
+			record := newMigrationRecord(r)
+			latest = &record
+		}
+	}
+
+	return latest, nil
+}
+
+// WaitUntilCurrent blocks until all migration files have been applied to the database,
+// polling every Options.WaitInterval. It returns the context error if ctx is cancelled
+// or times out first.
+//
+// This lets replicas that don't run migrations themselves hold off serving traffic
+// until the schema reaches the version they were built against:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+//	defer cancel()
+//	if err := m.WaitUntilCurrent(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+func (m *Migrator) WaitUntilCurrent(ctx context.Context) error {
+	ticker := time.NewTicker(m.waitInterval)
+	defer ticker.Stop()
+
+	for {
+		upToDate, err := m.IsUpToDate(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check migration status: %w", err)
+		}
+		if upToDate {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for migrations: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
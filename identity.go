@@ -0,0 +1,35 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnexpectedDatabase is returned when the connected database doesn't match
+// Options.ExpectedDatabase or Options.ExpectedDatabasePattern.
+var ErrUnexpectedDatabase = errors.New("connected to an unexpected database")
+
+// checkDatabaseIdentity refuses to run against a database other than the
+// expected one, so a misconfigured DATABASE_URL can't migrate the wrong
+// environment.
+func (m *Migrator) checkDatabaseIdentity(ctx context.Context) error {
+	if m.expectedDatabase == "" && m.expectedDatabasePattern == nil {
+		return nil
+	}
+
+	var name string
+	if err := m.db.QueryRowContext(ctx, "SELECT current_database()").Scan(&name); err != nil {
+		return fmt.Errorf("failed to get current database: %w", err)
+	}
+
+	if m.expectedDatabase != "" && name != m.expectedDatabase {
+		return fmt.Errorf("%w: connected to %q, expected %q", ErrUnexpectedDatabase, name, m.expectedDatabase)
+	}
+	if m.expectedDatabasePattern != nil && !m.expectedDatabasePattern.MatchString(name) {
+		return fmt.Errorf("%w: connected to %q, expected a name matching %s",
+			ErrUnexpectedDatabase, name, m.expectedDatabasePattern)
+	}
+
+	return nil
+}
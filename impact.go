@@ -0,0 +1,297 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ImpactLevel estimates how disruptive a statement is for concurrent traffic.
+type ImpactLevel int
+
+const (
+	// ImpactNone means the statement takes no lock that blocks normal traffic.
+	ImpactNone ImpactLevel = iota
+
+	// ImpactLow means the statement takes a blocking lock only briefly.
+	ImpactLow
+
+	// ImpactHigh means the statement holds a blocking lock for a time
+	// proportional to the table size (table rewrite, full scan or index build).
+	ImpactHigh
+)
+
+// String returns the lower-case name of the level.
+func (l ImpactLevel) String() string {
+	switch l {
+	case ImpactNone:
+		return "none"
+	case ImpactLow:
+		return "low"
+	case ImpactHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("impact(%d)", int(l))
+	}
+}
+
+// MarshalText encodes the level by name, e.g. in JSON reports.
+func (l ImpactLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level encoded by MarshalText, e.g. in saved plans.
+func (l *ImpactLevel) UnmarshalText(text []byte) error {
+	for _, level := range []ImpactLevel{ImpactNone, ImpactLow, ImpactHigh} {
+		if level.String() == string(text) {
+			*l = level
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown impact level %q", text)
+}
+
+// StatementImpact describes the predicted locking behaviour of a statement.
+type StatementImpact struct {
+	Line      int         `json:"line"`
+	Statement string      `json:"statement"`
+	Table     string      `json:"table,omitempty"`
+	Lock      string      `json:"lock"`
+	Rewrite   bool        `json:"rewrite"`
+	FullScan  bool        `json:"full_scan"`
+	Level     ImpactLevel `json:"level"`
+	Reason    string      `json:"reason"`
+
+	// EstimatedRows and TableBytes describe the affected table from planner
+	// statistics when available, to size the impact.
+	EstimatedRows int64 `json:"estimated_rows,omitempty"`
+	TableBytes    int64 `json:"table_bytes,omitempty"`
+}
+
+// MigrationImpact summarizes the predicted impact of a migration.
+type MigrationImpact struct {
+	Migration  string            `json:"migration"`
+	Level      ImpactLevel       `json:"level"`
+	Statements []StatementImpact `json:"statements"`
+}
+
+// AnalyzeImpact predicts which statements in pending migrations rewrite tables
+// or hold ACCESS EXCLUSIVE (or other blocking) locks, sized with the planner's
+// row estimates for the affected tables. Nothing is applied.
+func (m *Migrator) AnalyzeImpact(ctx context.Context) ([]MigrationImpact, error) {
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.analyzeImpact(ctx, pending)
+}
+
+// analyzeImpact analyzes migrations against the connected server.
+func (m *Migrator) analyzeImpact(ctx context.Context, migrations []*validator.MigrationFile) ([]MigrationImpact, error) {
+	var serverVersion int
+	if err := m.db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&serverVersion); err != nil {
+		return nil, fmt.Errorf("failed to get server version: %w", err)
+	}
+
+	impacts := analyzeMigrations(migrations, serverVersion)
+	for i := range impacts {
+		for j := range impacts[i].Statements {
+			stmt := &impacts[i].Statements[j]
+			if stmt.Table == "" || stmt.Level == ImpactNone {
+				continue
+			}
+			// Tables created earlier in the same batch don't exist yet; that's fine
+			_ = m.db.QueryRowContext(ctx, `
+				SELECT GREATEST(reltuples, 0)::bigint, pg_total_relation_size(oid)
+				FROM pg_class WHERE oid = to_regclass($1)
+			`, strings.ToLower(stmt.Table)).Scan(&stmt.EstimatedRows, &stmt.TableBytes)
+		}
+	}
+
+	return impacts, nil
+}
+
+// reportImpact prints the predicted impact of pending migrations and records it
+// in result. Analysis failures are only warnings.
+func (m *Migrator) reportImpact(ctx context.Context, migrations []*validator.MigrationFile, result *Result) {
+	if m.lintMode == LintOff || len(migrations) == 0 {
+		return
+	}
+
+	impacts, err := m.analyzeImpact(ctx, migrations)
+	if err != nil {
+		result.warnf("Impact analysis failed: %v", err)
+		return
+	}
+	result.Impacts = impacts
+
+	for _, impact := range impacts {
+		if impact.Level != ImpactHigh {
+			continue
+		}
+		m.log.Infof("🔒 %s has high lock impact:", impact.Migration)
+		for _, stmt := range impact.Statements {
+			if stmt.Level != ImpactHigh {
+				continue
+			}
+			size := ""
+			if stmt.EstimatedRows > 0 {
+				size = fmt.Sprintf(" (~%d rows, %d MB)", stmt.EstimatedRows, stmt.TableBytes/(1<<20))
+			}
+			m.log.Infof("   line %d: %s%s", stmt.Line, stmt.Reason, size)
+		}
+	}
+}
+
+// analyzeMigrations predicts the impact of every statement in migrations.
+// serverVersion is in server_version_num form (e.g. 150004); 0 assumes a current server.
+func analyzeMigrations(migrations []*validator.MigrationFile, serverVersion int) []MigrationImpact {
+	var impacts []MigrationImpact
+
+	for _, migration := range migrations {
+		impact := MigrationImpact{Migration: migration.Name, Statements: []StatementImpact{}}
+		for _, stmt := range sqlparse.Split(migration.Content) {
+			for _, si := range analyzeStatement(sqlparse.Normalize(stmt.Text), serverVersion) {
+				si.Line = stmt.Line
+				si.Statement = truncate(stmt.Text, maxSnippetLength)
+				if si.Level > impact.Level {
+					impact.Level = si.Level
+				}
+				impact.Statements = append(impact.Statements, si)
+			}
+		}
+		impacts = append(impacts, impact)
+	}
+
+	return impacts
+}
+
+const (
+	lockAccessExclusive = "ACCESS EXCLUSIVE"
+	lockShare           = "SHARE"
+	lockShareRowExcl    = "SHARE ROW EXCLUSIVE"
+)
+
+var (
+	createIndexPattern  = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX (CONCURRENTLY )?(IF NOT EXISTS )?(\S+ )?ON (ONLY )?(\S+)`)
+	simpleTablePattern  = regexp.MustCompile(`^(?:TRUNCATE|LOCK|CLUSTER|VACUUM FULL|REINDEX TABLE|REFRESH MATERIALIZED VIEW)(?: TABLE)?(?: ONLY)? (\S+)`)
+	volatileDefaultExpr = regexp.MustCompile(`DEFAULT .*\b(RANDOM|GEN_RANDOM_UUID|UUID_GENERATE_V[14]|CLOCK_TIMESTAMP|TIMEOFDAY|NEXTVAL)\s*\(`)
+	setNotNullPattern   = regexp.MustCompile(`^ALTER (COLUMN )?\S+ SET NOT NULL`)
+)
+
+// analyzeStatement predicts the impact of a single normalized statement,
+// returning one entry per blocking operation it performs.
+func analyzeStatement(normalized string, serverVersion int) []StatementImpact {
+	if match := alterTablePattern.FindStringSubmatch(normalized); match != nil {
+		table := match[3]
+		var impacts []StatementImpact
+		for _, action := range sqlparse.SplitList(match[4]) {
+			impacts = append(impacts, analyzeAlterAction(table, action, serverVersion))
+		}
+		return impacts
+	}
+
+	if match := createIndexPattern.FindStringSubmatch(normalized); match != nil {
+		if match[2] != "" {
+			return []StatementImpact{{Table: match[6], Lock: "SHARE UPDATE EXCLUSIVE", Level: ImpactNone,
+				Reason: "CREATE INDEX CONCURRENTLY builds the index without blocking writes"}}
+		}
+		return []StatementImpact{{Table: match[6], Lock: lockShare, FullScan: true, Level: ImpactHigh,
+			Reason: "CREATE INDEX without CONCURRENTLY blocks all writes until the index is built"}}
+	}
+
+	if match := simpleTablePattern.FindStringSubmatch(normalized); match != nil {
+		table := match[1]
+		switch {
+		case strings.HasPrefix(normalized, "TRUNCATE"):
+			return []StatementImpact{{Table: table, Lock: lockAccessExclusive, Level: ImpactLow,
+				Reason: "TRUNCATE takes an ACCESS EXCLUSIVE lock"}}
+		case strings.HasPrefix(normalized, "LOCK"):
+			return []StatementImpact{{Table: table, Lock: "explicit", Level: ImpactHigh,
+				Reason: "LOCK TABLE holds the lock until the migration transaction commits"}}
+		case strings.HasPrefix(normalized, "REINDEX") && !strings.Contains(normalized, "CONCURRENTLY"):
+			return []StatementImpact{{Table: table, Lock: lockShare, FullScan: true, Level: ImpactHigh,
+				Reason: "REINDEX without CONCURRENTLY blocks writes while indexes are rebuilt"}}
+		case strings.HasPrefix(normalized, "REFRESH"):
+			if strings.Contains(normalized, " CONCURRENTLY ") {
+				return nil
+			}
+			return []StatementImpact{{Table: table, Lock: lockAccessExclusive, FullScan: true, Level: ImpactHigh,
+				Reason: "REFRESH MATERIALIZED VIEW without CONCURRENTLY blocks reads until done"}}
+		case strings.HasPrefix(normalized, "CLUSTER"), strings.HasPrefix(normalized, "VACUUM FULL"):
+			return []StatementImpact{{Table: table, Lock: lockAccessExclusive, Rewrite: true, Level: ImpactHigh,
+				Reason: "rewrites the whole table under an ACCESS EXCLUSIVE lock"}}
+		}
+	}
+
+	return nil
+}
+
+// analyzeAlterAction predicts the impact of one ALTER TABLE action.
+func analyzeAlterAction(table, action string, serverVersion int) StatementImpact {
+	impact := StatementImpact{Table: table, Lock: lockAccessExclusive, Level: ImpactLow,
+		Reason: "ALTER TABLE takes a brief ACCESS EXCLUSIVE lock"}
+
+	rewrite := func(reason string) StatementImpact {
+		impact.Rewrite, impact.Level, impact.Reason = true, ImpactHigh, reason
+		return impact
+	}
+	scan := func(reason string) StatementImpact {
+		impact.FullScan, impact.Level, impact.Reason = true, ImpactHigh, reason
+		return impact
+	}
+
+	switch {
+	case alterTypePattern.MatchString(action):
+		return rewrite("changing a column type rewrites the table (unless the types are binary coercible) under an ACCESS EXCLUSIVE lock")
+
+	case strings.HasPrefix(action, "ADD ") && !isConstraintAction(action):
+		switch {
+		case strings.Contains(action, "SERIAL") || strings.Contains(action, " STORED"):
+			return rewrite("adding a serial or stored generated column rewrites the table")
+		case volatileDefaultExpr.MatchString(action):
+			return rewrite("adding a column with a volatile default rewrites the table")
+		case strings.Contains(action, " DEFAULT ") && serverVersion > 0 && serverVersion < 110000:
+			return rewrite("adding a column with a default rewrites the table before PostgreSQL 11")
+		}
+		return impact
+
+	case setNotNullPattern.MatchString(action):
+		return scan("SET NOT NULL scans the whole table under an ACCESS EXCLUSIVE lock (use a validated CHECK constraint first)")
+
+	case strings.HasPrefix(action, "ADD ") && !strings.Contains(action, " NOT VALID"):
+		switch {
+		case strings.Contains(action, "FOREIGN KEY"):
+			impact.Lock = lockShareRowExcl
+			return scan("adding a FOREIGN KEY validates all rows while blocking writes (add it NOT VALID, then VALIDATE)")
+		case strings.Contains(action, "CHECK"):
+			return scan("adding a CHECK constraint scans the whole table under an ACCESS EXCLUSIVE lock (add it NOT VALID, then VALIDATE)")
+		case (strings.Contains(action, "PRIMARY KEY") || strings.Contains(action, "UNIQUE")) && !strings.Contains(action, "USING INDEX"):
+			return scan("adding a PRIMARY KEY or UNIQUE constraint builds an index under an ACCESS EXCLUSIVE lock (build it CONCURRENTLY, then ADD ... USING INDEX)")
+		}
+		return impact
+
+	case strings.HasPrefix(action, "VALIDATE CONSTRAINT"):
+		impact.Lock = "SHARE UPDATE EXCLUSIVE"
+		impact.Level = ImpactNone
+		impact.Reason = "VALIDATE CONSTRAINT scans the table without blocking reads or writes"
+		return impact
+
+	case strings.HasPrefix(action, "SET TABLESPACE"), strings.HasPrefix(action, "SET LOGGED"),
+		strings.HasPrefix(action, "SET UNLOGGED"), strings.HasPrefix(action, "SET WITHOUT OIDS"):
+		return rewrite("this ALTER TABLE form rewrites the table under an ACCESS EXCLUSIVE lock")
+	}
+
+	return impact
+}
+
+// isConstraintAction reports whether an ADD action adds a table constraint rather than a column.
+func isConstraintAction(action string) bool {
+	fields := strings.Fields(action)
+	return len(fields) > 1 && fields[1] != "COLUMN" && isConstraintKeyword(fields[1])
+}
@@ -0,0 +1,28 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateIndex builds an index with a CREATE INDEX CONCURRENTLY statement
+// that names it, for index builds run outside migration files, e.g. from a
+// maintenance job:
+//
+//	err := m.CreateIndex(ctx, "CREATE INDEX CONCURRENTLY IF NOT EXISTS orders_customer_idx ON orders (customer_id)")
+//
+// It returns once the index is valid. An INVALID index of the same name left
+// by an earlier failed build is dropped first, and a build failing for a
+// transient reason is retried as configured by Options.IndexRetries and
+// Options.IndexRetryDelay. No-transaction migrations handle their CREATE
+// INDEX CONCURRENTLY statements the same way.
+func (m *Migrator) CreateIndex(ctx context.Context, statement string) error {
+	m.log.Infof("🏗️  Building index: %s", statement)
+
+	if err := m.tracker.CreateIndex(ctx, statement); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	m.log.Infof("✅ Index is valid")
+	return nil
+}
@@ -0,0 +1,102 @@
+package dsn
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Credentials returns the password to open a connection with, e.g. a
+// short-lived IAM authentication token.
+type Credentials func(ctx context.Context) (string, error)
+
+// Resolver returns the connection string to open a connection with, e.g. one
+// read from a secrets manager.
+type Resolver func(ctx context.Context) (string, error)
+
+// connector opens connections with driver, or lib/pq when nil, resolving
+// the connection string and password for each one.
+type connector struct {
+	resolve     func(ctx context.Context) (*DSN, error)
+	credentials Credentials
+	driver      driver.Driver
+}
+
+// Connector returns a driver.Connector for sql.OpenDB that opens
+// connections to the DSN with drv, or lib/pq when nil. With credentials, each
+// new connection asks it for the password, replacing any in the DSN, so
+// pooled connections keep working after a token expires.
+func (d *DSN) Connector(drv driver.Driver, credentials Credentials) driver.Connector {
+	return &connector{
+		resolve:     func(context.Context) (*DSN, error) { return d, nil },
+		credentials: credentials,
+		driver:      drv,
+	}
+}
+
+// NewConnector returns a driver.Connector like DSN.Connector that calls
+// resolve for the connection string of each new connection, so rotated
+// credentials are picked up.
+func NewConnector(drv driver.Driver, resolve Resolver, credentials Credentials) driver.Connector {
+	return &connector{
+		resolve: func(ctx context.Context) (*DSN, error) {
+			s, err := resolve(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve database URL: %w", err)
+			}
+			parsed, err := Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse database URL: %w", err)
+			}
+			return parsed, nil
+		},
+		credentials: credentials,
+		driver:      drv,
+	}
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.credentials != nil {
+		password, err := c.credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database credentials: %w", err)
+		}
+		conn, _ = conn.WithoutPassword()
+		conn = conn.WithParam("password", password)
+	}
+
+	driverConn, err := c.connect(ctx, conn.String())
+	return driverConn, conn.RedactError(err)
+}
+
+func (c *connector) connect(ctx context.Context, name string) (driver.Conn, error) {
+	if c.driver == nil {
+		pqConnector, err := pq.NewConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return pqConnector.Connect(ctx)
+	}
+
+	if driverCtx, ok := c.driver.(driver.DriverContext); ok {
+		driverConnector, err := driverCtx.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return driverConnector.Connect(ctx)
+	}
+	return c.driver.Open(name)
+}
+
+func (c *connector) Driver() driver.Driver {
+	if c.driver == nil {
+		return &pq.Driver{}
+	}
+	return c.driver
+}
@@ -0,0 +1,464 @@
+// Package dsn parses PostgreSQL connection strings in URL
+// ("postgres://user@host/db") and keyword/value ("host=... dbname=...") form,
+// including Unix-domain socket and multi-host forms.
+package dsn
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// mask replaces passwords in redacted output, matching url.URL.Redacted.
+const mask = "xxxxx"
+
+// DSN is a parsed connection string. It keeps the original form so it can be
+// turned back into a string the driver understands.
+type DSN struct {
+	url    *url.URL
+	params []param
+}
+
+// param is one keyword/value pair, kept in order.
+type param struct {
+	key, value string
+}
+
+// Host is one of the servers a connection string lists.
+type Host struct {
+	// Host is a host name, an IP address, or the directory of a Unix-domain
+	// socket, starting with "/".
+	Host string
+
+	// Port is empty when the connection string doesn't set one.
+	Port string
+}
+
+// String returns the host as "host:port", or just the host without a port.
+func (h Host) String() string {
+	if h.Port == "" {
+		return h.Host
+	}
+	return net.JoinHostPort(h.Host, h.Port)
+}
+
+// Parse parses a connection string in URL or keyword/value form.
+func Parse(s string) (*DSN, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		return parseURL(s)
+	}
+
+	params, err := parseKeywords(s)
+	if err != nil {
+		return nil, err
+	}
+	return &DSN{params: params}, nil
+}
+
+// parseURL parses a connection URL. Host lists ("h1:5432,h2:5433") and
+// percent-encoded socket directories ("%2Fvar%2Frun%2Fpostgresql") are valid
+// for libpq but not for net/url, so they are moved to the host and port
+// query parameters, which libpq treats the same.
+func parseURL(s string) (*DSN, error) {
+	scheme, rest, _ := strings.Cut(s, "://")
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	userinfo, hostList := "", authority
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		userinfo, hostList = authority[:at+1], authority[at+1:]
+	}
+
+	var hosts []Host
+	if strings.ContainsAny(hostList, "%,") {
+		var err error
+		if hosts, err = parseHostList(hostList); err != nil {
+			return nil, err
+		}
+		s = scheme + "://" + userinfo + tail
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		// url.Error repeats the input, password included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid connection URL: %w", err)
+	}
+
+	if hosts != nil {
+		names := make([]string, len(hosts))
+		ports := make([]string, len(hosts))
+		hasPort := false
+		for i, host := range hosts {
+			names[i], ports[i] = host.Host, host.Port
+			hasPort = hasPort || host.Port != ""
+		}
+		query := u.Query()
+		query.Set("host", strings.Join(names, ","))
+		if hasPort {
+			query.Set("port", strings.Join(ports, ","))
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return &DSN{url: u}, nil
+}
+
+// parseHostList parses the comma-separated "host[:port]" entries of a URL's
+// authority. Hosts may be bracketed IPv6 addresses or percent-encoded.
+func parseHostList(list string) ([]Host, error) {
+	var hosts []Host
+	for _, entry := range strings.Split(list, ",") {
+		var host Host
+		if strings.HasPrefix(entry, "[") {
+			closing := strings.Index(entry, "]")
+			if closing < 0 {
+				return nil, fmt.Errorf("invalid connection URL: missing \"]\" in host %q", entry)
+			}
+			host.Host = entry[1:closing]
+			host.Port = strings.TrimPrefix(entry[closing+1:], ":")
+		} else if colon := strings.LastIndex(entry, ":"); colon >= 0 {
+			host.Host, host.Port = entry[:colon], entry[colon+1:]
+		} else {
+			host.Host = entry
+		}
+
+		decoded, err := url.PathUnescape(host.Host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection URL: invalid host %q", host.Host)
+		}
+		host.Host = decoded
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// Hosts returns the servers the DSN lists, in the order they are tried.
+// Empty when it doesn't set a host, connecting to the default.
+func (d *DSN) Hosts() []Host {
+	var names, ports string
+	if d.url != nil {
+		if d.url.Host != "" {
+			return []Host{{Host: d.url.Hostname(), Port: d.url.Port()}}
+		}
+		query := d.url.Query()
+		names, ports = query.Get("host"), query.Get("port")
+	} else {
+		names, ports = d.get("host"), d.get("port")
+	}
+	if names == "" {
+		return nil
+	}
+
+	// A single port applies to every host
+	portList := strings.Split(ports, ",")
+	var hosts []Host
+	for i, name := range strings.Split(names, ",") {
+		host := Host{Host: name, Port: portList[0]}
+		if len(portList) > 1 && i < len(portList) {
+			host.Port = portList[i]
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// WithHost returns a copy of the DSN connecting to host only, e.g. to the
+// server of a multi-host DSN that turned out to be the primary.
+func (d *DSN) WithHost(host Host) *DSN {
+	if d.url != nil {
+		u := *d.url
+		query := u.Query()
+		if strings.HasPrefix(host.Host, "/") {
+			u.Host = ""
+			query.Set("host", host.Host)
+			query.Set("port", host.Port)
+			if host.Port == "" {
+				query.Del("port")
+			}
+		} else {
+			u.Host = host.String()
+			query.Del("host")
+			query.Del("port")
+		}
+		query.Del("hostaddr")
+		u.RawQuery = query.Encode()
+		return &DSN{url: &u}
+	}
+
+	pinned := &DSN{params: d.with("host", host.Host)}
+	pinned = &DSN{params: pinned.without("hostaddr")}
+	if host.Port == "" {
+		return &DSN{params: pinned.without("port")}
+	}
+	return &DSN{params: pinned.with("port", host.Port)}
+}
+
+// Database returns the database name, or "" if the connection string doesn't set one.
+func (d *DSN) Database() string {
+	if d.url != nil {
+		if name := d.url.Query().Get("dbname"); name != "" {
+			return name
+		}
+		return strings.TrimPrefix(d.url.Path, "/")
+	}
+	return d.get("dbname")
+}
+
+// WithDatabase returns a copy of the DSN connecting to database name instead.
+func (d *DSN) WithDatabase(name string) *DSN {
+	if d.url != nil {
+		u := *d.url
+		u.Path, u.RawPath = "/"+name, ""
+		if query := u.Query(); query.Has("dbname") {
+			query.Set("dbname", name)
+			u.RawQuery = query.Encode()
+		}
+		return &DSN{url: &u}
+	}
+
+	return &DSN{params: d.with("dbname", name)}
+}
+
+// WithParam returns a copy of the DSN with a connection parameter (such as
+// application_name) set, as a query parameter in URL form.
+func (d *DSN) WithParam(key, value string) *DSN {
+	if d.url != nil {
+		u := *d.url
+		query := u.Query()
+		query.Set(key, value)
+		u.RawQuery = query.Encode()
+		return &DSN{url: &u}
+	}
+
+	return &DSN{params: d.with(key, value)}
+}
+
+// String formats the DSN in its original form.
+func (d *DSN) String() string {
+	if d.url != nil {
+		return d.url.String()
+	}
+
+	parts := make([]string, len(d.params))
+	for i, p := range d.params {
+		parts[i] = p.key + "=" + quote(p.value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Redacted formats the DSN with its password masked.
+func (d *DSN) Redacted() string {
+	if d.url != nil {
+		u := *d.url
+		if query := u.Query(); query.Has("password") {
+			query.Set("password", mask)
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+
+	if d.get("password") == "" {
+		return d.String()
+	}
+	return (&DSN{params: d.with("password", mask)}).String()
+}
+
+// WithoutPassword returns a copy of the DSN without its password, and the
+// password, so it can be passed out of band (e.g. as PGPASSWORD).
+func (d *DSN) WithoutPassword() (*DSN, string) {
+	password := d.password()
+
+	if d.url != nil {
+		u := *d.url
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Del("password")
+			u.RawQuery = query.Encode()
+		}
+		return &DSN{url: &u}, password
+	}
+
+	params := make([]param, 0, len(d.params))
+	for _, p := range d.params {
+		if p.key != "password" {
+			params = append(params, p)
+		}
+	}
+	return &DSN{params: params}, password
+}
+
+// password returns the password set in the DSN, if any.
+func (d *DSN) password() string {
+	if d.url != nil {
+		if password := d.url.Query().Get("password"); password != "" {
+			return password
+		}
+		password, _ := d.url.User.Password()
+		return password
+	}
+	return d.get("password")
+}
+
+var (
+	urlPasswordPattern     = regexp.MustCompile(`(://[^:/@\s]*:)[^@\s]*@`)
+	keywordPasswordPattern = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+)
+
+// Redact masks the password in a connection string. Strings that don't parse
+// are masked on a best-effort basis so they can still be logged.
+func Redact(s string) string {
+	if d, err := Parse(s); err == nil {
+		return d.Redacted()
+	}
+
+	s = urlPasswordPattern.ReplaceAllString(s, "${1}"+mask+"@")
+	return keywordPasswordPattern.ReplaceAllString(s, "${1}"+mask)
+}
+
+// RedactError masks the DSN's password wherever it appears in err's message,
+// keeping err available to errors.Is and errors.As.
+func (d *DSN) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	password := d.password()
+	msg := err.Error()
+	if password == "" || !strings.Contains(msg, password) {
+		return err
+	}
+
+	msg = strings.ReplaceAll(msg, d.String(), d.Redacted())
+	msg = strings.ReplaceAll(msg, password, mask)
+	return &redactedError{msg: msg, err: err}
+}
+
+// redactedError is an error whose message had credentials removed.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// get returns the value of a keyword; later occurrences win, as in libpq.
+func (d *DSN) get(key string) string {
+	value := ""
+	for _, p := range d.params {
+		if p.key == key {
+			value = p.value
+		}
+	}
+	return value
+}
+
+// with returns a copy of the keyword params with key set to value.
+func (d *DSN) with(key, value string) []param {
+	params := make([]param, 0, len(d.params)+1)
+	found := false
+	for _, p := range d.params {
+		if p.key == key {
+			if found {
+				continue
+			}
+			p.value, found = value, true
+		}
+		params = append(params, p)
+	}
+	if !found {
+		params = append(params, param{key, value})
+	}
+	return params
+}
+
+// without returns a copy of the keyword params without key.
+func (d *DSN) without(key string) []param {
+	params := make([]param, 0, len(d.params))
+	for _, p := range d.params {
+		if p.key != key {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// parseKeywords parses "key=value" pairs separated by whitespace. Values may
+// be single-quoted; backslash escapes quotes and backslashes.
+func parseKeywords(s string) ([]param, error) {
+	var params []param
+	rest := s
+
+	for {
+		rest = strings.TrimLeft(rest, " \t\n\r")
+		if rest == "" {
+			return params, nil
+		}
+
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid connection string: missing \"=\" after %q", strings.Fields(rest)[0])
+		}
+		key := strings.TrimSpace(rest[:eq])
+		if strings.ContainsAny(key, " \t\n\r") {
+			return nil, fmt.Errorf("invalid connection string: missing \"=\" after %q", strings.Fields(key)[0])
+		}
+		rest = strings.TrimLeft(rest[eq+1:], " \t\n\r")
+
+		var value strings.Builder
+		quoted := strings.HasPrefix(rest, "'")
+		if quoted {
+			rest = rest[1:]
+		}
+
+		i := 0
+		closed := false
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if c == '\\' && i+1 < len(rest) {
+				i++
+				value.WriteByte(rest[i])
+				continue
+			}
+			if quoted && c == '\'' {
+				closed = true
+				i++
+				break
+			}
+			if !quoted && (c == ' ' || c == '\t' || c == '\n' || c == '\r') {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if quoted && !closed {
+			return nil, fmt.Errorf("invalid connection string: unterminated quoted value for %q", key)
+		}
+
+		params = append(params, param{key, value.String()})
+		rest = rest[i:]
+	}
+}
+
+// quote quotes a keyword value when it is empty or contains special characters.
+func quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r'\\") {
+		return value
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
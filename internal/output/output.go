@@ -0,0 +1,202 @@
+// Package output writes the migrator's human-facing messages, filtered by
+// verbosity level.
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Level is the verbosity of a Logger. Messages above the Logger's level are
+// dropped.
+type Level int
+
+const (
+	// LevelSilent prints nothing.
+	LevelSilent Level = iota
+
+	// LevelError prints warnings and errors only.
+	LevelError
+
+	// LevelInfo prints the progress of a run. This is the default.
+	LevelInfo
+
+	// LevelDebug additionally prints per-step detail, such as each migration
+	// replayed on the shadow database.
+	LevelDebug
+)
+
+// Format is how a Logger renders messages.
+type Format int
+
+const (
+	// FormatText writes each message as a line of text.
+	FormatText Format = iota
+
+	// FormatJSON writes each message as a JSON object on its own line, with
+	// "time", "level" and "msg" fields.
+	FormatJSON
+)
+
+// Options configures a Logger.
+type Options struct {
+	// Level is the most verbose level written.
+	Level Level
+
+	// Writer receives the messages. Defaults to os.Stdout.
+	Writer io.Writer
+
+	// Format is how messages are rendered. Defaults to FormatText.
+	Format Format
+
+	// ASCII replaces the symbols in messages with ASCII tags (see
+	// ASCIISymbols) and any other non-ASCII character with '?', for consoles
+	// and log processors that garble UTF-8.
+	ASCII bool
+}
+
+// Logger writes messages at or below its level. A nil *Logger logs text at
+// LevelInfo to stdout.
+type Logger struct {
+	level  Level
+	w      io.Writer
+	format Format
+	ascii  bool
+
+	mu sync.Mutex
+}
+
+// New creates a Logger printing text messages up to level on stdout.
+func New(level Level) *Logger {
+	return NewWithOptions(Options{Level: level})
+}
+
+// NewWithOptions creates a Logger with custom options.
+func NewWithOptions(opts Options) *Logger {
+	w := opts.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &Logger{level: opts.Level, w: w, format: opts.Format, ascii: opts.ASCII}
+}
+
+// Warnf prints a warning, shown at LevelError and above.
+func (l *Logger) Warnf(format string, args ...any) {
+	l.write(LevelError, "warn", "⚠️  Warning: ", fmt.Sprintf(format, args...))
+}
+
+// Infof prints a progress message, shown at LevelInfo and above.
+func (l *Logger) Infof(format string, args ...any) {
+	l.write(LevelInfo, "info", "", fmt.Sprintf(format, args...))
+}
+
+// Debugf prints a detail message, shown at LevelDebug only.
+func (l *Logger) Debugf(format string, args ...any) {
+	l.write(LevelDebug, "debug", "", fmt.Sprintf(format, args...))
+}
+
+// write writes msg if level is enabled. In text format the message is written
+// after prefix; in JSON the level name replaces the prefix, and indentation,
+// which only makes sense in a terminal, is dropped.
+func (l *Logger) write(level Level, name, prefix, msg string) {
+	if l == nil {
+		l = New(LevelInfo)
+	}
+	if level > l.level {
+		return
+	}
+
+	if l.ascii {
+		prefix, msg = ToASCII(prefix), ToASCII(msg)
+	}
+
+	line := []byte(prefix + msg)
+	if l.format == FormatJSON {
+		line, _ = json.Marshal(struct {
+			Time  time.Time `json:"time"`
+			Level string    `json:"level"`
+			Msg   string    `json:"msg"`
+		}{time.Now().UTC(), name, strings.TrimSpace(msg)})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(line, '\n'))
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying l, for code that only receives a
+// context, such as backup hooks.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or nil (which logs at
+// LevelInfo) if there is none.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(contextKey{}).(*Logger)
+	return l
+}
+
+// ASCIISymbols lists the symbols used in messages with their ASCII
+// renderings. Every symbol printed by the migrator must be listed here.
+var ASCIISymbols = [][2]string{
+	{"⚠️  Warning:", "WARNING:"},
+	{"✓", "[ok]"},
+	{"✅", "[ok]"},
+	{"⚠️", "[warn]"},
+	{"⏳", "[wait]"},
+	{"🚀", "[apply]"},
+	{"🔍", "[check]"},
+	{"🔎", "[lint]"},
+	{"🔒", "[lock]"},
+	{"🧪", "[test]"},
+	{"🧩", "[ext]"},
+	{"🧹", "[cleanup]"},
+	{"🗑️", "[drop]"},
+	{"🏗️", "[create]"},
+	{"📦", "[stored]"},
+	{"📥", "[copy]"},
+	{"📍", "[wal]"},
+	{"📊", "[analyze]"},
+	{"🔄", "[refresh]"},
+	{"🔁", "[alter]"},
+	{"↻", "[batch]"},
+	{"💾", "[backup]"},
+	{"♻️", "[restore]"},
+	{"📣", "[notify]"},
+	{"⏱️", "[time]"},
+	{"🐤", "[canary]"},
+}
+
+// asciiReplacer replaces the symbols in ASCIISymbols, collapsing the double
+// space that follows wide symbols so columns stay aligned.
+var asciiReplacer = func() *strings.Replacer {
+	var pairs []string
+	for _, symbol := range ASCIISymbols {
+		pairs = append(pairs, symbol[0]+"  ", symbol[1]+" ", symbol[0], symbol[1])
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// ToASCII renders msg in plain ASCII: known symbols become tags and any
+// other non-ASCII character becomes '?'.
+func ToASCII(msg string) string {
+	msg = asciiReplacer.Replace(msg)
+
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return '?'
+		}
+		return r
+	}, msg)
+}
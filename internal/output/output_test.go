@@ -0,0 +1,70 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"✓ Applied 001_init.sql", "[ok] Applied 001_init.sql"},
+		{"⚠️  Warning: replica lag", "WARNING: replica lag"},
+		{"🗑️  Cleaning up shadow database app_shadow...", "[drop] Cleaning up shadow database app_shadow..."},
+		{"  🧪 Testing migration: 002_users.sql", "  [test] Testing migration: 002_users.sql"},
+		{"failed on müşteri", "failed on m??teri"},
+	}
+
+	for _, tt := range tests {
+		if got := ToASCII(tt.in); got != tt.want {
+			t.Errorf("ToASCII(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLogger_ASCII(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithOptions(Options{Level: LevelInfo, Writer: &buf, ASCII: true})
+	l.Warnf("Failed to send %s notification", "run_completed")
+	l.Infof("✅ Successfully created database: %s", "app_shadow")
+
+	want := "WARNING: Failed to send run_completed notification\n[ok] Successfully created database: app_shadow\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// TestASCIISymbols_Complete checks that every symbol in a logged message has
+// an ASCII rendering.
+func TestASCIISymbols_Complete(t *testing.T) {
+	files, err := filepath.Glob("../../*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal, err := filepath.Glob("../*/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := regexp.MustCompile(`\.(?:Warnf|Infof|Debugf)\("((?:[^"\\]|\\.)*)"`)
+	for _, file := range append(files, internal...) {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range call.FindAllStringSubmatch(string(content), -1) {
+			if strings.Contains(ToASCII(match[1]), "?") && !strings.Contains(match[1], "?") {
+				t.Errorf("%s: message %q has a symbol missing from ASCIISymbols", file, match[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package shadowdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// CloneOptions configures Clone.
+type CloneOptions struct {
+	// SampleRows copies up to this many rows of each table from the main
+	// database. Zero copies the schema only.
+	SampleRows int
+
+	// Replace drops an existing database of the same name first.
+	Replace bool
+
+	// Skipped are recorded as skipped in the clone, so migrating the clone
+	// doesn't apply them either.
+	Skipped []tracker.Skip
+}
+
+// Clone creates the database name with the schema of the main database, by
+// replaying the applied migrations (names in apply order) from files the same
+// way TestNewMigrations does, and returns its connection string. Unlike the
+// shadow database, the clone is kept; it is dropped again only when cloning
+// fails. Credentials are masked in the returned error.
+func (m *Manager) Clone(ctx context.Context, mainTracker *tracker.Tracker, name string, applied []string, files []*validator.MigrationFile, opts CloneOptions) (string, error) {
+	if err := m.clone(ctx, mainTracker, name, applied, files, opts); err != nil {
+		return "", m.dsn.RedactError(err)
+	}
+	return m.dsn.WithDatabase(name).String(), nil
+}
+
+func (m *Manager) clone(ctx context.Context, mainTracker *tracker.Tracker, name string, applied []string, files []*validator.MigrationFile, opts CloneOptions) error {
+	postgresDB, err := m.connectToPostgresDatabase(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	defer postgresDB.Close()
+
+	var exists bool
+	if err := postgresDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check if database %s exists: %w", name, err)
+	}
+	if exists {
+		if !opts.Replace {
+			return fmt.Errorf("database %s already exists", name)
+		}
+		if err := m.dropDatabaseIfExists(ctx, postgresDB, name); err != nil {
+			return err
+		}
+	}
+
+	if err := m.createDatabase(ctx, postgresDB, name); err != nil {
+		return err
+	}
+	if _, err := postgresDB.ExecContext(ctx, fmt.Sprintf("COMMENT ON DATABASE %s IS %s", pq.QuoteIdentifier(name), pq.QuoteLiteral(cloneComment))); err != nil {
+		m.log.Warnf("Failed to mark %s as a clone: %v", name, err)
+	}
+
+	cloned := false
+	defer func() {
+		if cloned {
+			return
+		}
+		bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.cleanupTimeout)
+		defer cancel()
+		if err := m.dropDatabaseIfExists(bgCtx, postgresDB, name); err != nil {
+			m.log.Warnf("Failed to clean up clone %s: %v", name, m.dsn.RedactError(err))
+		}
+	}()
+
+	cloneDB, err := m.connectToDatabase(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to connect to clone %s: %w", name, err)
+	}
+	defer cloneDB.Close()
+
+	for _, extension := range m.extensions {
+		if _, err := cloneDB.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS "+pq.QuoteIdentifier(extension)); err != nil {
+			return fmt.Errorf("failed to install extension %s in clone: %w", extension, err)
+		}
+	}
+
+	cloneTracker := mainTracker.WithDB(cloneDB)
+	if err := cloneTracker.EnsureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to create migrations table in clone: %w", err)
+	}
+
+	m.log.Infof("🏗️  Cloning schema into %s: replaying %d migrations", name, len(applied))
+	if err := m.applyExistingMigrationsToShadow(ctx, mainTracker, cloneTracker, applied, files); err != nil {
+		return fmt.Errorf("failed to apply existing migrations to clone: %w", err)
+	}
+	for _, skip := range opts.Skipped {
+		if err := cloneTracker.RecordSkip(ctx, skip.Name, skip.Reason); err != nil {
+			return err
+		}
+	}
+
+	if opts.SampleRows > 0 {
+		if err := m.sampleData(ctx, cloneDB, opts.SampleRows); err != nil {
+			return err
+		}
+	}
+
+	cloned = true
+	return nil
+}
+
+// cloneComment marks databases created by Clone, so DropClone can't drop
+// anything else.
+const cloneComment = "clone created by migrator"
+
+// DropClone drops the database name, which must have been created by Clone.
+// Credentials are masked in the returned error.
+func (m *Manager) DropClone(ctx context.Context, name string) error {
+	postgresDB, err := m.connectToPostgresDatabase(ctx)
+	if err != nil {
+		return m.dsn.RedactError(fmt.Errorf("failed to connect to postgres database: %w", err))
+	}
+	defer postgresDB.Close()
+
+	var comment sql.NullString
+	err = postgresDB.QueryRowContext(ctx, "SELECT shobj_description(oid, 'pg_database') FROM pg_database WHERE datname = $1", name).Scan(&comment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return m.dsn.RedactError(fmt.Errorf("failed to check database %s: %w", name, err))
+	}
+	if comment.String != cloneComment {
+		return fmt.Errorf("database %s was not created by Clone", name)
+	}
+
+	return m.dsn.RedactError(m.dropDatabaseIfExists(ctx, postgresDB, name))
+}
+
+// cloneTable is a table of the clone, with the foreign keys it declares.
+type cloneTable struct {
+	name        string // schema-qualified and quoted
+	foreignKeys []cloneForeignKey
+}
+
+// cloneForeignKey is a foreign key, with quoted column names.
+type cloneForeignKey struct {
+	parent      string // schema-qualified and quoted
+	columns     []string
+	parentNames []string
+}
+
+// sampleData copies up to rows rows of each table of the main database into
+// the clone, parents before children. Rows referencing rows that weren't
+// copied are left out, so foreign keys hold, as are rows conflicting with
+// rows seeded by migrations. Sequences are advanced to their values in the
+// main database.
+func (m *Manager) sampleData(ctx context.Context, cloneDB *sql.DB, rows int) error {
+	tables, err := cloneTables(ctx, cloneDB)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		var sample string
+		err := m.mainDB.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT COALESCE(json_agg(t), '[]') FROM (SELECT * FROM %s LIMIT %d) t", table.name, rows,
+		)).Scan(&sample)
+		if err != nil {
+			m.log.Warnf("Skipping data of %s: %v", table.name, err)
+			continue
+		}
+
+		columns, err := insertableColumns(ctx, cloneDB, table.name)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		list := strings.Join(columns, ", ")
+		selected := "r." + strings.Join(columns, ", r.")
+
+		conditions := []string{"true"}
+		for _, fk := range table.foreignKeys {
+			var nulls, matches []string
+			for i, column := range fk.columns {
+				nulls = append(nulls, "r."+column+" IS NULL")
+				matches = append(matches, fmt.Sprintf("p.%s = r.%s", fk.parentNames[i], column))
+			}
+			conditions = append(conditions, fmt.Sprintf("(%s OR EXISTS (SELECT 1 FROM %s p WHERE %s))",
+				strings.Join(nulls, " OR "), fk.parent, strings.Join(matches, " AND ")))
+		}
+
+		res, err := cloneDB.ExecContext(ctx, fmt.Sprintf(
+			"INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE SELECT %s FROM json_populate_recordset(NULL::%s, $1) r WHERE %s ON CONFLICT DO NOTHING",
+			table.name, list, selected, table.name, strings.Join(conditions, " AND "),
+		), sample)
+		if err != nil {
+			return fmt.Errorf("failed to copy rows of %s: %w", table.name, err)
+		}
+		copied, _ := res.RowsAffected()
+		m.log.Debugf("  📥 Copied %d rows of %s", copied, table.name)
+	}
+
+	// Rows keep their ids; new ones shouldn't collide with them
+	sequences, err := m.mainDB.QueryContext(ctx, `
+		SELECT format('%I.%I', schemaname, sequencename), last_value
+		FROM pg_sequences WHERE last_value IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read sequences: %w", err)
+	}
+	defer sequences.Close()
+
+	for sequences.Next() {
+		var sequence string
+		var value int64
+		if err := sequences.Scan(&sequence, &value); err != nil {
+			return fmt.Errorf("failed to scan sequence: %w", err)
+		}
+		if _, err := cloneDB.ExecContext(ctx, "SELECT setval(to_regclass($1), $2) WHERE to_regclass($1) IS NOT NULL", sequence, value); err != nil {
+			return fmt.Errorf("failed to advance sequence %s: %w", sequence, err)
+		}
+	}
+	if err := sequences.Err(); err != nil {
+		return fmt.Errorf("error iterating sequences: %w", err)
+	}
+
+	m.log.Infof("📥 Copied sample data of %d tables", len(tables))
+	return nil
+}
+
+// cloneTables returns the tables of the clone outside the system schemas,
+// excluding partitions and the tracking tables, ordered so that tables
+// referenced by foreign keys come before the tables referencing them.
+// Tables in a reference cycle keep their name order.
+func cloneTables(ctx context.Context, db *sql.DB) ([]cloneTable, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT format('%I.%I', n.nspname, c.relname)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'
+			AND c.relname NOT LIKE '\_go\_migrations%'
+		ORDER BY 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables of clone: %w", err)
+	}
+	var tables []cloneTable
+	for rows.Next() {
+		var table cloneTable
+		if err := rows.Scan(&table.name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	for i := range tables {
+		tables[i].foreignKeys, err = foreignKeys(ctx, db, tables[i].name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Repeatedly take the tables whose parents are all taken; what remains
+	// is cyclic and taken as is
+	var ordered []cloneTable
+	taken := map[string]bool{}
+	for len(ordered) < len(tables) {
+		progressed := false
+		for _, table := range tables {
+			if taken[table.name] {
+				continue
+			}
+			ready := true
+			for _, fk := range table.foreignKeys {
+				if fk.parent != table.name && !taken[fk.parent] {
+					ready = false
+				}
+			}
+			if ready {
+				ordered = append(ordered, table)
+				taken[table.name] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			for _, table := range tables {
+				if !taken[table.name] {
+					ordered = append(ordered, table)
+					taken[table.name] = true
+				}
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+// foreignKeys returns the foreign keys declared by table.
+func foreignKeys(ctx context.Context, db *sql.DB, table string) ([]cloneForeignKey, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT format('%I.%I', pn.nspname, p.relname),
+			ARRAY(SELECT quote_ident(a.attname) FROM unnest(con.conkey) WITH ORDINALITY k(attnum, i)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.i),
+			ARRAY(SELECT quote_ident(a.attname) FROM unnest(con.confkey) WITH ORDINALITY k(attnum, i)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.i)
+		FROM pg_constraint con
+		JOIN pg_class p ON p.oid = con.confrelid
+		JOIN pg_namespace pn ON pn.oid = p.relnamespace
+		WHERE con.contype = 'f' AND con.conrelid = $1::regclass
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var fks []cloneForeignKey
+	for rows.Next() {
+		var fk cloneForeignKey
+		if err := rows.Scan(&fk.parent, pq.Array(&fk.columns), pq.Array(&fk.parentNames)); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		fks = append(fks, fk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign keys: %w", err)
+	}
+
+	return fks, nil
+}
+
+// insertableColumns returns the quoted names of the columns of table that
+// accept values, leaving out generated columns.
+func insertableColumns(ctx context.Context, db *sql.DB, table string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT quote_ident(c.column_name)
+		FROM information_schema.columns c
+		WHERE format('%I.%I', c.table_schema, c.table_name) = $1 AND c.is_generated = 'NEVER'
+		ORDER BY c.ordinal_position
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
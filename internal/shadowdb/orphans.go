@@ -0,0 +1,87 @@
+package shadowdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// shadowCommentPrefix starts the comment recording when a shadow database
+// was created, followed by the time in RFC 3339.
+const shadowCommentPrefix = "shadow database created by migrator at "
+
+// Orphan is a shadow database found on the server, of any migrated database.
+type Orphan struct {
+	Name string
+
+	// CreatedAt is when the database was created. Zero for shadow databases
+	// created before creation times were recorded.
+	CreatedAt time.Time
+
+	// Connections is the number of sessions connected to the database; a
+	// shadow database with connections is likely being tested on.
+	Connections int
+}
+
+// Orphans lists the shadow databases on the server, of all migrated
+// databases, by name. Credentials are masked in the returned error.
+func (m *Manager) Orphans(ctx context.Context) ([]Orphan, error) {
+	orphans, err := m.orphans(ctx)
+	return orphans, m.dsn.RedactError(err)
+}
+
+func (m *Manager) orphans(ctx context.Context) ([]Orphan, error) {
+	postgresDB, err := m.connectToPostgresDatabase(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	defer postgresDB.Close()
+
+	rows, err := postgresDB.QueryContext(ctx, `
+		SELECT d.datname, shobj_description(d.oid, 'pg_database'),
+			(SELECT COUNT(*) FROM pg_stat_activity a WHERE a.datname = d.datname)
+		FROM pg_database d
+		WHERE right(d.datname, length($1)) = $1
+		ORDER BY d.datname
+	`, ShadowSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shadow databases: %w", err)
+	}
+	defer rows.Close()
+
+	var orphans []Orphan
+	for rows.Next() {
+		var orphan Orphan
+		var comment sql.NullString
+		if err := rows.Scan(&orphan.Name, &comment, &orphan.Connections); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow database: %w", err)
+		}
+		if created, ok := strings.CutPrefix(comment.String, shadowCommentPrefix); ok {
+			orphan.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		}
+		orphans = append(orphans, orphan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shadow databases: %w", err)
+	}
+
+	return orphans, nil
+}
+
+// DropOrphan drops the shadow database name, terminating its connections.
+// Credentials are masked in the returned error.
+func (m *Manager) DropOrphan(ctx context.Context, name string) error {
+	if !strings.HasSuffix(name, ShadowSuffix) {
+		return fmt.Errorf("database %s is not a shadow database", name)
+	}
+
+	postgresDB, err := m.connectToPostgresDatabase(ctx)
+	if err != nil {
+		return m.dsn.RedactError(fmt.Errorf("failed to connect to postgres database: %w", err))
+	}
+	defer postgresDB.Close()
+
+	return m.dsn.RedactError(m.dropDatabaseIfExists(ctx, postgresDB, name))
+}
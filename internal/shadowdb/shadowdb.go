@@ -4,53 +4,234 @@ package shadowdb
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
-	"os"
+	"hash/crc32"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/lib/pq"
+
+	"github.com/hasirciogluhq/migrator/internal/dsn"
+	"github.com/hasirciogluhq/migrator/internal/output"
 	"github.com/hasirciogluhq/migrator/internal/tracker"
 	"github.com/hasirciogluhq/migrator/internal/validator"
 )
 
+// MigrationError reports a migration that failed on the shadow database.
+type MigrationError struct {
+	Name string
+	Err  error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migration %s failed on shadow database: %v", e.Name, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// TestResult is the outcome of testing one new migration on the shadow
+// database.
+type TestResult struct {
+	Name     string
+	Duration time.Duration
+
+	// Err is the migration's failure, nil if it passed.
+	Err error
+}
+
+// ShadowSuffix is appended to the name of the migrated database to name its
+// shadow database.
+const ShadowSuffix = "_gi_mig_shadow_db"
+
+// DefaultCleanupTimeout bounds dropping the shadow database after a test,
+// which proceeds even when the test's context was cancelled, unless
+// Options.CleanupTimeout is set.
+const DefaultCleanupTimeout = 30 * time.Second
+
 // Manager manages shadow database operations.
 type Manager struct {
-	mainDB        *sql.DB
-	currentDBName string
-	shadowDBName  string
-	databaseURL   string
+	mainDB         tracker.DB
+	currentDBName  string
+	shadowDBName   string
+	migrationsPath string
+	dsn            *dsn.DSN
+	resolver       dsn.Resolver
+	resolved       string
+	appName        string
+	host           *dsn.Host
+	credentials    dsn.Credentials
+	configureDB    func(*sql.DB)
+	driverName     string
+	driver         driver.Driver
+	setupTimeout   time.Duration
+	testTimeout    time.Duration
+	cleanupTimeout time.Duration
+	extensions     []string
+	onTest         func(migration string, index, total int)
+	profile        bool
+	profiles       []tracker.StatementProfile
+	rowCounts      []tracker.RowCount
+	results        []TestResult
+	log            *output.Logger
 }
 
-// NewWithURL creates a new shadow database Manager with explicit database URL.
-func NewWithURL(mainDB *sql.DB, databaseURL string) (*Manager, error) {
-	if databaseURL == "" {
+// Options configures a Manager.
+type Options struct {
+	// DatabaseURL is the connection string shadow databases are created
+	// through, in URL or keyword/value form.
+	DatabaseURL string
+
+	// Resolver returns the connection string each time the Manager connects,
+	// so rotated credentials are picked up. It takes precedence over
+	// DatabaseURL.
+	Resolver dsn.Resolver
+
+	// MigrationsPath is the directory applied migrations are read from when
+	// their files are not passed in. Defaults to "./migrations".
+	MigrationsPath string
+
+	// ApplicationName is the application_name of the connections.
+	ApplicationName string
+
+	// Credentials supplies the password of each new connection, for tokens
+	// that expire during long runs.
+	Credentials dsn.Credentials
+
+	// ConfigureDB is called with each connection pool the Manager opens,
+	// e.g. to set pool sizes and connection lifetimes.
+	ConfigureDB func(*sql.DB)
+
+	// DriverName is the database/sql driver connections are opened with,
+	// e.g. "pgx" when the program registers the pgx stdlib driver instead of
+	// lib/pq. Empty uses lib/pq.
+	DriverName string
+
+	// SetupTimeout bounds creating the shadow database and replaying the
+	// applied migrations; TestTimeout bounds testing the new migrations.
+	// Zero or negative means no limit.
+	SetupTimeout time.Duration
+	TestTimeout  time.Duration
+
+	// CleanupTimeout bounds dropping shadow databases and failed clones.
+	// Defaults to DefaultCleanupTimeout.
+	CleanupTimeout time.Duration
+
+	// Logger receives progress messages.
+	Logger *output.Logger
+}
+
+// New creates a new shadow database Manager connecting through databaseURL.
+func New(mainDB tracker.DB, databaseURL string) (*Manager, error) {
+	return NewWithOptions(mainDB, Options{DatabaseURL: databaseURL})
+}
+
+// NewWithOptions creates a new shadow database Manager with custom options.
+func NewWithOptions(mainDB tracker.DB, opts Options) (*Manager, error) {
+	if opts.DatabaseURL == "" && opts.Resolver == nil {
 		return nil, fmt.Errorf("database URL is required for shadow database operations")
 	}
 
+	// With a resolver, the DSN is parsed when connecting
+	parsed, err := dsn.Parse(opts.DatabaseURL)
+	if opts.Resolver != nil {
+		parsed, err = dsn.Parse("")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	if opts.ApplicationName != "" {
+		parsed = parsed.WithParam("application_name", opts.ApplicationName)
+	}
+
+	migrationsPath := opts.MigrationsPath
+	if migrationsPath == "" {
+		migrationsPath = "./migrations"
+	}
+
+	cleanupTimeout := opts.CleanupTimeout
+	if cleanupTimeout <= 0 {
+		cleanupTimeout = DefaultCleanupTimeout
+	}
+
 	return &Manager{
-		mainDB:      mainDB,
-		databaseURL: databaseURL,
+		mainDB:         mainDB,
+		migrationsPath: migrationsPath,
+		dsn:            parsed,
+		resolver:       opts.Resolver,
+		appName:        opts.ApplicationName,
+		credentials:    opts.Credentials,
+		configureDB:    opts.ConfigureDB,
+		driverName:     opts.DriverName,
+		setupTimeout:   opts.SetupTimeout,
+		testTimeout:    opts.TestTimeout,
+		cleanupTimeout: cleanupTimeout,
+		log:            opts.Logger,
 	}, nil
 }
 
-// New creates a new shadow database Manager using DATABASE_URL environment variable.
-// Deprecated: Use NewWithURL instead for more explicit configuration.
-func New(mainDB *sql.DB) (*Manager, error) {
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL environment variable not set")
+// SetExtensions sets the extensions installed in the shadow database before
+// any migration is replayed, for extensions installed out-of-band in production.
+func (m *Manager) SetExtensions(names []string) {
+	m.extensions = names
+}
+
+// SetOnTest sets a function called before each new migration is tested on
+// the shadow database, with its 1-based index among the new migrations.
+func (m *Manager) SetOnTest(fn func(migration string, index, total int)) {
+	m.onTest = fn
+}
+
+// SetProfile enables timing each statement of the tested migrations and
+// capturing the plans of DML statements.
+func (m *Manager) SetProfile(enabled bool) {
+	m.profile = enabled
+}
+
+// Profiles returns the statement profiles of the last test, in execution
+// order. Empty unless profiling is enabled.
+func (m *Manager) Profiles() []tracker.StatementProfile {
+	return m.profiles
+}
+
+// RowCounts returns the rows affected by the DML statements of the migrations
+// that passed the last test, in execution order.
+func (m *Manager) RowCounts() []tracker.RowCount {
+	return m.rowCounts
+}
+
+// Results returns the outcome of each new migration tested by the last
+// test, in order. Migrations after a failure are not tested and not listed.
+// Credentials are masked in the errors.
+func (m *Manager) Results() []TestResult {
+	results := make([]TestResult, len(m.results))
+	for i, result := range m.results {
+		result.Err = m.dsn.RedactError(result.Err)
+		results[i] = result
 	}
+	return results
+}
 
-	return NewWithURL(mainDB, databaseURL)
+// TestNewMigrations tests new migrations on a shadow database, after
+// replaying the applied migrations (names in apply order) from files. Applied
+// migrations without a file are replayed from their stored content.
+// Credentials are masked in the returned error.
+func (m *Manager) TestNewMigrations(ctx context.Context, mainTracker *tracker.Tracker, applied []string, files, newMigrations []*validator.MigrationFile) error {
+	return m.dsn.RedactError(m.testNewMigrations(ctx, mainTracker, applied, files, newMigrations))
 }
 
-// TestNewMigrations tests new migrations on a shadow database.
-func (m *Manager) TestNewMigrations(ctx context.Context, mainTracker *tracker.Tracker, newMigrations []*validator.MigrationFile) error {
+func (m *Manager) testNewMigrations(ctx context.Context, mainTracker *tracker.Tracker, applied []string, files, newMigrations []*validator.MigrationFile) error {
+	m.profiles, m.rowCounts, m.results = nil, nil, nil
 	if len(newMigrations) == 0 {
-		fmt.Println("✓ No new migrations found, skipping shadow database test")
+		m.log.Infof("✓ No new migrations found, skipping shadow database test")
 		return nil
 	}
 
-	fmt.Printf("🔍 Found %d new migrations, testing on shadow database...\n", len(newMigrations))
+	m.log.Infof("🔍 Found %d new migrations, testing on shadow database...", len(newMigrations))
 
 	// Get current database name
 	currentDBName, err := getCurrentDatabaseName(ctx, m.mainDB)
@@ -58,59 +239,96 @@ func (m *Manager) TestNewMigrations(ctx context.Context, mainTracker *tracker.Tr
 		return fmt.Errorf("failed to get current database name: %w", err)
 	}
 	m.currentDBName = currentDBName
-	m.shadowDBName = currentDBName + "_gi_mig_shadow_db"
+	m.shadowDBName = shadowDatabaseName(currentDBName)
 
 	// Setup shadow database
-	shadowDB, cleanup, err := m.setupShadowDatabase(ctx)
+	setupCtx, cancelSetup := withPhaseTimeout(ctx, "shadow database setup", m.setupTimeout)
+	defer cancelSetup()
+	shadowDB, cleanup, err := m.setupShadowDatabase(setupCtx)
 	if err != nil {
-		return fmt.Errorf("failed to setup shadow database: %w", err)
+		return phaseError(setupCtx, fmt.Errorf("failed to setup shadow database: %w", err))
 	}
 	defer cleanup()
 
-	// Create shadow tracker
-	shadowTracker := tracker.New(shadowDB)
-	if err := shadowTracker.EnsureMigrationsTable(ctx); err != nil {
-		return fmt.Errorf("failed to create migrations table in shadow: %w", err)
+	for _, name := range m.extensions {
+		m.log.Debugf("  🧩 Installing extension %s in shadow database", name)
+		if _, err := shadowDB.ExecContext(setupCtx, "CREATE EXTENSION IF NOT EXISTS "+pq.QuoteIdentifier(name)); err != nil {
+			return phaseError(setupCtx, fmt.Errorf("failed to install extension %s in shadow database: %w", name, err))
+		}
+	}
+
+	// Create shadow tracker, applying migrations with the same settings as production
+	shadowTracker := mainTracker.WithDB(shadowDB)
+	if err := shadowTracker.EnsureMigrationsTable(setupCtx); err != nil {
+		return phaseError(setupCtx, fmt.Errorf("failed to create migrations table in shadow: %w", err))
 	}
 
 	// Apply existing migrations to shadow database
-	if err := m.applyExistingMigrationsToShadow(ctx, mainTracker, shadowTracker); err != nil {
-		return fmt.Errorf("failed to apply existing migrations to shadow: %w", err)
+	if err := m.applyExistingMigrationsToShadow(setupCtx, mainTracker, shadowTracker, applied, files); err != nil {
+		return phaseError(setupCtx, fmt.Errorf("failed to apply existing migrations to shadow: %w", err))
 	}
+	cancelSetup()
 
 	// Test new migrations on shadow database
-	if err := m.testMigrationsOnShadow(ctx, shadowDB, newMigrations); err != nil {
-		return fmt.Errorf("failed to test migrations on shadow: %w", err)
+	testCtx, cancelTest := withPhaseTimeout(ctx, "shadow database test", m.testTimeout)
+	defer cancelTest()
+	if err := m.testMigrationsOnShadow(testCtx, mainTracker, shadowTracker, newMigrations); err != nil {
+		return phaseError(testCtx, fmt.Errorf("failed to test migrations on shadow: %w", err))
 	}
 
-	fmt.Println("✓ Shadow database test passed")
+	m.log.Infof("✓ Shadow database test passed")
 	return nil
 }
 
+// withPhaseTimeout bounds ctx by timeout, if positive, for phaseError to
+// name phase when it expires.
+func withPhaseTimeout(ctx context.Context, phase string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%s exceeded its %s timeout: %w", phase, timeout, context.DeadlineExceeded))
+}
+
+// phaseError names the phase of phaseCtx in err when its timeout expired,
+// since drivers only report a cancelled query.
+func phaseError(phaseCtx context.Context, err error) error {
+	cause := context.Cause(phaseCtx)
+	if phaseCtx.Err() != context.DeadlineExceeded || cause == phaseCtx.Err() || errors.Is(err, cause) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", cause, err)
+}
+
 // setupShadowDatabase creates and configures a shadow database for testing.
 func (m *Manager) setupShadowDatabase(ctx context.Context) (*sql.DB, func(), error) {
 	// Connect to postgres database for management
-	postgresDB, err := m.connectToPostgresDatabase()
+	postgresDB, err := m.connectToPostgresDatabase(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to postgres database: %w", err)
 	}
 
-	fmt.Println("🧹 Cleaning up any previous shadow database before testing...")
+	m.log.Debugf("🧹 Cleaning up any previous shadow database before testing...")
 
 	// Clean up existing shadow database
-	if err := dropDatabaseIfExists(ctx, postgresDB, m.shadowDBName); err != nil {
+	if err := m.dropDatabaseIfExists(ctx, postgresDB, m.shadowDBName); err != nil {
 		postgresDB.Close()
 		return nil, nil, fmt.Errorf("failed to drop existing shadow database: %w", err)
 	}
 
 	// Create new shadow database
-	if err := createDatabase(ctx, postgresDB, m.shadowDBName); err != nil {
+	if err := m.createDatabase(ctx, postgresDB, m.shadowDBName); err != nil {
 		postgresDB.Close()
 		return nil, nil, fmt.Errorf("failed to create shadow database: %w", err)
 	}
 
+	// Record when it was created, for Orphans
+	comment := shadowCommentPrefix + time.Now().UTC().Format(time.RFC3339)
+	if _, err := postgresDB.ExecContext(ctx, fmt.Sprintf("COMMENT ON DATABASE %s IS %s", pq.QuoteIdentifier(m.shadowDBName), pq.QuoteLiteral(comment))); err != nil {
+		m.log.Debugf("  Could not record creation time of %s: %v", m.shadowDBName, err)
+	}
+
 	// Connect to shadow database
-	shadowDB, err := m.connectToDatabase(m.shadowDBName)
+	shadowDB, err := m.connectToDatabase(ctx, m.shadowDBName)
 	if err != nil {
 		postgresDB.Close()
 		return nil, nil, fmt.Errorf("failed to connect to shadow database: %w", err)
@@ -120,11 +338,12 @@ func (m *Manager) setupShadowDatabase(ctx context.Context) (*sql.DB, func(), err
 	cleanup := func() {
 		shadowDB.Close()
 
-		// Clean up shadow database with background context
-		bgCtx := context.Background()
-		fmt.Printf("🗑️  Cleaning up shadow database %s...\n", m.shadowDBName)
-		if err := dropDatabaseIfExists(bgCtx, postgresDB, m.shadowDBName); err != nil {
-			fmt.Printf("⚠️  Warning: Failed to clean up shadow database %s: %v\n", m.shadowDBName, err)
+		// Clean up even if the run was cancelled, but don't hang on it
+		bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.cleanupTimeout)
+		defer cancel()
+		m.log.Debugf("🗑️  Cleaning up shadow database %s...", m.shadowDBName)
+		if err := m.dropDatabaseIfExists(bgCtx, postgresDB, m.shadowDBName); err != nil {
+			m.log.Warnf("Failed to clean up shadow database %s: %v", m.shadowDBName, m.dsn.RedactError(err))
 		}
 
 		postgresDB.Close()
@@ -134,26 +353,24 @@ func (m *Manager) setupShadowDatabase(ctx context.Context) (*sql.DB, func(), err
 }
 
 // applyExistingMigrationsToShadow applies all existing migrations to shadow database.
-func (m *Manager) applyExistingMigrationsToShadow(ctx context.Context, mainTracker, shadowTracker *tracker.Tracker) error {
-	appliedMigrations, err := mainTracker.GetAppliedMigrations(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
-	}
-
-	// Get migrations path
-	migrationsPath := os.Getenv("MIGRATIONS_PATH")
-	if migrationsPath == "" {
-		migrationsPath = "./migrations"
+func (m *Manager) applyExistingMigrationsToShadow(ctx context.Context, mainTracker, shadowTracker *tracker.Tracker, applied []string, files []*validator.MigrationFile) error {
+	byName := make(map[string]*validator.MigrationFile, len(files))
+	for _, file := range files {
+		byName[file.Name] = file
 	}
 
 	// Apply each existing migration to shadow
-	for _, migrationName := range appliedMigrations {
-		content, err := os.ReadFile(migrationsPath + "/" + migrationName)
+	for _, migrationName := range applied {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		migration, err := m.appliedMigration(ctx, mainTracker, byName[migrationName], migrationName)
 		if err != nil {
-			return fmt.Errorf("failed to read migration %s: %w", migrationName, err)
+			return err
 		}
 
-		if err := shadowTracker.ApplyMigration(ctx, migrationName, string(content)); err != nil {
+		if _, err := shadowTracker.ApplyMigration(ctx, migration); err != nil {
 			return fmt.Errorf("failed to apply existing migration %s to shadow: %w", migrationName, err)
 		}
 	}
@@ -161,25 +378,113 @@ func (m *Manager) applyExistingMigrationsToShadow(ctx context.Context, mainTrack
 	return nil
 }
 
-// testMigrationsOnShadow tests new migrations on shadow database.
-func (m *Manager) testMigrationsOnShadow(ctx context.Context, shadowDB *sql.DB, migrations []*validator.MigrationFile) error {
-	shadowTracker := tracker.New(shadowDB)
+// appliedMigration prepares an applied migration for replay from its file,
+// falling back to the content stored in the tracking table when the file is
+// gone.
+func (m *Manager) appliedMigration(ctx context.Context, mainTracker *tracker.Tracker, file *validator.MigrationFile, migrationName string) (tracker.Migration, error) {
+	var content, checksum string
+	var compressed bool
+	if file != nil {
+		if err := file.Load(); err != nil {
+			return tracker.Migration{}, err
+		}
+		content, checksum, compressed = file.Content, file.Checksum(), file.Compressed()
+	} else {
+		stored, ok, err := mainTracker.GetContent(ctx, migrationName)
+		if err != nil {
+			return tracker.Migration{}, fmt.Errorf("failed to read stored content of migration %s: %w", migrationName, err)
+		}
+		if !ok {
+			return tracker.Migration{}, fmt.Errorf("failed to read migration %s: file not found and no stored content", migrationName)
+		}
 
-	for _, migration := range migrations {
-		fmt.Printf("  🧪 Testing migration: %s\n", migration.Name)
+		m.log.Debugf("  📦 Using stored content for %s (file not found)", migrationName)
+		content = validator.Normalize(stored)
+	}
 
-		if err := shadowTracker.ApplyMigration(ctx, migration.Name, migration.Content); err != nil {
-			return fmt.Errorf("migration %s failed on shadow database: %w", migration.Name, err)
+	copies, err := validator.Copies(m.migrationsPath, migrationName, content)
+	if err != nil {
+		return tracker.Migration{}, fmt.Errorf("failed to load data files of migration %s: %w", migrationName, err)
+	}
+
+	isolation, err := validator.Isolation(content)
+	if err != nil {
+		return tracker.Migration{}, fmt.Errorf("invalid migration %s: %w", migrationName, err)
+	}
+
+	return tracker.Migration{
+		Name:          migrationName,
+		Content:       content,
+		Checksum:      checksum,
+		OmitContent:   compressed,
+		Copies:        copies,
+		Isolation:     isolation,
+		NoTransaction: validator.NoTransaction(content),
+	}, nil
+}
+
+// testMigrationsOnShadow tests new migrations on shadow database, logging each
+// attempt in the main database's log table.
+func (m *Manager) testMigrationsOnShadow(ctx context.Context, mainTracker, shadowTracker *tracker.Tracker, migrations []*validator.MigrationFile) error {
+	if m.profile {
+		shadowTracker = shadowTracker.WithProfiler(func(profile tracker.StatementProfile) {
+			m.profiles = append(m.profiles, profile)
+		})
+	}
+
+	for i, migration := range migrations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m.log.Debugf("  🧪 Testing migration: %s", migration.Name)
+		if m.onTest != nil {
+			m.onTest(migration.Name, i+1, len(migrations))
+		}
+
+		copies, err := migration.Copies()
+		if err != nil {
+			return &MigrationError{Name: migration.Name, Err: err}
+		}
+		isolation, err := migration.Isolation()
+		if err != nil {
+			return &MigrationError{Name: migration.Name, Err: err}
+		}
+
+		attempt := tracker.Attempt{Name: migration.Name, Target: tracker.TargetShadow, StartedAt: time.Now()}
+		attempt.RowCounts, attempt.Err = shadowTracker.ApplyMigration(ctx, tracker.Migration{
+			Name:          migration.Name,
+			Content:       migration.Content,
+			Checksum:      migration.Checksum(),
+			OmitContent:   migration.Compressed(),
+			Copies:        copies,
+			Isolation:     isolation,
+			NoTransaction: migration.NoTransaction(),
+		})
+		attempt.FinishedAt = time.Now()
+		m.results = append(m.results, TestResult{Name: migration.Name, Duration: attempt.FinishedAt.Sub(attempt.StartedAt), Err: attempt.Err})
+		if err := mainTracker.LogAttempt(context.WithoutCancel(ctx), attempt); err != nil {
+			m.log.Warnf("%v", err)
+		}
+
+		if attempt.Err != nil {
+			return &MigrationError{Name: migration.Name, Err: attempt.Err}
 		}
+		m.rowCounts = append(m.rowCounts, attempt.RowCounts...)
 
-		fmt.Printf("  ✓ Migration %s passed shadow test\n", migration.Name)
+		m.log.Debugf("  ✓ Migration %s passed shadow test", migration.Name)
 	}
 
 	return nil
 }
 
-// EnsureCleanup ensures shadow database is dropped.
+// EnsureCleanup ensures shadow database is dropped. Credentials are masked in
+// the returned error.
 func (m *Manager) EnsureCleanup(ctx context.Context) error {
+	return m.dsn.RedactError(m.ensureCleanup(ctx))
+}
+
+func (m *Manager) ensureCleanup(ctx context.Context) error {
 	// Get current database name if not set
 	if m.currentDBName == "" {
 		currentDBName, err := getCurrentDatabaseName(ctx, m.mainDB)
@@ -187,11 +492,11 @@ func (m *Manager) EnsureCleanup(ctx context.Context) error {
 			return fmt.Errorf("failed to get current database name: %w", err)
 		}
 		m.currentDBName = currentDBName
-		m.shadowDBName = currentDBName + "_gi_mig_shadow_db"
+		m.shadowDBName = shadowDatabaseName(currentDBName)
 	}
 
 	// Connect to postgres database for management
-	postgresDB, err := m.connectToPostgresDatabase()
+	postgresDB, err := m.connectToPostgresDatabase(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres database: %w", err)
 	}
@@ -209,8 +514,8 @@ func (m *Manager) EnsureCleanup(ctx context.Context) error {
 	}
 
 	if exists {
-		fmt.Printf("🧹 Final cleanup: Shadow database %s still exists, dropping...\n", m.shadowDBName)
-		if err := dropDatabaseIfExists(ctx, postgresDB, m.shadowDBName); err != nil {
+		m.log.Infof("🧹 Final cleanup: Shadow database %s still exists, dropping...", m.shadowDBName)
+		if err := m.dropDatabaseIfExists(ctx, postgresDB, m.shadowDBName); err != nil {
 			return fmt.Errorf("failed to drop shadow database: %w", err)
 		}
 	}
@@ -220,37 +525,135 @@ func (m *Manager) EnsureCleanup(ctx context.Context) error {
 
 // Helper functions
 
-func getCurrentDatabaseName(ctx context.Context, db *sql.DB) (string, error) {
+func getCurrentDatabaseName(ctx context.Context, db tracker.DB) (string, error) {
 	var dbName string
 	err := db.QueryRowContext(ctx, "SELECT current_database()").Scan(&dbName)
 	return dbName, err
 }
 
-func (m *Manager) connectToPostgresDatabase() (*sql.DB, error) {
-	currentDB := extractDBNameFromDSN(m.databaseURL)
-	dsn := strings.Replace(m.databaseURL, "/"+currentDB, "/postgres", 1)
-	return sql.Open("postgres", dsn)
+func (m *Manager) connectToPostgresDatabase(ctx context.Context) (*sql.DB, error) {
+	return m.connectToDatabase(ctx, "postgres")
 }
 
-func (m *Manager) connectToDatabase(dbName string) (*sql.DB, error) {
-	currentDB := extractDBNameFromDSN(m.databaseURL)
-	dsn := strings.Replace(m.databaseURL, "/"+currentDB, "/"+dbName, 1)
-	return sql.Open("postgres", dsn)
+func (m *Manager) connectToDatabase(ctx context.Context, dbName string) (*sql.DB, error) {
+	if err := m.lookupDriver(); err != nil {
+		return nil, err
+	}
+	server, err := m.serverDSN(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.open(server.WithDatabase(dbName)), nil
 }
 
-func extractDBNameFromDSN(dsn string) string {
-	parts := strings.Split(dsn, "/")
-	if len(parts) > 0 {
-		lastPart := parts[len(parts)-1]
-		if idx := strings.Index(lastPart, "?"); idx != -1 {
-			return lastPart[:idx]
+// lookupDriver finds the driver registered as driverName.
+func (m *Manager) lookupDriver() error {
+	if m.driverName == "" || m.driver != nil {
+		return nil
+	}
+
+	// sql.Open only looks the driver up; it doesn't connect
+	db, err := sql.Open(m.driverName, "")
+	if err != nil {
+		return fmt.Errorf("failed to find database driver: %w", err)
+	}
+	m.driver = db.Driver()
+	return db.Close()
+}
+
+// open returns a pool of connections to conn, asking for credentials for
+// each new connection when set, and configured by configureDB.
+func (m *Manager) open(conn *dsn.DSN) *sql.DB {
+	db := sql.OpenDB(conn.Connector(m.driver, m.credentials))
+	if m.configureDB != nil {
+		m.configureDB(db)
+	}
+	return db
+}
+
+// serverDSN returns the DSN shadow connections use. lib/pq can't connect to
+// multi-host DSNs, and shadow databases must be created on the primary
+// anyway, so the first host that accepts writes is picked once and kept.
+func (m *Manager) serverDSN(ctx context.Context) (*dsn.DSN, error) {
+	if err := m.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if m.host != nil {
+		return m.dsn.WithHost(*m.host), nil
+	}
+	hosts := m.dsn.Hosts()
+	if len(hosts) <= 1 {
+		return m.dsn, nil
+	}
+
+	var errs []error
+	for _, host := range hosts {
+		inRecovery, err := m.inRecovery(ctx, m.dsn.WithHost(host))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+			continue
+		}
+		if inRecovery {
+			m.log.Debugf("  Skipping %s for shadow connections: it is a standby", host)
+			continue
 		}
-		return lastPart
+		m.log.Debugf("  Using %s for shadow connections", host)
+		m.host = &host
+		return m.dsn.WithHost(host), nil
 	}
-	return "postgres"
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to find a primary among %d hosts: %w", len(hosts), errors.Join(errs...))
+	}
+	return nil, fmt.Errorf("failed to find a primary among %d hosts: all are standbys", len(hosts))
+}
+
+// resolve re-reads the database URL when the Manager has a resolver. The
+// host picked among several is kept unless the URL changed.
+func (m *Manager) resolve(ctx context.Context) error {
+	if m.resolver == nil {
+		return nil
+	}
+
+	databaseURL, err := m.resolver(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resolve database URL: %w", err)
+	}
+	if databaseURL == m.resolved {
+		return nil
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("database URL is required for shadow database operations")
+	}
+
+	parsed, err := dsn.Parse(databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	if m.appName != "" {
+		parsed = parsed.WithParam("application_name", m.appName)
+	}
+	m.dsn, m.resolved, m.host = parsed, databaseURL, nil
+	return nil
 }
 
-func dropDatabaseIfExists(ctx context.Context, db *sql.DB, dbName string) error {
+// inRecovery reports whether the server server points to is a standby.
+func (m *Manager) inRecovery(ctx context.Context, server *dsn.DSN) (bool, error) {
+	db := m.open(server.WithDatabase("postgres"))
+	defer db.Close()
+
+	var inRecovery bool
+	if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return false, err
+	}
+	return inRecovery, nil
+}
+
+func (m *Manager) dropDatabaseIfExists(ctx context.Context, db *sql.DB, dbName string) error {
+	if err := validateDatabaseName(dbName); err != nil {
+		return err
+	}
+
 	// Terminate all connections to the database first
 	_, err := db.ExecContext(ctx, `
 		SELECT pg_terminate_backend(pid) 
@@ -258,32 +661,70 @@ func dropDatabaseIfExists(ctx context.Context, db *sql.DB, dbName string) error
 		WHERE datname = $1 AND pid <> pg_backend_pid()
 	`, dbName)
 	if err != nil {
-		fmt.Printf("⚠️  Warning: Failed to terminate connections for %s: %v\n", dbName, err)
+		m.log.Warnf("Failed to terminate connections for %s: %v", dbName, err)
 	}
 
-	// Drop the database - Note: Database names cannot be parameterized
-	// This is safe because dbName is constructed internally
-	dropSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s", dbName)
+	// Database names cannot be parameterized
+	dropSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName))
 	_, err = db.ExecContext(ctx, dropSQL)
 	if err != nil {
 		return fmt.Errorf("failed to drop database %s: %w", dbName, err)
 	}
 
-	fmt.Printf("✅ Successfully dropped database: %s\n", dbName)
+	m.log.Debugf("✅ Successfully dropped database: %s", dbName)
 	return nil
 }
 
-func createDatabase(ctx context.Context, db *sql.DB, dbName string) error {
-	fmt.Printf("🏗️  Creating database: %s\n", dbName)
+func (m *Manager) createDatabase(ctx context.Context, db *sql.DB, dbName string) error {
+	if err := validateDatabaseName(dbName); err != nil {
+		return err
+	}
+
+	m.log.Debugf("🏗️  Creating database: %s", dbName)
 
-	// Note: Database names cannot be parameterized
-	// This is safe because dbName is constructed internally
-	createSQL := fmt.Sprintf("CREATE DATABASE %s", dbName)
+	// Database names cannot be parameterized
+	createSQL := fmt.Sprintf("CREATE DATABASE %s", pq.QuoteIdentifier(dbName))
 	_, err := db.ExecContext(ctx, createSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create database %s: %w", dbName, err)
 	}
 
-	fmt.Printf("✅ Successfully created database: %s\n", dbName)
+	m.log.Debugf("✅ Successfully created database: %s", dbName)
 	return nil
 }
+
+// maxIdentifierLength is PostgreSQL's limit on identifiers, in bytes. Longer
+// names are silently truncated.
+const maxIdentifierLength = 63
+
+// validateDatabaseName fails for names PostgreSQL would not use as given.
+func validateDatabaseName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("database name is empty")
+	case len(name) > maxIdentifierLength:
+		return fmt.Errorf("database name %q is longer than %d bytes", name, maxIdentifierLength)
+	case strings.ContainsRune(name, 0):
+		return fmt.Errorf("database name %q contains a NUL character", name)
+	}
+	return nil
+}
+
+// shadowDatabaseName returns the name of the shadow database of database.
+// When appending ShadowSuffix would exceed the identifier limit, database is
+// shortened and a hash of it added, so long names sharing a prefix still get
+// distinct shadow databases.
+func shadowDatabaseName(database string) string {
+	name := database + ShadowSuffix
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+
+	hash := fmt.Sprintf("_%08x", crc32.ChecksumIEEE([]byte(database)))
+	prefix := database[:maxIdentifierLength-len(ShadowSuffix)-len(hash)]
+	// Don't cut a multi-byte character in half
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix + hash + ShadowSuffix
+}
@@ -0,0 +1,343 @@
+// Package sqlparse provides a lightweight PostgreSQL lexer for splitting
+// migration files into statements and normalizing them for pattern matching.
+//
+// It is not a full parser: it understands just enough of the lexical structure
+// (string literals, quoted identifiers, dollar quoting and comments) to find
+// statement boundaries reliably.
+package sqlparse
+
+import (
+	"strings"
+)
+
+// Statement is a single SQL statement found in a migration file.
+type Statement struct {
+	// Index is the zero-based position of the statement in the file.
+	Index int
+
+	// Text is the statement as written, without the terminating semicolon.
+	Text string
+
+	// Line is the 1-based line on which the statement starts.
+	Line int
+}
+
+// Split splits SQL content into statements at top-level semicolons, keeping
+// the bodies of BEGIN ATOMIC functions together. Statements consisting only
+// of whitespace and comments are dropped.
+func Split(content string) []Statement {
+	var statements []Statement
+
+	start := 0
+	emit := func(end int) {
+		// Start the statement at its first character outside leading comments
+		s := &scanner{src: content[:end], pos: start}
+		for s.pos < end {
+			if c := s.src[s.pos]; c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+				s.pos++
+			} else if s.atLineComment() || s.atBlockComment() {
+				s.skipToken()
+			} else {
+				break
+			}
+		}
+		if s.pos >= end {
+			return
+		}
+
+		statements = append(statements, Statement{
+			Index: len(statements),
+			Text:  strings.TrimRight(content[s.pos:end], " \t\r\n"),
+			Line:  strings.Count(content[:s.pos], "\n") + 1,
+		})
+	}
+
+	// Semicolons inside SQL-standard function bodies (BEGIN ATOMIC ... END)
+	// don't end the statement. CASE ... END may nest inside them.
+	var atomic, cases int
+	prev := ""
+
+	s := &scanner{src: content}
+	for s.pos < len(s.src) {
+		if s.skipToken() {
+			continue
+		}
+		c := s.src[s.pos]
+		if isIdentChar(c) && (s.pos == 0 || !isIdentChar(s.src[s.pos-1])) {
+			begin := s.pos
+			for s.pos < len(s.src) && isIdentChar(s.src[s.pos]) {
+				s.pos++
+			}
+			word := strings.ToUpper(s.src[begin:s.pos])
+			switch {
+			case word == "ATOMIC" && prev == "BEGIN":
+				atomic++
+			case word == "CASE" && atomic > 0:
+				cases++
+			case word == "END" && cases > 0:
+				cases--
+			case word == "END" && atomic > 0:
+				atomic--
+			}
+			prev = word
+			continue
+		}
+		if c == ';' && atomic == 0 {
+			emit(s.pos)
+			start = s.pos + 1
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			prev = ""
+		}
+		s.pos++
+	}
+	emit(len(content))
+
+	return statements
+}
+
+// Normalize returns a canonical form of a statement for pattern matching:
+// comments are removed, string literals and dollar-quoted bodies are emptied,
+// keywords and identifiers are upper-cased and whitespace is collapsed.
+//
+// For example:
+//
+//	alter table "Users"  -- note
+//	  add col text default 'x'
+//
+// becomes:
+//
+//	ALTER TABLE "USERS" ADD COL TEXT DEFAULT ''
+func Normalize(sql string) string {
+	var b strings.Builder
+	s := &scanner{src: sql}
+
+	space := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), " ") {
+			b.WriteByte(' ')
+		}
+	}
+
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case s.atLineComment() || s.atBlockComment():
+			s.skipToken()
+			space()
+		case c == '\'':
+			s.skipToken()
+			b.WriteString("''")
+		case c == '$' && s.dollarTag() != "":
+			s.skipToken()
+			b.WriteString("$$")
+		case c == '"':
+			begin := s.pos
+			s.skipToken()
+			b.WriteString(strings.ToUpper(s.src[begin:s.pos]))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space()
+			s.pos++
+		default:
+			b.WriteString(strings.ToUpper(string(c)))
+			s.pos++
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// DirectivePrefix starts a directive comment, e.g. "-- migrator:requires-pg>=14".
+const DirectivePrefix = "migrator:"
+
+// Directive is an instruction to the migrator written as a line comment.
+type Directive struct {
+	// Key is the directive name, e.g. "requires-pg".
+	Key string
+
+	// Value is the rest of the comment after the key, trimmed, e.g. ">=14".
+	Value string
+
+	// Line is the 1-based line of the directive.
+	Line int
+}
+
+// Directives returns the directive comments in content, in order. Only
+// comments outside string literals and function bodies count.
+func Directives(content string) []Directive {
+	var directives []Directive
+
+	s := &scanner{src: content}
+	for s.pos < len(s.src) {
+		if s.atLineComment() {
+			start := s.pos
+			s.skipToken()
+			text := strings.TrimSpace(strings.TrimPrefix(s.src[start:s.pos], "--"))
+			if rest, ok := strings.CutPrefix(text, DirectivePrefix); ok {
+				end := 0
+				for end < len(rest) && (isIdentChar(rest[end]) || rest[end] == '-') {
+					end++
+				}
+				directives = append(directives, Directive{
+					Key:   strings.ToLower(rest[:end]),
+					Value: strings.TrimSpace(rest[end:]),
+					Line:  strings.Count(content[:start], "\n") + 1,
+				})
+			}
+			continue
+		}
+		if !s.skipToken() {
+			s.pos++
+		}
+	}
+
+	return directives
+}
+
+// scanner walks SQL source, skipping over tokens that may contain semicolons.
+type scanner struct {
+	src string
+	pos int
+}
+
+func (s *scanner) atLineComment() bool {
+	return strings.HasPrefix(s.src[s.pos:], "--")
+}
+
+func (s *scanner) atBlockComment() bool {
+	return strings.HasPrefix(s.src[s.pos:], "/*")
+}
+
+// skipToken advances past a comment, string literal, quoted identifier or
+// dollar-quoted string starting at the current position. It reports whether
+// such a token was found.
+func (s *scanner) skipToken() bool {
+	c := s.src[s.pos]
+	switch {
+	case s.atLineComment():
+		if idx := strings.IndexByte(s.src[s.pos:], '\n'); idx != -1 {
+			s.pos += idx + 1
+		} else {
+			s.pos = len(s.src)
+		}
+	case s.atBlockComment():
+		// Block comments nest in PostgreSQL
+		depth := 0
+		for s.pos < len(s.src) {
+			if strings.HasPrefix(s.src[s.pos:], "/*") {
+				depth++
+				s.pos += 2
+			} else if strings.HasPrefix(s.src[s.pos:], "*/") {
+				depth--
+				s.pos += 2
+				if depth == 0 {
+					break
+				}
+			} else {
+				s.pos++
+			}
+		}
+	case c == '\'':
+		s.skipQuoted('\'', s.escapeString())
+	case c == '"':
+		s.skipQuoted('"', false)
+	case c == '$':
+		tag := s.dollarTag()
+		if tag == "" {
+			return false
+		}
+		s.pos += len(tag)
+		if idx := strings.Index(s.src[s.pos:], tag); idx != -1 {
+			s.pos += idx + len(tag)
+		} else {
+			s.pos = len(s.src)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// skipQuoted skips a quoted token where the quote character is escaped by
+// doubling it, and optionally by a backslash.
+func (s *scanner) skipQuoted(quote byte, backslashEscapes bool) {
+	s.pos++
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case backslashEscapes && c == '\\':
+			s.pos += 2
+		case c == quote:
+			if s.pos+1 < len(s.src) && s.src[s.pos+1] == quote {
+				s.pos += 2
+				continue
+			}
+			s.pos++
+			return
+		default:
+			s.pos++
+		}
+	}
+	if s.pos > len(s.src) {
+		s.pos = len(s.src)
+	}
+}
+
+// escapeString reports whether the string literal at the current position is
+// an E'...' literal, in which backslashes escape characters.
+func (s *scanner) escapeString() bool {
+	if s.pos == 0 {
+		return false
+	}
+	prev := s.src[s.pos-1]
+	if prev != 'E' && prev != 'e' {
+		return false
+	}
+	return s.pos < 2 || !isIdentChar(s.src[s.pos-2])
+}
+
+// dollarTag returns the dollar-quote delimiter ($$ or $tag$) starting at the
+// current position, or "" if there is none. Positional parameters like $1 and
+// identifiers containing $ are not dollar quotes.
+func (s *scanner) dollarTag() string {
+	if s.pos > 0 && isIdentChar(s.src[s.pos-1]) {
+		return ""
+	}
+	for i := s.pos + 1; i < len(s.src); i++ {
+		c := s.src[i]
+		if c == '$' {
+			return s.src[s.pos : i+1]
+		}
+		if !isIdentChar(c) || (i == s.pos+1 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// SplitList splits a normalized clause at top-level commas, ignoring commas
+// inside parentheses. It is used to separate the actions of an ALTER TABLE.
+func SplitList(normalized string) []string {
+	var parts []string
+
+	depth, start := 0, 0
+	for i := 0; i < len(normalized); i++ {
+		switch normalized[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(normalized[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, strings.TrimSpace(normalized[start:]))
+
+	return parts
+}
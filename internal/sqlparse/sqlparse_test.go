@@ -0,0 +1,79 @@
+package sqlparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	content := `-- leading comment
+CREATE TABLE users (id SERIAL PRIMARY KEY, note TEXT DEFAULT 'a;b');
+
+/* block; /* nested; */ comment */
+CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN
+	NEW.updated_at = now();
+	RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+INSERT INTO "weird;name" VALUES (E'it\'s;', $1);
+-- trailing comment only
+`
+
+	statements := Split(content)
+	require.Len(t, statements, 3)
+
+	assert.Equal(t, 2, statements[0].Line)
+	assert.Contains(t, statements[0].Text, "'a;b'")
+
+	assert.Equal(t, 5, statements[1].Line)
+	assert.Contains(t, statements[1].Text, "RETURN NEW;")
+	assert.Equal(t, 1, statements[1].Index)
+
+	assert.Equal(t, `INSERT INTO "weird;name" VALUES (E'it\'s;', $1)`, statements[2].Text)
+}
+
+func TestNormalize(t *testing.T) {
+	got := Normalize("alter table \"Users\"  -- note\n  add col text default 'x;y'")
+	assert.Equal(t, `ALTER TABLE "USERS" ADD COL TEXT DEFAULT ''`, got)
+
+	got = Normalize("CREATE FUNCTION f() RETURNS void AS $$ DROP TABLE x; $$ LANGUAGE sql")
+	assert.Equal(t, "CREATE FUNCTION F() RETURNS VOID AS $$ LANGUAGE SQL", got)
+}
+
+func TestSplitList(t *testing.T) {
+	parts := SplitList("ADD COLUMN A NUMERIC(10, 2) NOT NULL, DROP COLUMN B")
+	assert.Equal(t, []string{"ADD COLUMN A NUMERIC(10, 2) NOT NULL", "DROP COLUMN B"}, parts)
+}
+
+func TestDirectives(t *testing.T) {
+	content := `-- migrator:requires-pg>=14
+--migrator:no-transaction
+-- a regular comment
+CREATE FUNCTION f() RETURNS void AS $$
+-- migrator:ignored inside a function body
+$$ LANGUAGE sql;
+SELECT '-- migrator:ignored inside a string'; -- migrator:Extension pgcrypto
+`
+
+	assert.Equal(t, []Directive{
+		{Key: "requires-pg", Value: ">=14", Line: 1},
+		{Key: "no-transaction", Value: "", Line: 2},
+		{Key: "extension", Value: "pgcrypto", Line: 7},
+	}, Directives(content))
+}
+
+func TestSplit_BeginAtomic(t *testing.T) {
+	content := `CREATE FUNCTION grade(score int) RETURNS text LANGUAGE sql
+BEGIN ATOMIC
+	SELECT CASE WHEN score > 50 THEN 'pass' ELSE 'fail' END;
+END;
+INSERT INTO grades VALUES (grade(70));`
+
+	statements := Split(content)
+	require.Len(t, statements, 2)
+	assert.Contains(t, statements[0].Text, "ELSE 'fail' END;\nEND")
+	assert.Equal(t, "INSERT INTO grades VALUES (grade(70))", statements[1].Text)
+}
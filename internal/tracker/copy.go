@@ -0,0 +1,87 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Copy loads a companion data file into a table with the COPY protocol after
+// the migration's SQL runs, in the same transaction.
+type Copy struct {
+	// Table is the target table, optionally schema-qualified.
+	Table string
+
+	// Path is the data file. Files ending in .tsv are tab-separated, all
+	// others comma-separated. The first row names the columns; empty fields
+	// load as NULL.
+	Path string
+}
+
+// copyFrom streams a data file into its table and returns the number of rows loaded.
+func copyFrom(ctx context.Context, tx *sql.Tx, c Copy) (int64, error) {
+	file, err := os.Open(c.Path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open data file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if strings.EqualFold(filepath.Ext(c.Path), ".tsv") {
+		reader.Comma = '\t'
+	}
+
+	columns, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header of %s: %w", filepath.Base(c.Path), err)
+	}
+	columns[0] = strings.TrimPrefix(columns[0], "\ufeff")
+
+	query := pq.CopyIn(c.Table, columns...)
+	if schema, table, ok := strings.Cut(c.Table, "."); ok {
+		query = pq.CopyInSchema(schema, table, columns...)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start copy into %s: %w", c.Table, err)
+	}
+	defer stmt.Close()
+
+	var rows int64
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %w", filepath.Base(c.Path), err)
+		}
+
+		values := make([]any, len(record))
+		for i, value := range record {
+			if value != "" {
+				values[i] = value
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return rows, fmt.Errorf("failed to copy row %d of %s: %w", rows+1, filepath.Base(c.Path), err)
+		}
+		rows++
+	}
+
+	// An Exec without arguments flushes the copy
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return rows, fmt.Errorf("failed to copy into %s: %w", c.Table, err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,226 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IndexOptions configures how CREATE INDEX CONCURRENTLY statements are run.
+type IndexOptions struct {
+	// Retries is how many times a build that failed for a transient reason
+	// (deadlock, lock or statement timeout, serialization failure) is
+	// retried.
+	Retries int
+
+	// RetryDelay is the time to wait before a retry.
+	RetryDelay time.Duration
+}
+
+// concurrentIndexPattern matches CREATE INDEX CONCURRENTLY statements that
+// name their index, capturing the index name and the table.
+var concurrentIndexPattern = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\s+(?:IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")+"|[a-z_][a-z0-9_$]*)\s+ON\s+(?:ONLY\s+)?((?:(?:"(?:[^"]|"")+"|[a-z_][a-z0-9_$]*)\.)?(?:"(?:[^"]|"")+"|[a-z_][a-z0-9_$]*))`)
+
+// concurrentIndex returns the name of the index a CREATE INDEX CONCURRENTLY
+// statement builds, qualified with the table's schema if the table is, as
+// accepted by to_regclass. ok is false for other statements and for indexes
+// without a name.
+func concurrentIndex(statement string) (name string, ok bool) {
+	match := concurrentIndexPattern.FindStringSubmatch(strings.TrimSpace(statement))
+	if match == nil || strings.EqualFold(match[1], "ON") {
+		return "", false
+	}
+
+	name = match[1]
+	table := match[2]
+	if i := lastUnquotedDot(table); i >= 0 {
+		name = table[:i+1] + name
+	}
+	return name, true
+}
+
+// lastUnquotedDot returns the index of the last '.' outside double quotes, or
+// -1 if there is none.
+func lastUnquotedDot(name string) int {
+	quoted, last := false, -1
+	for i, r := range name {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '.' && !quoted:
+			last = i
+		}
+	}
+	return last
+}
+
+// CreateIndex runs a CREATE INDEX CONCURRENTLY statement on a connection of
+// its own so that it leaves a valid index or none at all. An INVALID index of
+// the same name left by an earlier failed build is dropped first, a build
+// failing for a transient reason is retried after dropping its leftover, and
+// an index still being built by another session is waited for. An index that
+// is not valid when the statement returns is an error.
+func (t *Tracker) CreateIndex(ctx context.Context, statement string) error {
+	if _, ok := concurrentIndex(statement); !ok {
+		return fmt.Errorf("not a CREATE INDEX CONCURRENTLY statement with an index name: %s", statement)
+	}
+
+	var db session = t.db
+	if pool, ok := t.db.(interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+	}); ok {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get connection: %w", err)
+		}
+		defer conn.Close()
+		db = conn
+	}
+
+	_, err := t.createIndex(ctx, db, statement)
+	return err
+}
+
+// createIndex runs a CREATE INDEX CONCURRENTLY statement on db, see
+// CreateIndex.
+func (t *Tracker) createIndex(ctx context.Context, db session, statement string) (sql.Result, error) {
+	name, _ := concurrentIndex(statement)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.dropInvalidIndex(ctx, db, name); err != nil {
+			return nil, err
+		}
+
+		res, err := db.ExecContext(ctx, statement)
+		if err == nil {
+			return res, t.waitForValidIndex(ctx, db, name)
+		}
+
+		if dropErr := t.dropInvalidIndex(context.WithoutCancel(ctx), db, name); dropErr != nil {
+			t.log.Warnf("%v", dropErr)
+		}
+		if attempt >= t.indexRetries || !transientIndexError(err) {
+			return nil, err
+		}
+
+		t.log.Infof("  ↻ Building index %s failed (%v), retrying in %s", name, err, t.indexRetryDelay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(t.indexRetryDelay):
+		}
+	}
+}
+
+// indexState returns whether the index exists, whether it is valid, and
+// whether another session is still building it.
+func indexState(ctx context.Context, db session, name string) (exists, valid, building bool, err error) {
+	var oid int64
+	err = db.QueryRowContext(ctx, `
+		SELECT i.indexrelid::bigint, i.indisvalid AND i.indisready
+		FROM pg_index i
+		WHERE i.indexrelid = to_regclass($1)
+	`, name).Scan(&oid, &valid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, false, nil
+	}
+	if err != nil {
+		return false, false, false, fmt.Errorf("failed to check index %s: %w", name, err)
+	}
+	if valid {
+		return true, true, false, nil
+	}
+
+	building, err = indexBuilding(ctx, db, oid)
+	return true, false, building, err
+}
+
+// indexBuilding reports whether another session is building the index with
+// the given OID. Servers before PostgreSQL 12 don't report index builds, so
+// it is always false there.
+func indexBuilding(ctx context.Context, db rowQuerier, oid int64) (bool, error) {
+	var reported bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('pg_catalog.pg_stat_progress_create_index') IS NOT NULL").Scan(&reported); err != nil {
+		return false, fmt.Errorf("failed to check index builds: %w", err)
+	}
+	if !reported {
+		return false, nil
+	}
+
+	var building bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pg_stat_progress_create_index WHERE index_relid = $1::oid AND pid <> pg_backend_pid())
+	`, oid).Scan(&building)
+	if err != nil {
+		return false, fmt.Errorf("failed to check index builds: %w", err)
+	}
+	return building, nil
+}
+
+// dropInvalidIndex drops the index if it exists but is INVALID and nobody is
+// building it, as left behind by a failed concurrent build.
+func (t *Tracker) dropInvalidIndex(ctx context.Context, db session, name string) error {
+	exists, valid, building, err := indexState(ctx, db, name)
+	if err != nil || !exists || valid || building {
+		return err
+	}
+
+	// The name as PostgreSQL renders it is qualified and quoted as needed
+	var resolved sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1)::text", name).Scan(&resolved); err != nil {
+		return fmt.Errorf("failed to resolve index %s: %w", name, err)
+	}
+	if !resolved.Valid {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+resolved.String); err != nil {
+		return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+	}
+	t.log.Infof("  🗑️  Dropped invalid index %s left by an earlier build", name)
+	return nil
+}
+
+// waitForValidIndex returns once the index is valid, polling while another
+// session is still building it.
+func (t *Tracker) waitForValidIndex(ctx context.Context, db session, name string) error {
+	for {
+		exists, valid, building, err := indexState(ctx, db, name)
+		if err != nil {
+			return err
+		}
+		if !exists || valid {
+			return nil
+		}
+		if !building {
+			return fmt.Errorf("index %s is INVALID after it was built", name)
+		}
+
+		t.log.Debugf("  ⏳ Waiting for index %s being built by another session", name)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// transientIndexError reports whether a failed index build may succeed when
+// retried. SQLState is implemented by the errors of lib/pq and pgx alike.
+func transientIndexError(err error) bool {
+	var stateErr interface{ SQLState() string }
+	if !errors.As(err, &stateErr) {
+		return false
+	}
+	switch stateErr.SQLState() {
+	case "40P01", // deadlock_detected
+		"40001", // serialization_failure
+		"55P03", // lock_not_available
+		"57014": // query_canceled, e.g. by statement_timeout
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StatementProfile reports how long a statement of a migration took to run
+// and, for DML, the plan it was executed with.
+type StatementProfile struct {
+	// Migration is the name of the migration containing the statement.
+	Migration string
+
+	// Index is the zero-based position of the statement in the migration.
+	Index int
+
+	// Line is the 1-based line on which the statement starts.
+	Line int
+
+	// Statement is the statement as written.
+	Statement string
+
+	// Duration is how long the statement took to execute.
+	Duration time.Duration
+
+	// Plan is the EXPLAIN output for DML statements, empty otherwise or if
+	// the statement could not be explained.
+	Plan string
+}
+
+// WithProfiler returns a copy of the tracker that applies migrations one
+// statement at a time and reports the timing and plan of each to fn.
+func (t *Tracker) WithProfiler(fn func(StatementProfile)) *Tracker {
+	copied := *t
+	copied.profiler = fn
+	return &copied
+}
+
+// explain returns the plan of a statement. It must run under a savepoint: a
+// statement that cannot be explained aborts the savepoint, not the migration.
+func explain(ctx context.Context, tx *sql.Tx, statement string) (string, error) {
+	rows, err := tx.QueryContext(ctx, "EXPLAIN "+statement)
+	if err != nil {
+		return "", fmt.Errorf("failed to explain statement: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", fmt.Errorf("failed to scan plan: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,188 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+)
+
+// SchemaTable records the upgrades applied to the tracking tables themselves.
+const SchemaTable = "_go_migrations_schema"
+
+// schemaLockKey is the transaction-level advisory lock key that serializes
+// upgrades of the tracking tables ("go_sch" in ASCII).
+const schemaLockKey int64 = 0x676f5f736368
+
+// schemaUpgrade is one step in the evolution of the tracking tables.
+//
+// Upgrades are append-only: never edit or reorder a released step, add a new
+// one instead. Statements must be idempotent (IF NOT EXISTS), since
+// installations that predate versioning replay every step over tables that
+// already have some of the columns.
+type schemaUpgrade struct {
+	version     int
+	description string
+	statements  []string
+}
+
+var schemaUpgrades = []schemaUpgrade{
+	{1, "create migrations table", []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL UNIQUE,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`, MigrationsTable)}},
+
+	{2, "record migration metadata", []string{fmt.Sprintf(`
+		ALTER TABLE %s
+			ADD COLUMN IF NOT EXISTS duration_ms BIGINT,
+			ADD COLUMN IF NOT EXISTS applied_by TEXT,
+			ADD COLUMN IF NOT EXISTS hostname TEXT,
+			ADD COLUMN IF NOT EXISTS app_version TEXT,
+			ADD COLUMN IF NOT EXISTS content TEXT,
+			ADD COLUMN IF NOT EXISTS checksum VARCHAR(64),
+			ADD COLUMN IF NOT EXISTS batch INTEGER
+	`, MigrationsTable)}},
+
+	{3, "create attempt log table", []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			target TEXT NOT NULL,
+			started_at TIMESTAMPTZ NOT NULL,
+			finished_at TIMESTAMPTZ NOT NULL,
+			outcome TEXT NOT NULL,
+			error TEXT
+		)
+	`, LogTable)}},
+
+	{4, "create batches table", []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			batch INTEGER PRIMARY KEY,
+			started_at TIMESTAMPTZ NOT NULL,
+			finished_at TIMESTAMPTZ NOT NULL,
+			lsn_before TEXT,
+			lsn_after TEXT
+		)
+	`, BatchesTable), fmt.Sprintf(`
+		ALTER TABLE %s
+			ADD COLUMN IF NOT EXISTS suspect TEXT
+	`, BatchesTable)}},
+
+	{5, "log row counts", []string{fmt.Sprintf(`
+		ALTER TABLE %s
+			ADD COLUMN IF NOT EXISTS row_counts JSONB
+	`, LogTable)}},
+
+	{6, "create skipped migrations table", []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name VARCHAR(255) PRIMARY KEY,
+			reason TEXT NOT NULL,
+			skipped_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			skipped_by TEXT,
+			hostname TEXT
+		)
+	`, SkippedTable)}},
+
+	{7, "create tags table", []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name VARCHAR(255) PRIMARY KEY,
+			head VARCHAR(255),
+			batch INTEGER,
+			lsn TEXT,
+			tagged_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			tagged_by TEXT,
+			hostname TEXT,
+			app_version TEXT
+		)
+	`, TagsTable)}},
+
+	{8, "record migration front matter", []string{fmt.Sprintf(`
+		ALTER TABLE %s
+			ADD COLUMN IF NOT EXISTS description TEXT,
+			ADD COLUMN IF NOT EXISTS author TEXT,
+			ADD COLUMN IF NOT EXISTS ticket TEXT,
+			ADD COLUMN IF NOT EXISTS risk TEXT
+	`, MigrationsTable)}},
+}
+
+// SchemaVersion is the version of the tracking tables this package writes.
+var SchemaVersion = schemaUpgrades[len(schemaUpgrades)-1].version
+
+// GetSchemaVersion returns the version of the tracking tables in the
+// database, 0 if they predate versioning or don't exist. It never modifies
+// the database.
+func (t *Tracker) GetSchemaVersion(ctx context.Context) (int, error) {
+	var exists bool
+	if err := t.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", SchemaTable).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check schema table: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	return schemaVersion(ctx, t.db)
+}
+
+// schemaVersion reads the version from the schema table.
+func schemaVersion(ctx context.Context, db rowQuerier) (int, error) {
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", SchemaTable)
+	if err := db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
+// upgradeSchema applies the pending schema upgrades in one transaction.
+// Concurrent callers wait for each other, and the loser finds nothing left
+// to do.
+func (t *Tracker) upgradeSchema(ctx context.Context) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin schema upgrade: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", schemaLockKey); err != nil {
+		return fmt.Errorf("failed to lock tracking tables for upgrade: %w", err)
+	}
+
+	createSchemaTableSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`, SchemaTable)
+	if _, err := tx.ExecContext(ctx, createSchemaTableSQL); err != nil {
+		return fmt.Errorf("failed to create schema table: %w", err)
+	}
+
+	current, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	insertSQL := fmt.Sprintf("INSERT INTO %s (version, description) VALUES ($1, $2)", SchemaTable)
+	for _, upgrade := range schemaUpgrades {
+		if upgrade.version <= current {
+			continue
+		}
+
+		for _, statement := range upgrade.statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to upgrade tracking tables to version %d (%s): %w", upgrade.version, upgrade.description, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, insertSQL, upgrade.version, upgrade.description); err != nil {
+			return fmt.Errorf("failed to record schema version %d: %w", upgrade.version, err)
+		}
+		t.log.Debugf("  🏗️  Upgraded tracking tables to version %d: %s", upgrade.version, upgrade.description)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schema upgrade: %w", err)
+	}
+	return nil
+}
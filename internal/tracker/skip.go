@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Skip describes a migration recorded as intentionally never applied.
+type Skip struct {
+	Name   string
+	Reason string
+
+	SkippedAt time.Time
+
+	// SkippedBy is the database role that recorded the skip.
+	SkippedBy string
+
+	// Hostname is the host the migrator ran on.
+	Hostname string
+}
+
+// RecordSkip records a migration as skipped, replacing the reason of an
+// earlier skip.
+func (t *Tracker) RecordSkip(ctx context.Context, migrationName, reason string) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, reason, skipped_by, hostname)
+		VALUES ($1, $2, current_user, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			reason = EXCLUDED.reason, skipped_at = now(),
+			skipped_by = EXCLUDED.skipped_by, hostname = EXCLUDED.hostname
+	`, SkippedTable)
+
+	if _, err := t.db.ExecContext(ctx, query, migrationName, reason, t.hostname); err != nil {
+		return fmt.Errorf("failed to record skipped migration: %w", err)
+	}
+	return nil
+}
+
+// RemoveSkip deletes the skip of a migration, making it pending again. It
+// returns false if the migration wasn't skipped.
+func (t *Tracker) RemoveSkip(ctx context.Context, migrationName string) (bool, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE name = $1", SkippedTable)
+
+	res, err := t.db.ExecContext(ctx, query, migrationName)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove skipped migration: %w", err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove skipped migration: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// GetSkipped returns the skipped migrations in name order. Tracking tables
+// that predate skips have none; like TableExists, it never modifies the
+// database.
+func (t *Tracker) GetSkipped(ctx context.Context) ([]Skip, error) {
+	var exists bool
+	if err := t.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", SkippedTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check skipped migrations table: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT name, reason, skipped_at, COALESCE(skipped_by, ''), COALESCE(hostname, '')
+		FROM %s
+		ORDER BY name
+	`, SkippedTable)
+
+	rows, err := t.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get skipped migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var skips []Skip
+	for rows.Next() {
+		var skip Skip
+		if err := rows.Scan(&skip.Name, &skip.Reason, &skip.SkippedAt, &skip.SkippedBy, &skip.Hostname); err != nil {
+			return nil, fmt.Errorf("failed to scan skipped migration: %w", err)
+		}
+		skips = append(skips, skip)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating skipped migrations: %w", err)
+	}
+
+	return skips, nil
+}
@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTagExists is returned by RecordTag for a tag name already in use.
+var ErrTagExists = errors.New("tag already exists")
+
+// Tag is a named marker at a point of the migration history.
+type Tag struct {
+	Name string
+
+	// Head is the last applied migration when the tag was recorded, empty if
+	// none was applied.
+	Head string
+
+	// Batch is the highest batch number when the tag was recorded, zero if
+	// none.
+	Batch int
+
+	// LSN is pg_current_wal_lsn() when the tag was recorded, empty if unknown.
+	LSN string
+
+	TaggedAt time.Time
+
+	// TaggedBy is the database role that recorded the tag.
+	TaggedBy string
+
+	// Hostname is the host the migrator ran on.
+	Hostname string
+
+	// AppVersion is the application version supplied by the caller.
+	AppVersion string
+}
+
+// RecordTag records a tag at the current head of the migration history, with
+// lsn as its WAL position, and returns it. Tags are immutable: reusing a name
+// fails with ErrTagExists.
+func (t *Tracker) RecordTag(ctx context.Context, name, lsn string) (Tag, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (name, head, batch, lsn, tagged_by, hostname, app_version)
+		SELECT $1,
+			(SELECT name FROM %[2]s ORDER BY applied_at DESC, id DESC LIMIT 1),
+			(SELECT MAX(batch) FROM %[2]s),
+			NULLIF($2, ''), current_user, $3, $4
+		ON CONFLICT (name) DO NOTHING
+		RETURNING COALESCE(head, ''), COALESCE(batch, 0), tagged_at, tagged_by
+	`, TagsTable, MigrationsTable)
+
+	tag := Tag{Name: name, LSN: lsn, Hostname: t.hostname, AppVersion: t.appVersion}
+	err := t.db.QueryRowContext(ctx, query, name, lsn, t.hostname, t.appVersion).
+		Scan(&tag.Head, &tag.Batch, &tag.TaggedAt, &tag.TaggedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Tag{}, fmt.Errorf("%w: %s", ErrTagExists, name)
+	}
+	if err != nil {
+		return Tag{}, fmt.Errorf("failed to record tag %s: %w", name, err)
+	}
+
+	return tag, nil
+}
+
+// GetTags returns all tags in the order they were recorded. Tracking tables
+// that predate tags have none; like TableExists, it never modifies the
+// database.
+func (t *Tracker) GetTags(ctx context.Context) ([]Tag, error) {
+	var exists bool
+	if err := t.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", TagsTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check tags table: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT name, COALESCE(head, ''), COALESCE(batch, 0), COALESCE(lsn, ''), tagged_at,
+			COALESCE(tagged_by, ''), COALESCE(hostname, ''), COALESCE(app_version, '')
+		FROM %s
+		ORDER BY tagged_at, name
+	`, TagsTable)
+
+	rows, err := t.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(&tag.Name, &tag.Head, &tag.Batch, &tag.LSN, &tag.TaggedAt,
+			&tag.TaggedBy, &tag.Hostname, &tag.AppVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+
+	return tags, nil
+}
@@ -3,42 +3,490 @@ package tracker
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/hasirciogluhq/migrator/internal/output"
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
 )
 
 const (
 	// MigrationsTable is the name of the table that tracks applied migrations
 	MigrationsTable = "_go_migrations"
+
+	// LogTable is the name of the table that records every migration attempt
+	LogTable = "_go_migrations_log"
+
+	// BatchesTable is the name of the table that records each batch of applied migrations
+	BatchesTable = "_go_migrations_batches"
+
+	// ProgressTable is the name of the table that records the progress of
+	// long-running, resumable operations such as backfills
+	ProgressTable = "_go_migrations_progress"
+
+	// SkippedTable is the name of the table that records migrations
+	// intentionally never applied to this database
+	SkippedTable = "_go_migrations_skipped"
+
+	// TagsTable is the name of the table that records named markers in the
+	// migration history, such as application releases
+	TagsTable = "_go_migrations_tags"
 )
 
+// Target identifies the database a migration attempt ran against.
+type Target string
+
+const (
+	// TargetProduction is the database being migrated.
+	TargetProduction Target = "production"
+
+	// TargetShadow is the temporary shadow database.
+	TargetShadow Target = "shadow"
+
+	// TargetVerification is a post-apply verification query run against the
+	// database being migrated.
+	TargetVerification Target = "verification"
+)
+
+// Attempt describes a single try at applying a migration, successful or not.
+type Attempt struct {
+	Name       string
+	Target     Target
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        error
+
+	// RowCounts are the rows affected by the DML statements of the migration.
+	RowCounts []RowCount
+}
+
+// RowCount is the number of rows affected by a DML statement of a migration.
+type RowCount struct {
+	Migration string `json:"migration"`
+
+	// Index is the zero-based position of the statement in the migration.
+	Index int `json:"index"`
+
+	// Line is the 1-based line on which the statement starts.
+	Line int `json:"line"`
+
+	// Command is the statement's leading keyword, e.g. "UPDATE".
+	Command string `json:"command"`
+
+	Rows int64 `json:"rows"`
+}
+
+// Record describes a single applied migration as stored in the tracking table.
+type Record struct {
+	Name      string
+	AppliedAt time.Time
+
+	// Duration is how long the migration took to execute. Zero for migrations
+	// applied before durations were recorded.
+	Duration time.Duration
+
+	// AppliedBy is the database role that applied the migration.
+	AppliedBy string
+
+	// Hostname is the host the migrator ran on.
+	Hostname string
+
+	// AppVersion is the application version supplied by the caller.
+	AppVersion string
+
+	// Checksum is the SHA-256 of the migration content at apply time.
+	// Empty for migrations applied before checksums were recorded.
+	Checksum string
+
+	// Batch numbers the run that applied the migration. Zero if unknown.
+	Batch int
+
+	// Metadata is the front matter the migration declared when applied.
+	Metadata Metadata
+}
+
+// Metadata is the front matter of a migration, declared with directives
+// such as "-- migrator:author jane". Fields not declared are empty.
+type Metadata struct {
+	Description string
+	Author      string
+
+	// Ticket is the issue or pull request the migration belongs to.
+	Ticket string
+
+	// Risk is "low", "medium" or "high".
+	Risk string
+}
+
+// Batch describes a run that applied migrations, with the WAL positions
+// around it for point-in-time recovery.
+type Batch struct {
+	Batch      int
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// LSNBefore and LSNAfter are pg_current_wal_lsn() before the first and
+	// after the last migration of the batch. Empty if unknown.
+	LSNBefore string
+	LSNAfter  string
+
+	// Suspect is the reason the batch was marked suspect, empty if it wasn't.
+	Suspect string
+}
+
+// Progress is the saved state of a long-running, resumable operation.
+type Progress struct {
+	Name string
+
+	// Step is the operation's current step, e.g. "backfill".
+	Step string
+
+	// LastKey is the last key processed, as text. Empty before the first batch.
+	LastKey string
+
+	// Rows is the number of rows processed so far.
+	Rows int64
+
+	// Completed is true once the operation finished.
+	Completed bool
+}
+
+// Migration is a migration to be applied by the Tracker.
+type Migration struct {
+	Name    string
+	Content string
+
+	// Checksum is the checksum of Content, if already known, so large
+	// migrations aren't hashed again when recorded.
+	Checksum string
+
+	// OmitContent leaves Content out of the migrations table even when
+	// Options.StoreContent is set, for migrations too large to store.
+	OmitContent bool
+
+	// Batch numbers the run applying the migration, see NextBatch.
+	// Zero records no batch.
+	Batch int
+
+	// Copies are data files loaded after the migration's SQL runs.
+	Copies []Copy
+
+	// Isolation overrides the transaction isolation level of this migration.
+	// sql.LevelDefault uses the Tracker's level.
+	Isolation sql.IsolationLevel
+
+	// NoTransaction runs the migration's statements one at a time outside a
+	// transaction, see ApplyMigration.
+	NoTransaction bool
+
+	// Metadata is recorded with the migration.
+	Metadata Metadata
+}
+
+// Checksum returns the hex-encoded SHA-256 of migration content.
+func Checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Options configures what the Tracker records alongside each migration.
+type Options struct {
+	// AppVersion is recorded with each applied migration.
+	AppVersion string
+
+	// StoreContent stores the SQL content of each applied migration.
+	StoreContent bool
+
+	// SearchPath is set for the duration of each migration transaction.
+	SearchPath string
+
+	// Role is assumed with SET LOCAL ROLE for the duration of each migration
+	// transaction.
+	Role string
+
+	// Isolation is the isolation level of migration transactions. Defaults to
+	// sql.LevelReadCommitted.
+	Isolation sql.IsolationLevel
+
+	// StatementSavepoints runs each statement of a migration under its own
+	// savepoint, so failures return a *StatementError naming the statement.
+	StatementSavepoints bool
+
+	// Index configures CREATE INDEX CONCURRENTLY statements of no-transaction
+	// migrations, see ApplyMigration.
+	Index IndexOptions
+
+	// Logger receives progress messages. Defaults to info level on stdout.
+	Logger *output.Logger
+}
+
+// DB is the subset of *sql.DB used to track and apply migrations. It is also
+// satisfied by *sql.Conn and by instrumented wrappers.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
 // Tracker manages migration tracking in the database.
 type Tracker struct {
-	db *sql.DB
+	db           DB
+	hostname     string
+	appVersion   string
+	storeContent bool
+	searchPath   string
+	role         string
+	isolation    sql.IsolationLevel
+
+	statementSavepoints bool
+	indexRetries        int
+	indexRetryDelay     time.Duration
+	profiler            func(StatementProfile)
+	log                 *output.Logger
 }
 
 // New creates a new Tracker instance.
-func New(db *sql.DB) *Tracker {
-	return &Tracker{db: db}
+func New(db DB) *Tracker {
+	return NewWithOptions(db, Options{})
 }
 
-// EnsureMigrationsTable creates the migrations tracking table if it doesn't exist.
+// NewWithOptions creates a new Tracker instance with custom options.
+func NewWithOptions(db DB, opts Options) *Tracker {
+	hostname, _ := os.Hostname()
+
+	return &Tracker{
+		db:           db,
+		hostname:     hostname,
+		appVersion:   opts.AppVersion,
+		storeContent: opts.StoreContent,
+		searchPath:   opts.SearchPath,
+		role:         opts.Role,
+		isolation:    opts.Isolation,
+
+		statementSavepoints: opts.StatementSavepoints,
+		indexRetries:        opts.Index.Retries,
+		indexRetryDelay:     opts.Index.RetryDelay,
+		log:                 opts.Logger,
+	}
+}
+
+// WithSearchPath returns a copy of the tracker that runs migrations with
+// searchPath, keeping its other options.
+func (t *Tracker) WithSearchPath(searchPath string) *Tracker {
+	copied := *t
+	copied.searchPath = searchPath
+	return &copied
+}
+
+// WithDB returns a copy of the tracker that uses db, keeping its options.
+func (t *Tracker) WithDB(db DB) *Tracker {
+	copied := *t
+	copied.db = db
+	return &copied
+}
+
+// EnsureMigrationsTable creates the tracking tables if they don't exist and
+// upgrades them to SchemaVersion. Tables that are already current are left
+// untouched, so the check costs two queries and takes no locks.
 func (t *Tracker) EnsureMigrationsTable(ctx context.Context) error {
-	createTableSQL := fmt.Sprintf(`
+	version, err := t.GetSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > SchemaVersion {
+		// A newer migrator upgraded the tables; upgrades only add columns
+		t.log.Warnf("Tracking tables have schema version %d, newer than this migrator supports (%d); consider upgrading the migrator", version, SchemaVersion)
+		return nil
+	}
+	if version == SchemaVersion {
+		return nil
+	}
+
+	return t.upgradeSchema(ctx)
+}
+
+// EnsureProgressTable creates the progress table if it doesn't exist.
+func (t *Tracker) EnsureProgressTable(ctx context.Context) error {
+	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL UNIQUE,
-			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			name TEXT PRIMARY KEY,
+			step TEXT NOT NULL,
+			last_key TEXT,
+			rows_done BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			completed_at TIMESTAMPTZ
 		)
-	`, MigrationsTable)
+	`, ProgressTable)
+
+	if _, err := t.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create progress table: %w", err)
+	}
+	return nil
+}
 
-	if _, err := t.db.ExecContext(ctx, createTableSQL); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+// GetProgress returns the saved progress of an operation. The second return
+// value is false if the operation hasn't started.
+func (t *Tracker) GetProgress(ctx context.Context, name string) (Progress, bool, error) {
+	query := fmt.Sprintf(`
+		SELECT step, COALESCE(last_key, ''), rows_done, completed_at IS NOT NULL
+		FROM %s WHERE name = $1
+	`, ProgressTable)
+
+	progress := Progress{Name: name}
+	err := t.db.QueryRowContext(ctx, query, name).Scan(&progress.Step, &progress.LastKey, &progress.Rows, &progress.Completed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return progress, false, nil
+	}
+	if err != nil {
+		return progress, false, fmt.Errorf("failed to get progress of %s: %w", name, err)
+	}
+
+	return progress, true, nil
+}
+
+// SaveProgress stores the progress of an operation.
+func (t *Tracker) SaveProgress(ctx context.Context, progress Progress) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, step, last_key, rows_done, updated_at, completed_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, now(), CASE WHEN $5 THEN now() END)
+		ON CONFLICT (name) DO UPDATE SET
+			step = EXCLUDED.step, last_key = EXCLUDED.last_key, rows_done = EXCLUDED.rows_done,
+			updated_at = EXCLUDED.updated_at, completed_at = EXCLUDED.completed_at
+	`, ProgressTable)
+
+	_, err := t.db.ExecContext(ctx, query, progress.Name, progress.Step, progress.LastKey, progress.Rows, progress.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to save progress of %s: %w", progress.Name, err)
+	}
+	return nil
+}
+
+// CurrentLSN returns the current WAL write position.
+func (t *Tracker) CurrentLSN(ctx context.Context) (string, error) {
+	var lsn string
+	if err := t.db.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return "", fmt.Errorf("failed to get current WAL LSN: %w", err)
+	}
+	return lsn, nil
+}
+
+// RecordBatch stores a batch of applied migrations, replacing an existing
+// record of the same batch.
+func (t *Tracker) RecordBatch(ctx context.Context, batch Batch) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (batch, started_at, finished_at, lsn_before, lsn_after)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		ON CONFLICT (batch) DO UPDATE SET
+			started_at = EXCLUDED.started_at, finished_at = EXCLUDED.finished_at,
+			lsn_before = EXCLUDED.lsn_before, lsn_after = EXCLUDED.lsn_after
+	`, BatchesTable)
+
+	_, err := t.db.ExecContext(ctx, query, batch.Batch, batch.StartedAt, batch.FinishedAt, batch.LSNBefore, batch.LSNAfter)
+	if err != nil {
+		return fmt.Errorf("failed to record batch %d: %w", batch.Batch, err)
+	}
+
+	return nil
+}
+
+// MarkBatchSuspect flags a recorded batch as suspect, e.g. because a
+// verification query failed after it was applied.
+func (t *Tracker) MarkBatchSuspect(ctx context.Context, batch int, reason string) error {
+	query := fmt.Sprintf(`UPDATE %s SET suspect = $2 WHERE batch = $1`, BatchesTable)
+
+	if _, err := t.db.ExecContext(ctx, query, batch, reason); err != nil {
+		return fmt.Errorf("failed to mark batch %d as suspect: %w", batch, err)
+	}
+	return nil
+}
+
+// GetBatches retrieves all recorded batches in order.
+func (t *Tracker) GetBatches(ctx context.Context) ([]Batch, error) {
+	query := fmt.Sprintf(`
+		SELECT batch, started_at, finished_at, COALESCE(lsn_before, ''), COALESCE(lsn_after, ''), COALESCE(suspect, '')
+		FROM %s
+		ORDER BY batch
+	`, BatchesTable)
+
+	rows, err := t.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []Batch
+	for rows.Next() {
+		var batch Batch
+		if err := rows.Scan(&batch.Batch, &batch.StartedAt, &batch.FinishedAt, &batch.LSNBefore, &batch.LSNAfter, &batch.Suspect); err != nil {
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batches: %w", err)
+	}
+
+	return batches, nil
+}
+
+// LogAttempt records a migration attempt in the log table. It runs outside of
+// the migration transaction so failed attempts are kept.
+func (t *Tracker) LogAttempt(ctx context.Context, attempt Attempt) error {
+	outcome := "success"
+	var errText sql.NullString
+	if attempt.Err != nil {
+		outcome = "failure"
+		errText = sql.NullString{String: attempt.Err.Error(), Valid: true}
+	}
+
+	var rowCounts sql.NullString
+	if len(attempt.RowCounts) > 0 {
+		encoded, err := json.Marshal(attempt.RowCounts)
+		if err != nil {
+			return fmt.Errorf("failed to encode row counts: %w", err)
+		}
+		rowCounts = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, target, started_at, finished_at, outcome, error, row_counts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, LogTable)
+
+	_, err := t.db.ExecContext(ctx, query, attempt.Name, string(attempt.Target),
+		attempt.StartedAt, attempt.FinishedAt, outcome, errText, rowCounts)
+	if err != nil {
+		return fmt.Errorf("failed to log migration attempt: %w", err)
 	}
 
 	return nil
 }
 
+// TableExists reports whether the migrations tracking table exists.
+// Unlike EnsureMigrationsTable it never modifies the database.
+func (t *Tracker) TableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := t.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", MigrationsTable).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+
+	return exists, nil
+}
+
 // IsApplied checks if a migration has been applied.
 func (t *Tracker) IsApplied(ctx context.Context, migrationName string) (bool, error) {
 	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE name = $1", MigrationsTable)
@@ -52,14 +500,51 @@ func (t *Tracker) IsApplied(ctx context.Context, migrationName string) (bool, er
 	return count > 0, nil
 }
 
-// Record records a migration as applied.
-func (t *Tracker) Record(ctx context.Context, migrationName string) error {
-	query := fmt.Sprintf("INSERT INTO %s (name) VALUES ($1)", MigrationsTable)
+// AppliedSet returns the names of all applied migrations, fetched with a
+// single query, for checking many migrations without a query each.
+func (t *Tracker) AppliedSet(ctx context.Context) (map[string]bool, error) {
+	names, err := t.GetAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if _, err := t.db.ExecContext(ctx, query, migrationName); err != nil {
-		return fmt.Errorf("failed to record migration: %w", err)
+	applied := make(map[string]bool, len(names))
+	for _, name := range names {
+		applied[name] = true
 	}
+	return applied, nil
+}
+
+// Record records a migration as applied without executing it, with the
+// checksum of its content and no duration.
+func (t *Tracker) Record(ctx context.Context, migration Migration) error {
+	return t.record(ctx, t.db, migration, 0)
+}
 
+// RecordWith records migrations as applied and runs statements in the same
+// transaction, so the tracking table changes exactly when the statements
+// take effect, e.g. when a prepared schema is switched live.
+func (t *Tracker) RecordWith(ctx context.Context, migrations []Migration, statements []string) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, migration := range migrations {
+		if err := t.record(ctx, tx, migration, 0); err != nil {
+			return err
+		}
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", statement, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
 	return nil
 }
 
@@ -89,15 +574,108 @@ func (t *Tracker) GetAppliedMigrations(ctx context.Context) ([]string, error) {
 	return migrations, nil
 }
 
-// ApplyMigration applies a single migration within a transaction.
-func (t *Tracker) ApplyMigration(ctx context.Context, migrationName, content string) error {
+// GetAppliedRecords retrieves the tracking rows of all applied migrations in apply order.
+func (t *Tracker) GetAppliedRecords(ctx context.Context) ([]Record, error) {
+	query := fmt.Sprintf(`
+		SELECT name, applied_at, duration_ms,
+			COALESCE(applied_by, ''), COALESCE(hostname, ''), COALESCE(app_version, ''),
+			COALESCE(checksum, ''), COALESCE(batch, 0),
+			COALESCE(description, ''), COALESCE(author, ''), COALESCE(ticket, ''), COALESCE(risk, '')
+		FROM %s
+		ORDER BY applied_at, id
+	`, MigrationsTable)
+
+	rows, err := t.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var record Record
+		var durationMS sql.NullInt64
+		err := rows.Scan(&record.Name, &record.AppliedAt, &durationMS,
+			&record.AppliedBy, &record.Hostname, &record.AppVersion,
+			&record.Checksum, &record.Batch,
+			&record.Metadata.Description, &record.Metadata.Author, &record.Metadata.Ticket, &record.Metadata.Risk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan migration record: %w", err)
+		}
+		record.Duration = time.Duration(durationMS.Int64) * time.Millisecond
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating migrations: %w", err)
+	}
+
+	return records, nil
+}
+
+// GetContent returns the stored SQL content of an applied migration.
+// The second return value is false if the migration isn't applied or was
+// applied without content storage enabled.
+func (t *Tracker) GetContent(ctx context.Context, migrationName string) (string, bool, error) {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE name = $1", MigrationsTable)
+
+	var content sql.NullString
+	err := t.db.QueryRowContext(ctx, query, migrationName).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get migration content: %w", err)
+	}
+
+	return content.String, content.Valid, nil
+}
+
+// NextBatch returns the batch number for a new run: one more than the highest recorded.
+func (t *Tracker) NextBatch(ctx context.Context) (int, error) {
+	query := fmt.Sprintf("SELECT COALESCE(MAX(batch), 0) + 1 FROM %s", MigrationsTable)
+
+	var batch int
+	if err := t.db.QueryRowContext(ctx, query).Scan(&batch); err != nil {
+		return 0, fmt.Errorf("failed to get next batch number: %w", err)
+	}
+
+	return batch, nil
+}
+
+// ApplyMigration applies a single migration within a transaction and returns
+// the rows affected by each of its DML statements. Migrations marked
+// NoTransaction instead run statement by statement on a single connection
+// without a transaction; a failing statement leaves the ones before it
+// applied. Their CREATE INDEX CONCURRENTLY statements clean up INVALID
+// leftovers, retry and wait as described on CreateIndex, so the migration is
+// only recorded with a valid index.
+func (t *Tracker) ApplyMigration(ctx context.Context, migration Migration) ([]RowCount, error) {
+	return t.apply(ctx, migration, true)
+}
+
+// Replay runs a migration like ApplyMigration without recording it in the
+// tracking table, e.g. to build a copy of the schema.
+func (t *Tracker) Replay(ctx context.Context, migration Migration) ([]RowCount, error) {
+	return t.apply(ctx, migration, false)
+}
+
+// apply runs a migration, recording it in the same transaction when record
+// is set.
+func (t *Tracker) apply(ctx context.Context, migration Migration, record bool) ([]RowCount, error) {
+	if migration.NoTransaction {
+		return t.applyNoTransaction(ctx, migration, record)
+	}
+
+	migrationName, content := migration.Name, migration.Content
+
 	// Start transaction with isolation level
 	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelReadCommitted,
+		Isolation: t.isolationFor(migration),
 		ReadOnly:  false,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	// Track if we need to rollback
@@ -105,30 +683,478 @@ func (t *Tracker) ApplyMigration(ctx context.Context, migrationName, content str
 	defer func() {
 		if shouldRollback {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				fmt.Printf("⚠️  Warning: Failed to rollback transaction for %s: %v\n", migrationName, rbErr)
+				t.log.Warnf("Failed to rollback transaction for %s: %v", migrationName, rbErr)
 			}
 		}
 	}()
 
+	// Run the migration with the configured session settings
+	restore, err := t.enterMigrationSession(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply the migration SQL
-	if _, err := tx.ExecContext(ctx, content); err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+	// Statements run one at a time when they are reported on individually
+	start := time.Now()
+	var rowCounts []RowCount
+	statements := sqlparse.Split(content)
+	if t.statementSavepoints || t.profiler != nil || hasDML(statements) {
+		if rowCounts, err = t.execStatements(ctx, tx, migrationName, statements); err != nil {
+			return nil, fmt.Errorf("failed to execute migration: %w", err)
+		}
+	} else if _, err := tx.ExecContext(ctx, content); err != nil {
+		return nil, fmt.Errorf("failed to execute migration: %w", err)
+	}
+
+	for _, c := range migration.Copies {
+		rows, err := copyFrom(ctx, tx, c)
+		if err != nil {
+			return nil, err
+		}
+		t.log.Debugf("  📥 Copied %d rows into %s from %s", rows, c.Table, filepath.Base(c.Path))
+	}
+	duration := time.Since(start)
+
+	if err := restore(); err != nil {
+		return nil, err
 	}
 
 	// Record the migration in tracking table
-	recordQuery := fmt.Sprintf("INSERT INTO %s (name) VALUES ($1)", MigrationsTable)
-	if _, err := tx.ExecContext(ctx, recordQuery, migrationName); err != nil {
-		return fmt.Errorf("failed to record migration: %w", err)
+	if record {
+		if err := t.record(ctx, tx, migration, duration); err != nil {
+			return nil, err
+		}
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit migration: %w", err)
+		return nil, fmt.Errorf("failed to commit migration: %w", err)
 	}
 
 	// Mark that we don't need to rollback since commit succeeded
 	shouldRollback = false
 
-	fmt.Printf("✓ Applied migration (atomic): %s\n", migrationName)
+	t.log.Debugf("✓ Applied migration (atomic): %s", migrationName)
+	return rowCounts, nil
+}
+
+// applyNoTransaction applies a no-transaction migration. Its statements run
+// on one connection, so transactions they begin and commit themselves, and
+// session settings, carry over between statements.
+func (t *Tracker) applyNoTransaction(ctx context.Context, migration Migration, record bool) (rowCounts []RowCount, err error) {
+	var db session = t.db
+	if pool, ok := t.db.(interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+	}); ok {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connection: %w", err)
+		}
+		defer conn.Close()
+		db = conn
+	}
+
+	restore, err := t.enterSession(ctx, db, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Don't leave a transaction opened by the migration behind on a
+		// connection that is reused; outside a transaction this only warns
+		if _, rbErr := db.ExecContext(context.WithoutCancel(ctx), "ROLLBACK"); rbErr != nil {
+			t.log.Warnf("Failed to roll back open transaction of %s: %v", migration.Name, rbErr)
+		}
+		if rsErr := restore(); rsErr != nil {
+			t.log.Warnf("Failed to restore session of %s: %v", migration.Name, rsErr)
+		}
+	}()
+
+	start := time.Now()
+	for _, statement := range sqlparse.Split(migration.Content) {
+		var res sql.Result
+		if _, ok := concurrentIndex(statement.Text); ok {
+			res, err = t.createIndex(ctx, db, statement.Text)
+		} else {
+			res, err = db.ExecContext(ctx, statement.Text)
+		}
+		if err != nil {
+			err = &StatementError{Index: statement.Index, Line: statement.Line, Statement: statement.Text, Err: err}
+			if statement.Index > 0 {
+				return nil, fmt.Errorf("failed to execute migration without a transaction (statements before %d remain applied): %w", statement.Index+1, err)
+			}
+			return nil, fmt.Errorf("failed to execute migration: %w", err)
+		}
+
+		if isDML(statement.Text) {
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get affected rows: %w", err)
+			}
+			rowCounts = append(rowCounts, RowCount{
+				Migration: migration.Name,
+				Index:     statement.Index,
+				Line:      statement.Line,
+				Command:   command(statement.Text),
+				Rows:      rows,
+			})
+		}
+	}
+	duration := time.Since(start)
+
+	if err := restore(); err != nil {
+		return nil, err
+	}
+
+	if record {
+		if err := t.record(ctx, db, migration, duration); err != nil {
+			return nil, err
+		}
+	}
+
+	t.log.Debugf("✓ Applied migration (no transaction): %s", migration.Name)
+	return rowCounts, nil
+}
+
+// ChunkOptions configures a migration applied as repeated batches.
+type ChunkOptions struct {
+	// Pause is the time to wait between batches.
+	Pause time.Duration
+
+	// BatchTimeout bounds each batch. Zero means no limit beyond the context.
+	BatchTimeout time.Duration
+}
+
+// ApplyChunked applies a single-statement migration by running it repeatedly,
+// each run in its own transaction, until it affects no rows. The statement
+// must limit itself to a batch of rows still to be processed, e.g.
+//
+//	DELETE FROM events WHERE id IN (SELECT id FROM events WHERE created_at < '2020-01-01' LIMIT 5000)
+//
+// Progress is saved after each batch, and the migration is recorded once a
+// run affects no rows. An interrupted migration stays pending and continues
+// with the remaining rows when applied again. The returned row count totals
+// the rows affected by all batches, including those of interrupted runs.
+func (t *Tracker) ApplyChunked(ctx context.Context, migration Migration, opts ChunkOptions) ([]RowCount, error) {
+	if err := t.EnsureProgressTable(ctx); err != nil {
+		return nil, err
+	}
+
+	progress, _, err := t.GetProgress(ctx, migration.Name)
+	if err != nil {
+		return nil, err
+	}
+	progress.Step = "chunked"
+	progress.Completed = false
+
+	start := time.Now()
+	for {
+		rows, err := t.applyChunk(ctx, migration, opts.BatchTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute batch after %d rows: %w", progress.Rows, err)
+		}
+		if rows == 0 {
+			break
+		}
+
+		progress.Rows += rows
+		if err := t.SaveProgress(ctx, progress); err != nil {
+			return nil, err
+		}
+		t.log.Debugf("  ↻ %s: %d rows", migration.Name, progress.Rows)
+
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+
+	if err := t.record(ctx, t.db, migration, time.Since(start)); err != nil {
+		return nil, err
+	}
+
+	progress.Completed = true
+	if err := t.SaveProgress(ctx, progress); err != nil {
+		return nil, err
+	}
+
+	t.log.Debugf("✓ Applied migration (chunked, %d rows): %s", progress.Rows, migration.Name)
+
+	count := RowCount{Migration: migration.Name, Line: 1, Command: command(migration.Content), Rows: progress.Rows}
+	if statements := sqlparse.Split(migration.Content); len(statements) > 0 {
+		count.Line = statements[0].Line
+	}
+	return []RowCount{count}, nil
+}
+
+// applyChunk runs one batch of a chunked migration in its own transaction and
+// returns the number of rows it affected.
+func (t *Tracker) applyChunk(ctx context.Context, migration Migration, timeout time.Duration) (int64, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{Isolation: t.isolationFor(migration)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	restore, err := t.enterMigrationSession(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, migration.Content)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if err := restore(); err != nil {
+		return 0, err
+	}
+
+	return rows, tx.Commit()
+}
+
+// StatementError is returned in statement savepoint mode when a statement of
+// a migration fails.
+type StatementError struct {
+	// Index is the zero-based position of the statement in the migration.
+	Index int
+
+	// Line is the 1-based line on which the statement starts.
+	Line int
+
+	// Statement is the failing statement.
+	Statement string
+
+	Err error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %d at line %d failed: %v", e.Index+1, e.Line, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// execStatements executes statements one at a time and returns the rows
+// affected by the DML statements. With savepoints (statement savepoint mode
+// or a profiler set), each statement runs under a savepoint and the failing
+// statement is rolled back to its savepoint and reported; the caller rolls
+// back the transaction as a whole. With a profiler set, each statement is
+// timed and DML is explained before it runs.
+func (t *Tracker) execStatements(ctx context.Context, tx *sql.Tx, migrationName string, statements []sqlparse.Statement) ([]RowCount, error) {
+	savepoints := t.statementSavepoints || t.profiler != nil
+
+	var rowCounts []RowCount
+	for _, statement := range statements {
+		if savepoints {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT migrator_statement"); err != nil {
+				return nil, fmt.Errorf("failed to create savepoint: %w", err)
+			}
+		}
+
+		dml := isDML(statement.Text)
+		profile := StatementProfile{Migration: migrationName, Index: statement.Index, Line: statement.Line, Statement: statement.Text}
+		if t.profiler != nil && dml {
+			plan, err := explain(ctx, tx, statement.Text)
+			if err != nil {
+				t.log.Debugf("  Could not explain statement %d of %s: %v", statement.Index+1, migrationName, err)
+				if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT migrator_statement"); rbErr != nil {
+					return nil, fmt.Errorf("failed to roll back to savepoint: %w", rbErr)
+				}
+			}
+			profile.Plan = plan
+		}
+
+		start := time.Now()
+		res, err := tx.ExecContext(ctx, statement.Text)
+		if err != nil {
+			if savepoints {
+				if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT migrator_statement"); rbErr != nil {
+					t.log.Warnf("Failed to roll back to savepoint: %v", rbErr)
+				}
+			}
+			return nil, &StatementError{Index: statement.Index, Line: statement.Line, Statement: statement.Text, Err: err}
+		}
+		profile.Duration = time.Since(start)
+
+		if dml {
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get affected rows: %w", err)
+			}
+			rowCounts = append(rowCounts, RowCount{
+				Migration: migrationName,
+				Index:     statement.Index,
+				Line:      statement.Line,
+				Command:   command(statement.Text),
+				Rows:      rows,
+			})
+		}
+
+		if savepoints {
+			if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT migrator_statement"); err != nil {
+				return nil, fmt.Errorf("failed to release savepoint: %w", err)
+			}
+		}
+
+		if t.profiler != nil {
+			t.profiler(profile)
+		}
+	}
+
+	return rowCounts, nil
+}
+
+// hasDML reports whether any of the statements is DML.
+func hasDML(statements []sqlparse.Statement) bool {
+	for _, statement := range statements {
+		if isDML(statement.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDML reports whether a statement inserts, updates or deletes rows.
+func isDML(statement string) bool {
+	switch command(statement) {
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "WITH":
+		return true
+	}
+	return false
+}
+
+// command returns the leading keyword of a statement, upper-cased.
+func command(statement string) string {
+	keyword, _, _ := strings.Cut(sqlparse.Normalize(statement), " ")
+	return keyword
+}
+
+// isolationFor returns the isolation level to apply a migration with.
+func (t *Tracker) isolationFor(migration Migration) sql.IsolationLevel {
+	if migration.Isolation != sql.LevelDefault {
+		return migration.Isolation
+	}
+	if t.isolation != sql.LevelDefault {
+		return t.isolation
+	}
+	return sql.LevelReadCommitted
+}
+
+// execer is satisfied by both DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// rowQuerier is satisfied by both DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// session is satisfied by DB, *sql.Conn and *sql.Tx.
+type session interface {
+	execer
+	rowQuerier
+}
+
+// record inserts a migration into the tracking table.
+func (t *Tracker) record(ctx context.Context, db execer, migration Migration, duration time.Duration) error {
+	var storedContent sql.NullString
+	if t.storeContent && !migration.OmitContent {
+		storedContent = sql.NullString{String: migration.Content, Valid: true}
+	}
+
+	checksum := migration.Checksum
+	if checksum == "" {
+		checksum = Checksum(migration.Content)
+	}
+
+	var batch sql.NullInt64
+	if migration.Batch > 0 {
+		batch = sql.NullInt64{Int64: int64(migration.Batch), Valid: true}
+	}
+
+	recordQuery := fmt.Sprintf(`
+		INSERT INTO %s (name, duration_ms, applied_by, hostname, app_version, content, checksum, batch,
+			description, author, ticket, risk)
+		VALUES ($1, $2, current_user, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''))
+	`, MigrationsTable)
+	metadata := migration.Metadata
+	_, err := db.ExecContext(ctx, recordQuery, migration.Name, duration.Milliseconds(),
+		t.hostname, t.appVersion, storedContent, checksum, batch,
+		metadata.Description, metadata.Author, metadata.Ticket, metadata.Risk)
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
 	return nil
 }
+
+// enterMigrationSession applies the configured role and search_path to tx for
+// executing migration SQL. The returned function switches back to the
+// connection's own settings, so the tracking table is written by the
+// connecting user and found through its search_path.
+func (t *Tracker) enterMigrationSession(ctx context.Context, tx *sql.Tx) (func() error, error) {
+	return t.enterSession(ctx, tx, true)
+}
+
+// enterSession applies the configured role and search_path to db, scoped to
+// the current transaction when local is set and to the session otherwise.
+// The returned function restores the previous settings.
+func (t *Tracker) enterSession(ctx context.Context, db session, local bool) (func() error, error) {
+	if t.searchPath == "" && t.role == "" {
+		return func() error { return nil }, nil
+	}
+
+	var originalSearchPath string
+	if err := db.QueryRowContext(ctx, "SELECT current_setting('search_path')").Scan(&originalSearchPath); err != nil {
+		return nil, fmt.Errorf("failed to get search_path: %w", err)
+	}
+
+	set := "SET "
+	if local {
+		set = "SET LOCAL "
+	}
+
+	if t.role != "" {
+		// Roles can't be bound as parameters
+		if _, err := db.ExecContext(ctx, set+"ROLE "+pq.QuoteIdentifier(t.role)); err != nil {
+			return nil, fmt.Errorf("failed to set role %s: %w", t.role, err)
+		}
+	}
+	if t.searchPath != "" {
+		if _, err := db.ExecContext(ctx, "SELECT set_config('search_path', $1, $2)", t.searchPath, local); err != nil {
+			return nil, fmt.Errorf("failed to set search_path: %w", err)
+		}
+	}
+
+	restore := func() error {
+		if t.role != "" {
+			if _, err := db.ExecContext(ctx, set+"ROLE NONE"); err != nil {
+				return fmt.Errorf("failed to reset role: %w", err)
+			}
+		}
+		if _, err := db.ExecContext(ctx, "SELECT set_config('search_path', $1, $2)", originalSearchPath, local); err != nil {
+			return fmt.Errorf("failed to restore search_path: %w", err)
+		}
+		return nil
+	}
+
+	return restore, nil
+}
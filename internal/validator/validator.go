@@ -2,115 +2,321 @@
 package validator
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/hasirciogluhq/migrator/internal/output"
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
 	"github.com/hasirciogluhq/migrator/internal/tracker"
 )
 
+// IgnoreFile is the name of the file in the migrations directory listing
+// patterns of files to ignore, one per line. Blank lines and lines starting
+// with # are skipped.
+const IgnoreFile = ".migratorignore"
+
 // Validator validates migration files and their consistency.
 type Validator struct {
 	tracker        *tracker.Tracker
 	migrationsPath string
+	ignorePatterns []string
+	log            *output.Logger
+}
+
+// Options configures which files the Validator considers.
+type Options struct {
+	// IgnorePatterns are filepath.Match patterns of file names in the
+	// migrations directory that are not migrations, e.g. "*.tmp.sql".
+	// Patterns in the directory's .migratorignore file apply as well.
+	IgnorePatterns []string
+
+	// Logger receives progress messages. Defaults to info level on stdout.
+	Logger *output.Logger
 }
 
 // New creates a new Validator instance.
 func New(t *tracker.Tracker, migrationsPath string) *Validator {
+	return NewWithOptions(t, migrationsPath, Options{})
+}
+
+// NewWithOptions creates a new Validator instance with custom options.
+func NewWithOptions(t *tracker.Tracker, migrationsPath string, opts Options) *Validator {
 	return &Validator{
 		tracker:        t,
 		migrationsPath: migrationsPath,
+		ignorePatterns: opts.IgnorePatterns,
+		log:            opts.Logger,
 	}
 }
 
+// WithTracker returns a copy of the validator that uses t, keeping its options.
+func (v *Validator) WithTracker(t *tracker.Tracker) *Validator {
+	copied := *v
+	copied.tracker = t
+	return &copied
+}
+
 // ValidateExistingMigrations checks if all applied migrations still exist in filesystem.
 func (v *Validator) ValidateExistingMigrations(ctx context.Context) error {
-	fmt.Println("🔍 Validating existing migrations...")
+	v.log.Infof("🔍 Validating existing migrations...")
 
+	appliedMigrations, missingMigrations, err := v.checkExistingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	return v.reportMissing(appliedMigrations, missingMigrations)
+}
+
+// ValidateApplied checks that all applied migrations exist among files, like
+// ValidateExistingMigrations but with both already loaded.
+func (v *Validator) ValidateApplied(appliedMigrations []string, files []*MigrationFile) error {
+	v.log.Infof("🔍 Validating existing migrations...")
+
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		names[file.Name] = true
+	}
+
+	return v.reportMissing(appliedMigrations, missing(appliedMigrations, names))
+}
+
+// reportMissing fails when applied migrations are missing from filesystem.
+func (v *Validator) reportMissing(appliedMigrations, missingMigrations []string) error {
+	if len(missingMigrations) > 0 {
+		return fmt.Errorf("critical: %d applied migrations are missing from filesystem: %v "+
+			"(if content storage was enabled, RestoreFiles can recover them)",
+			len(missingMigrations), missingMigrations)
+	}
+
+	v.log.Infof("✓ All %d applied migrations validated successfully", len(appliedMigrations))
+	return nil
+}
+
+// FindMissingMigrations returns the applied migrations that no longer exist in filesystem.
+func (v *Validator) FindMissingMigrations(ctx context.Context) ([]string, error) {
+	_, missingMigrations, err := v.checkExistingMigrations(ctx)
+	return missingMigrations, err
+}
+
+// checkExistingMigrations returns all applied migrations and those missing from filesystem.
+func (v *Validator) checkExistingMigrations(ctx context.Context) ([]string, []string, error) {
 	// Get all applied migrations from database
 	appliedMigrations, err := v.tracker.GetAppliedMigrations(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
+		return nil, nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
 	// Get all migration files from filesystem
-	files, err := os.ReadDir(v.migrationsPath)
+	files, err := v.readMigrationsDir()
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
+		return nil, nil, err
 	}
 
 	// Create a map of filesystem files for quick lookup
 	fsFiles := make(map[string]bool)
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".sql") {
-			fsFiles[file.Name()] = true
-		}
+		fsFiles[file.Name()] = true
 	}
 
-	// Check if all applied migrations exist in filesystem
+	return appliedMigrations, missing(appliedMigrations, fsFiles), nil
+}
+
+// missing returns the applied migrations that are not among files.
+func missing(appliedMigrations []string, files map[string]bool) []string {
 	var missingMigrations []string
 	for _, appliedMigration := range appliedMigrations {
-		if !fsFiles[appliedMigration] {
+		if !files[appliedMigration] {
 			missingMigrations = append(missingMigrations, appliedMigration)
 		}
 	}
+	return missingMigrations
+}
 
-	if len(missingMigrations) > 0 {
-		return fmt.Errorf("critical: %d applied migrations are missing from filesystem: %v",
-			len(missingMigrations), missingMigrations)
+// GetMigrationFiles reads and parses all migration files from the migrations directory.
+func (v *Validator) GetMigrationFiles(ctx context.Context) ([]*MigrationFile, error) {
+	migrationFiles, err := v.ListMigrationFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := LoadAll(ctx, migrationFiles); err != nil {
+		return nil, err
+	}
+
+	return migrationFiles, nil
+}
+
+// ListMigrationFiles lists the migration files in the migrations directory
+// without reading them. Call Load (or LoadAll) before using their content.
+func (v *Validator) ListMigrationFiles(ctx context.Context) ([]*MigrationFile, error) {
+	files, err := v.readMigrationsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	migrationFiles := make([]*MigrationFile, 0, len(files))
+	for _, file := range files {
+		migrationFiles = append(migrationFiles, &MigrationFile{
+			Name:     file.Name(),
+			dir:      v.migrationsPath,
+			tracker:  v.tracker,
+			unloaded: true,
+		})
+	}
+
+	return migrationFiles, nil
+}
+
+// LoadAll reads the content of migration files that haven't been read yet.
+func LoadAll(ctx context.Context, migrations []*MigrationFile) error {
+	for _, migration := range migrations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := migration.Load(); err != nil {
+			return err
+		}
 	}
 
-	fmt.Printf("✓ All %d applied migrations validated successfully\n", len(appliedMigrations))
 	return nil
 }
 
-// GetMigrationFiles reads and parses all migration files from the migrations directory.
-func (v *Validator) GetMigrationFiles(ctx context.Context) ([]*MigrationFile, error) {
-	files, err := os.ReadDir(v.migrationsPath)
+// readMigrationsDir returns the migration files in the migrations directory,
+// sorted by name, leaving out ignored files.
+func (v *Validator) readMigrationsDir() ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(v.migrationsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	var migrationFiles []*MigrationFile
+	patterns, err := v.loadIgnorePatterns()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".sql") {
+	var files []os.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() || !IsMigrationFile(entry.Name()) {
 			continue
 		}
 
-		migrationFile, err := v.createMigrationFile(ctx, file)
+		ignored, err := matchAny(patterns, entry.Name())
 		if err != nil {
-			return nil, fmt.Errorf("failed to create migration file for %s: %w", file.Name(), err)
+			return nil, err
+		}
+		if !ignored {
+			files = append(files, entry)
 		}
-
-		migrationFiles = append(migrationFiles, migrationFile)
 	}
 
-	return migrationFiles, nil
+	return files, nil
 }
 
-// createMigrationFile creates a MigrationFile struct for a given file.
-func (v *Validator) createMigrationFile(ctx context.Context, file os.DirEntry) (*MigrationFile, error) {
-	filePath := filepath.Join(v.migrationsPath, file.Name())
-	content, err := os.ReadFile(filePath)
+// loadIgnorePatterns returns the configured ignore patterns followed by those
+// in the migrations directory's ignore file, if it exists.
+func (v *Validator) loadIgnorePatterns() ([]string, error) {
+	patterns := append([]string(nil), v.ignorePatterns...)
+
+	content, err := os.ReadFile(filepath.Join(v.migrationsPath, IgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return patterns, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFile, err)
 	}
 
-	return &MigrationFile{
-		Name:    file.Name(),
-		Content: string(content),
-		tracker: v.tracker,
-	}, nil
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+
+	return patterns, nil
+}
+
+// matchAny reports whether name matches any of the patterns.
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // MigrationFile represents a single migration file.
 type MigrationFile struct {
-	Name    string
+	Name string
+
+	// Content is the normalized SQL of the migration. It is empty for files
+	// from ListMigrationFiles until Load is called.
 	Content string
+
+	dir     string
 	tracker *tracker.Tracker
+
+	// checksum is the checksum of Content, computed when it was loaded
+	checksum string
+
+	// rawChecksum is the checksum of the file before normalization, if it
+	// differs from that of Content
+	rawChecksum string
+
+	// unloaded is set for listed files whose content hasn't been read yet
+	unloaded bool
+}
+
+// Load reads the migration's content from its file, if it hasn't been read
+// yet.
+func (m *MigrationFile) Load() error {
+	if !m.unloaded {
+		return nil
+	}
+
+	raw, checksum, err := readFile(filepath.Join(m.dir, m.Name))
+	if err != nil {
+		return fmt.Errorf("failed to read migration file %s: %w", m.Name, err)
+	}
+
+	m.Content = Normalize(raw)
+	m.checksum = checksum
+	if m.Content != raw {
+		m.rawChecksum = checksum
+		m.checksum = tracker.Checksum(m.Content)
+	}
+	m.unloaded = false
+
+	return nil
+}
+
+// Compressed reports whether the migration is stored gzip-compressed. The
+// content of compressed migrations isn't stored in the migrations table, see
+// Options.StoreContent.
+func (m *MigrationFile) Compressed() bool {
+	return strings.HasSuffix(m.Name, ".gz")
+}
+
+// Version returns the version of this migration, see VersionFromName.
+func (m *MigrationFile) Version() string {
+	return VersionFromName(m.Name)
 }
 
 // IsApplied checks if this migration has been applied to the database.
@@ -118,25 +324,417 @@ func (m *MigrationFile) IsApplied(ctx context.Context) (bool, error) {
 	return m.tracker.IsApplied(ctx, m.Name)
 }
 
-// Apply applies this migration to the database.
-func (m *MigrationFile) Apply(ctx context.Context) error {
-	return m.tracker.ApplyMigration(ctx, m.Name, m.Content)
+// Checksum returns the SHA-256 of this migration's content.
+func (m *MigrationFile) Checksum() string {
+	if m.checksum != "" {
+		return m.checksum
+	}
+	return tracker.Checksum(m.Content)
 }
 
-// FindNewMigrations identifies which migrations haven't been applied yet.
-func FindNewMigrations(ctx context.Context, allMigrations []*MigrationFile) ([]*MigrationFile, error) {
-	var newMigrations []*MigrationFile
+// MatchesChecksum reports whether a recorded checksum matches this migration.
+// Checksums of the file before normalization also match, so migrations
+// applied before content was normalized aren't reported as modified.
+func (m *MigrationFile) MatchesChecksum(checksum string) bool {
+	return checksum == m.Checksum() || (m.rawChecksum != "" && checksum == m.rawChecksum)
+}
+
+// Apply applies this migration to the database as part of the given batch.
+// Chunked migrations are applied in repeated batches, see Chunk. It returns
+// the rows affected by the migration's DML statements.
+func (m *MigrationFile) Apply(ctx context.Context, batch int) ([]tracker.RowCount, error) {
+	migration, err := m.Migration(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, chunked, err := m.Chunk()
+	if err != nil {
+		return nil, err
+	}
+	if chunked {
+		return m.tracker.ApplyChunked(ctx, migration, opts)
+	}
+
+	return m.tracker.ApplyMigration(ctx, migration)
+}
+
+// Migration loads this migration and prepares it for the tracker as part of
+// the given batch, with the options set by its directives.
+func (m *MigrationFile) Migration(batch int) (tracker.Migration, error) {
+	if err := m.Load(); err != nil {
+		return tracker.Migration{}, err
+	}
+
+	copies, err := m.Copies()
+	if err != nil {
+		return tracker.Migration{}, err
+	}
+
+	isolation, err := m.Isolation()
+	if err != nil {
+		return tracker.Migration{}, err
+	}
+
+	metadata, err := m.Metadata()
+	if err != nil {
+		return tracker.Migration{}, err
+	}
+
+	return tracker.Migration{
+		Name:          m.Name,
+		Content:       m.Content,
+		Checksum:      m.Checksum(),
+		OmitContent:   m.Compressed(),
+		Batch:         batch,
+		Copies:        copies,
+		Isolation:     isolation,
+		NoTransaction: m.NoTransaction(),
+		Metadata:      metadata,
+	}, nil
+}
+
+// Chunk returns the options of a chunked migration, marked with a directive
+// such as "-- migrator:chunked pause=100ms timeout=1m". The second return
+// value is false for regular migrations. Chunked migrations must consist of
+// a single statement. Each batch times out after 5 minutes unless set.
+func (m *MigrationFile) Chunk() (tracker.ChunkOptions, bool, error) {
+	opts := tracker.ChunkOptions{BatchTimeout: 5 * time.Minute}
+
+	for _, directive := range sqlparse.Directives(m.Content) {
+		if directive.Key != "chunked" {
+			continue
+		}
+
+		for _, field := range strings.Fields(directive.Value) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return opts, false, fmt.Errorf("invalid chunked option %q at line %d: expected key=value", field, directive.Line)
+			}
+			var target *time.Duration
+			switch key {
+			case "pause":
+				target = &opts.Pause
+			case "timeout":
+				target = &opts.BatchTimeout
+			default:
+				return opts, false, fmt.Errorf("unknown chunked option %q at line %d", key, directive.Line)
+			}
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return opts, false, fmt.Errorf("invalid chunked option %q at line %d: %w", field, directive.Line, err)
+			}
+			*target = duration
+		}
+
+		if statements := sqlparse.Split(m.Content); len(statements) != 1 {
+			return opts, false, fmt.Errorf("chunked migration must contain exactly one statement, found %d", len(statements))
+		}
+
+		return opts, true, nil
+	}
+
+	return opts, false, nil
+}
+
+// Verifications returns the queries declared with "-- migrator:verify <query>"
+// directives, to be run against the database after this migration is applied.
+func (m *MigrationFile) Verifications() []string {
+	var queries []string
+	for _, directive := range sqlparse.Directives(m.Content) {
+		if directive.Key == "verify" && directive.Value != "" {
+			queries = append(queries, directive.Value)
+		}
+	}
+	return queries
+}
+
+// Isolation returns the isolation level requested by this migration, see Isolation.
+func (m *MigrationFile) Isolation() (sql.IsolationLevel, error) {
+	return Isolation(m.Content)
+}
+
+// Isolation returns the transaction isolation level requested by a
+// migration's "-- migrator:isolation <level>" directive, one of "read
+// committed", "repeatable read" or "serializable". Without a directive it
+// returns sql.LevelDefault.
+func Isolation(content string) (sql.IsolationLevel, error) {
+	level := sql.LevelDefault
+
+	for _, directive := range sqlparse.Directives(content) {
+		if directive.Key != "isolation" {
+			continue
+		}
+
+		switch strings.ToLower(strings.Join(strings.Fields(directive.Value), " ")) {
+		case "read committed":
+			level = sql.LevelReadCommitted
+		case "repeatable read":
+			level = sql.LevelRepeatableRead
+		case "serializable":
+			level = sql.LevelSerializable
+		default:
+			return sql.LevelDefault, fmt.Errorf("invalid isolation level %q at line %d", directive.Value, directive.Line)
+		}
+	}
+
+	return level, nil
+}
+
+// Metadata returns the front matter of this migration, see Metadata.
+func (m *MigrationFile) Metadata() (tracker.Metadata, error) {
+	return Metadata(m.Content)
+}
+
+// Metadata returns the front matter a migration declares with directives,
+// usually in its header:
+//
+//	-- migrator:description Split names into first and last name
+//	-- migrator:author jane@example.com
+//	-- migrator:ticket https://github.com/acme/app/pull/1234
+//	-- migrator:risk high
+//
+// Risk must be "low", "medium" or "high". Each key may appear only once.
+func Metadata(content string) (tracker.Metadata, error) {
+	var metadata tracker.Metadata
+
+	seen := map[string]bool{}
+	for _, directive := range sqlparse.Directives(content) {
+		var target *string
+		switch directive.Key {
+		case "description":
+			target = &metadata.Description
+		case "author":
+			target = &metadata.Author
+		case "ticket":
+			target = &metadata.Ticket
+		case "risk":
+			target = &metadata.Risk
+		default:
+			continue
+		}
+
+		if seen[directive.Key] {
+			return tracker.Metadata{}, fmt.Errorf("duplicate %s directive at line %d", directive.Key, directive.Line)
+		}
+		seen[directive.Key] = true
+		if directive.Value == "" {
+			return tracker.Metadata{}, fmt.Errorf("empty %s directive at line %d", directive.Key, directive.Line)
+		}
+		*target = directive.Value
+	}
+
+	switch metadata.Risk = strings.ToLower(metadata.Risk); metadata.Risk {
+	case "", "low", "medium", "high":
+	default:
+		return tracker.Metadata{}, fmt.Errorf("invalid risk %q: expected low, medium or high", metadata.Risk)
+	}
+
+	return metadata, nil
+}
+
+// NoTransaction reports whether this migration runs without a transaction,
+// see NoTransaction.
+func (m *MigrationFile) NoTransaction() bool {
+	return NoTransaction(m.Content)
+}
+
+// NoTransaction reports whether a migration is marked with a
+// "-- migrator:no-transaction" directive. Such migrations run statement by
+// statement outside a transaction and may control transactions themselves.
+func NoTransaction(content string) bool {
+	for _, directive := range sqlparse.Directives(content) {
+		if directive.Key == "no-transaction" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransactionControl fails on statements that begin or end a
+// transaction (BEGIN, START TRANSACTION, COMMIT, END, ROLLBACK, ABORT, PREPARE
+// TRANSACTION). Migrations already run in a transaction of their own, which
+// such statements would commit or abandon halfway, leaving the migration
+// partly applied and unrecorded. Savepoints are allowed. Migrations marked
+// no-transaction are not checked.
+func CheckTransactionControl(content string) error {
+	if NoTransaction(content) {
+		return nil
+	}
+
+	for _, statement := range sqlparse.Split(content) {
+		fields := strings.Fields(sqlparse.Normalize(statement.Text))
+		if len(fields) == 0 {
+			continue
+		}
+
+		control := false
+		switch fields[0] {
+		case "BEGIN", "COMMIT", "END", "ABORT":
+			control = true
+		case "START", "PREPARE":
+			control = len(fields) > 1 && fields[1] == "TRANSACTION"
+		case "ROLLBACK":
+			// ROLLBACK TO SAVEPOINT stays within the transaction
+			control = len(fields) == 1 || fields[1] != "TO"
+		}
+		if control {
+			return fmt.Errorf("line %d: %s controls the transaction, but migrations already run in one (remove it, or add \"-- migrator:no-transaction\" to run the migration without a transaction)",
+				statement.Line, fields[0])
+		}
+	}
+
+	return nil
+}
+
+// Copies returns the data files this migration loads, see Copies.
+func (m *MigrationFile) Copies() ([]tracker.Copy, error) {
+	return Copies(m.dir, m.Name, m.Content)
+}
+
+// Copies returns the data files loaded by a migration, declared with
+// directives such as "-- migrator:copy countries". By default a directive
+// loads the companion file named like the migration with a .csv extension
+// (001_seed_countries.csv for 001_seed_countries.sql); "-- migrator:copy
+// countries FROM countries.tsv" names the file, relative to the migrations
+// directory. Missing files are an error.
+func Copies(migrationsPath, name, content string) ([]tracker.Copy, error) {
+	var copies []tracker.Copy
+
+	for _, directive := range sqlparse.Directives(content) {
+		if directive.Key != "copy" {
+			continue
+		}
 
+		fields := strings.Fields(directive.Value)
+		file := TrimExtension(name) + ".csv"
+		switch {
+		case len(fields) == 1:
+		case len(fields) == 3 && strings.EqualFold(fields[1], "FROM"):
+			file = fields[2]
+		default:
+			return nil, fmt.Errorf("invalid copy directive at line %d: expected \"copy <table> [FROM <file>]\"", directive.Line)
+		}
+
+		path := filepath.Join(migrationsPath, file)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("data file for copy directive at line %d: %w", directive.Line, err)
+		}
+
+		copies = append(copies, tracker.Copy{Table: fields[0], Path: path})
+	}
+
+	return copies, nil
+}
+
+// NewMigrations returns the migrations whose names are not in applied, in
+// their original order.
+func NewMigrations(allMigrations []*MigrationFile, applied map[string]bool) []*MigrationFile {
+	var newMigrations []*MigrationFile
 	for _, migration := range allMigrations {
-		isApplied, err := migration.IsApplied(ctx)
+		if !applied[migration.Name] {
+			newMigrations = append(newMigrations, migration)
+		}
+	}
+
+	return newMigrations
+}
+
+// VersionFromName returns the numeric prefix of a migration file name
+// ("003" for "003_add_index.sql"). Names without a numeric prefix use the
+// name without its extension as version.
+func VersionFromName(name string) string {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	if end > 0 {
+		return name[:end]
+	}
+	return TrimExtension(name)
+}
+
+// IsMigrationFile reports whether a file name is a migration: plain .sql or
+// gzip-compressed .sql.gz.
+func IsMigrationFile(name string) bool {
+	return strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".sql.gz")
+}
+
+// TrimExtension returns a migration file name without its .sql or .sql.gz
+// extension.
+func TrimExtension(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".sql")
+}
+
+// Normalize strips a leading UTF-8 byte order mark and converts CRLF line
+// endings to LF, so migrations authored on Windows parse and checksum the
+// same as everywhere else.
+func Normalize(content string) string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+// readFile reads a migration file as is, decompressing .gz files. It also
+// returns the checksum of the content, the same as tracker.Checksum but
+// computed while reading, so large generated migrations are read only once.
+func readFile(path string) (string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	size, err := contentSize(file, path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check migration %s: %w", migration.Name, err)
+			return "", "", fmt.Errorf("failed to decompress file: %w", err)
 		}
+		defer gz.Close()
+		reader = gz
+	}
 
-		if !isApplied {
-			newMigrations = append(newMigrations, migration)
+	// The content is copied once into the builder, where a []byte from
+	// io.ReadAll would be copied again into the string
+	var content strings.Builder
+	content.Grow(size)
+	hash := sha256.New()
+	if _, err := io.Copy(&content, io.TeeReader(reader, hash)); err != nil {
+		return "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return content.String(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// maxSizeHint bounds the buffer allocated up front for a migration file, so
+// a corrupt gzip trailer can't claim gigabytes.
+const maxSizeHint = 1 << 30
+
+// contentSize estimates the size of a migration file's content: the file
+// size, or for .gz files the uncompressed size recorded in the gzip trailer
+// (modulo 4 GiB).
+func contentSize(file *os.File, path string) (int, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	if strings.HasSuffix(path, ".gz") {
+		var trailer [4]byte
+		if size < int64(len(trailer)) {
+			return 0, nil
+		}
+		if _, err := file.ReadAt(trailer[:], size-int64(len(trailer))); err != nil {
+			return 0, err
 		}
+		size = int64(binary.LittleEndian.Uint32(trailer[:]))
 	}
 
-	return newMigrations, nil
+	return int(min(size, maxSizeHint)), nil
 }
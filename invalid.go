@@ -0,0 +1,81 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+)
+
+// InvalidObject is an index or constraint PostgreSQL does not consider
+// valid, typically left behind by a failed CREATE INDEX CONCURRENTLY or an
+// ADD CONSTRAINT ... NOT VALID that was never validated.
+//
+// An INVALID index still slows down every write to its table without ever
+// being used by queries, and blocks a later CREATE INDEX IF NOT EXISTS of the
+// same name. A NOT VALID constraint is not enforced for existing rows.
+type InvalidObject struct {
+	// Kind is "index" or "constraint".
+	Kind string `json:"kind"`
+
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	Name   string `json:"name"`
+
+	// Definition is the object's definition as reported by PostgreSQL.
+	Definition string `json:"definition"`
+}
+
+// String returns e.g. `index public.users_email_idx on users`.
+func (o InvalidObject) String() string {
+	return fmt.Sprintf("%s %s.%s on %s", o.Kind, o.Schema, o.Name, o.Table)
+}
+
+// InvalidObjects lists the INVALID indexes and NOT VALID constraints of the
+// database outside the system schemas. Indexes still being built
+// concurrently are not reported.
+func (m *Migrator) InvalidObjects(ctx context.Context) ([]InvalidObject, error) {
+	// Index builds in progress are only reported from PostgreSQL 12
+	var reported bool
+	if err := m.db.QueryRowContext(ctx, "SELECT to_regclass('pg_catalog.pg_stat_progress_create_index') IS NOT NULL").Scan(&reported); err != nil {
+		return nil, fmt.Errorf("failed to check index builds: %w", err)
+	}
+	building := ""
+	if reported {
+		building = "AND NOT EXISTS (SELECT 1 FROM pg_stat_progress_create_index p WHERE p.index_relid = i.indexrelid)"
+	}
+
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT 'index', n.nspname, t.relname, c.relname, pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT i.indisvalid %s
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_toast%%'
+		UNION ALL
+		SELECT 'constraint', n.nspname, t.relname, con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE NOT con.convalidated
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY 1 DESC, 2, 3, 4
+	`, building))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invalid objects: %w", err)
+	}
+	defer rows.Close()
+
+	objects := []InvalidObject{}
+	for rows.Next() {
+		var object InvalidObject
+		if err := rows.Scan(&object.Kind, &object.Schema, &object.Table, &object.Name, &object.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan invalid object: %w", err)
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invalid objects: %w", err)
+	}
+
+	return objects, nil
+}
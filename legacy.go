@@ -0,0 +1,194 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// LegacyFormat identifies the tracking table layout of another migration tool.
+type LegacyFormat int
+
+const (
+	// LegacyGoose reads goose's goose_db_version table: a version is applied
+	// when its latest row has is_applied set.
+	LegacyGoose LegacyFormat = iota
+
+	// LegacyGolangMigrate reads golang-migrate's schema_migrations table,
+	// which holds only the current version: every version up to it is
+	// applied. A dirty version fails the run until it is fixed with
+	// golang-migrate.
+	LegacyGolangMigrate
+
+	// LegacyVersionList reads a table with one row per applied version in a
+	// "version" column, as written by dbmate and Rails (schema_migrations).
+	LegacyVersionList
+)
+
+// defaultTable returns the table a tool uses by default.
+func (f LegacyFormat) defaultTable() string {
+	if f == LegacyGoose {
+		return "goose_db_version"
+	}
+	return "schema_migrations"
+}
+
+// LegacyTracking configures reading the tracking table of the migration tool
+// a project is moving away from.
+type LegacyTracking struct {
+	// Format is the layout of the table.
+	Format LegacyFormat
+
+	// Table is the legacy tracking table, optionally schema-qualified.
+	// Defaults to the tool's default table.
+	Table string
+}
+
+// legacyVersions reads the versions applied by the legacy tool and returns a
+// function reporting whether a migration version is among them. Versions are
+// compared numerically when both are numbers, so goose's version 1 matches
+// 001_create_users.sql. A missing table applies nothing.
+func (m *Migrator) legacyVersions(ctx context.Context) (func(version string) bool, error) {
+	none := func(string) bool { return false }
+	if m.legacy == nil {
+		return none, nil
+	}
+
+	table := m.legacy.Table
+	if table == "" {
+		table = m.legacy.Format.defaultTable()
+	}
+
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check legacy tracking table %s: %w", table, err)
+	}
+	if !exists {
+		m.log.Debugf("   Legacy tracking table %s does not exist", table)
+		return none, nil
+	}
+
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+	quoted := strings.Join(parts, ".")
+
+	if m.legacy.Format == LegacyGolangMigrate {
+		var current int64
+		var dirty bool
+		err := m.db.QueryRowContext(ctx, fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", quoted)).Scan(&current, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return none, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read legacy tracking table %s: %w", table, err)
+		}
+		if dirty {
+			return nil, fmt.Errorf("legacy tracking table %s is dirty at version %d: fix it with golang-migrate first", table, current)
+		}
+		return func(version string) bool {
+			n, err := strconv.ParseInt(version, 10, 64)
+			return err == nil && n <= current
+		}, nil
+	}
+
+	query := fmt.Sprintf("SELECT version::text FROM %s", quoted)
+	if m.legacy.Format == LegacyGoose {
+		// goose appends a row per up and down; the latest one decides
+		query = fmt.Sprintf(`
+			SELECT version_id::text FROM (
+				SELECT DISTINCT ON (version_id) version_id, is_applied
+				FROM %s ORDER BY version_id, id DESC
+			) latest WHERE is_applied AND version_id > 0
+		`, quoted)
+	}
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legacy tracking table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan legacy version: %w", err)
+		}
+		applied[normalizeVersion(version)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read legacy tracking table %s: %w", table, err)
+	}
+
+	return func(version string) bool {
+		return applied[normalizeVersion(version)]
+	}, nil
+}
+
+// normalizeVersion strips leading zeros from numeric versions.
+func normalizeVersion(version string) string {
+	if _, err := strconv.ParseUint(version, 10, 64); err != nil {
+		return version
+	}
+	if trimmed := strings.TrimLeft(version, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+// legacyApplied returns the names of the files, in name order, that the
+// legacy tool applied and the tracking table doesn't record.
+func (m *Migrator) legacyApplied(ctx context.Context, files []*validator.MigrationFile, applied map[string]bool) ([]string, error) {
+	isApplied, err := m.legacyVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if !applied[file.Name] && isApplied(file.Version()) {
+			names = append(names, file.Name)
+		}
+	}
+	return names, nil
+}
+
+// appliedSet returns the names of the migrations that are not pending: the
+// applied ones, including those applied by the legacy tool, and the skipped
+// ones. tracked reports whether the tracking table exists; when it doesn't,
+// only legacy migrations are applied.
+func (m *Migrator) appliedSet(ctx context.Context, files []*validator.MigrationFile, tracked bool) (map[string]bool, error) {
+	applied := map[string]bool{}
+	if tracked {
+		var err error
+		if applied, err = m.tracker.AppliedSet(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	legacy, err := m.legacyApplied(ctx, files, applied)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range legacy {
+		applied[name] = true
+	}
+
+	skips, err := m.tracker.GetSkipped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, skip := range skips {
+		applied[skip.Name] = true
+	}
+	return applied, nil
+}
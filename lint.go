@@ -0,0 +1,250 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// LintMode controls what happens when linting finds dangerous statements in
+// pending migrations.
+type LintMode int
+
+const (
+	// LintWarn reports findings but never fails the run. This is the default.
+	LintWarn LintMode = iota
+
+	// LintBlock fails the run, before anything is applied, when a finding
+	// has SeverityError.
+	LintBlock
+
+	// LintOff disables linting.
+	LintOff
+)
+
+// Severity ranks how dangerous a lint finding is.
+type Severity int
+
+const (
+	// SeverityInfo is informational only.
+	SeverityInfo Severity = iota
+
+	// SeverityWarning flags statements that deserve a second look.
+	SeverityWarning
+
+	// SeverityError flags statements that risk data loss or downtime.
+	SeverityError
+)
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the severity by name, e.g. in JSON reports.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParsedStatement is a single statement of a pending migration, as passed to lint rules.
+type ParsedStatement struct {
+	// Migration is the file the statement belongs to.
+	Migration string
+
+	// Index is the zero-based position of the statement in the file.
+	Index int
+
+	// Line is the 1-based line on which the statement starts.
+	Line int
+
+	// SQL is the statement as written.
+	SQL string
+
+	// Normalized is the statement with comments removed, literals emptied,
+	// whitespace collapsed and everything upper-cased, for pattern matching.
+	Normalized string
+
+	// NoTransaction is true when the migration runs without a transaction
+	// ("-- migrator:no-transaction"), each statement committing on its own.
+	NoTransaction bool
+
+	// Preceding are the statements before this one in the same migration,
+	// in order, for rules depending on what the migration did earlier.
+	Preceding []ParsedStatement
+}
+
+// Finding is a problem reported by a lint rule.
+type Finding struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Migration string   `json:"migration"`
+	Line      int      `json:"line"`
+	Statement string   `json:"statement"`
+	Message   string   `json:"message"`
+}
+
+// String formats the finding like a compiler diagnostic.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s] %s", f.Migration, f.Line, f.Severity, f.Rule, f.Message)
+}
+
+// LintRule checks a statement and reports findings.
+type LintRule struct {
+	// Name identifies the rule in findings.
+	Name string
+
+	// Description explains what the rule looks for.
+	Description string
+
+	// Check inspects a statement. Migration, Line, Statement and Rule are filled
+	// in on returned findings when left empty.
+	Check func(stmt ParsedStatement) []Finding
+}
+
+// LintError is returned when linting blocks a run.
+type LintError struct {
+	// Findings lists the blocking findings.
+	Findings []Finding
+}
+
+func (e *LintError) Error() string {
+	lines := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		lines = append(lines, "  "+f.String())
+	}
+	return fmt.Sprintf("lint found %d blocking issue(s):\n%s", len(e.Findings), strings.Join(lines, "\n"))
+}
+
+// BuiltinLintRules returns the lint rules checked by default. Renames and type
+// changes on hotTables are reported as errors instead of warnings.
+func BuiltinLintRules(hotTables ...string) []LintRule {
+	return builtinLintRules(hotTables)
+}
+
+// configureLintRules builds the rule set described by opts: the enabled
+// built-in rules followed by custom rules, with severity overrides applied.
+func configureLintRules(opts Options) []LintRule {
+	disabled := make(map[string]bool, len(opts.DisabledLintRules))
+	for _, name := range opts.DisabledLintRules {
+		disabled[name] = true
+	}
+
+	var rules []LintRule
+	for _, rule := range append(builtinLintRules(opts.HotTables), opts.LintRules...) {
+		if disabled[rule.Name] || rule.Check == nil {
+			continue
+		}
+		if severity, ok := opts.LintSeverities[rule.Name]; ok {
+			rule.Check = withSeverity(rule.Check, severity)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// withSeverity wraps a rule check so all its findings get the given severity.
+func withSeverity(check func(ParsedStatement) []Finding, severity Severity) func(ParsedStatement) []Finding {
+	return func(stmt ParsedStatement) []Finding {
+		findings := check(stmt)
+		for i := range findings {
+			findings[i].Severity = severity
+		}
+		return findings
+	}
+}
+
+// Lint checks pending migrations for dangerous statements without applying anything.
+func (m *Migrator) Lint(ctx context.Context) ([]Finding, error) {
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return lintMigrations(pending, m.lintRules), nil
+}
+
+// lintPending lints the pending migrations of a run, printing findings and
+// recording them in result. It returns a *LintError when the run must stop.
+func (m *Migrator) lintPending(migrations []*validator.MigrationFile, result *Result) error {
+	if m.lintMode == LintOff || len(migrations) == 0 {
+		return nil
+	}
+
+	findings := lintMigrations(migrations, m.lintRules)
+	result.LintFindings = append(result.LintFindings, findings...)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	m.log.Infof("🔎 Lint found %d issue(s) in pending migrations:", len(findings))
+	var blocking []Finding
+	for _, f := range findings {
+		m.log.Infof("   %s", f)
+		if f.Severity >= SeverityError {
+			blocking = append(blocking, f)
+		}
+	}
+
+	if m.lintMode == LintBlock && len(blocking) > 0 {
+		return &LintError{Findings: blocking}
+	}
+
+	return nil
+}
+
+// lintMigrations runs rules against every statement of the given migrations.
+func lintMigrations(migrations []*validator.MigrationFile, rules []LintRule) []Finding {
+	findings := []Finding{}
+
+	for _, migration := range migrations {
+		var statements []ParsedStatement
+		noTransaction := migration.NoTransaction()
+		for _, stmt := range sqlparse.Split(migration.Content) {
+			statements = append(statements, ParsedStatement{
+				Migration:     migration.Name,
+				Index:         stmt.Index,
+				Line:          stmt.Line,
+				SQL:           stmt.Text,
+				Normalized:    sqlparse.Normalize(stmt.Text),
+				NoTransaction: noTransaction,
+			})
+		}
+
+		for i, parsed := range statements {
+			parsed.Preceding = statements[:i:i]
+
+			for _, rule := range rules {
+				for _, f := range rule.Check(parsed) {
+					if f.Rule == "" {
+						f.Rule = rule.Name
+					}
+					if f.Migration == "" {
+						f.Migration = parsed.Migration
+					}
+					if f.Line == 0 {
+						f.Line = parsed.Line
+					}
+					if f.Statement == "" {
+						f.Statement = truncate(parsed.SQL, maxSnippetLength)
+					}
+					findings = append(findings, f)
+				}
+			}
+		}
+	}
+
+	return findings
+}
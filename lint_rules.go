@@ -0,0 +1,254 @@
+package migrator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+)
+
+var (
+	dropTablePattern  = regexp.MustCompile(`^DROP TABLE (IF EXISTS )?(.+?)( CASCADE| RESTRICT)?$`)
+	alterTablePattern = regexp.MustCompile(`^ALTER TABLE (IF EXISTS )?(ONLY )?(\S+) (.*)$`)
+	dropColumnPattern = regexp.MustCompile(`^DROP (COLUMN )?(IF EXISTS )?(\S+)`)
+	alterTypePattern  = regexp.MustCompile(`^ALTER (COLUMN )?(\S+) (SET DATA )?TYPE `)
+	addColumnPattern  = regexp.MustCompile(`^ADD (COLUMN )?(IF NOT EXISTS )?(\S+) `)
+	renamePattern     = regexp.MustCompile(`^RENAME (COLUMN |CONSTRAINT )?(\S+ )?TO (\S+)`)
+
+	// Enum operations are matched on the raw SQL because normalization empties literals
+	enumAlterPattern = regexp.MustCompile(`(?is)^\s*ALTER\s+TYPE\s+(\S+)\s+(ADD|DROP|RENAME)\s+VALUE\s+(?:IF\s+NOT\s+EXISTS\s+)?'((?:[^']|'')*)'(?:\s+(?:BEFORE|AFTER|TO)\s+'((?:[^']|'')*)')?`)
+	stringLiteral    = regexp.MustCompile(`'((?:[^']|'')*)'`)
+
+	// Statements that write or filter rows, where a literal likely is an enum value
+	enumUsePattern = regexp.MustCompile(`^(UPDATE|INSERT|DELETE|MERGE) |\bWHERE\b`)
+)
+
+// builtinLintRules returns the rules checked by default. Statements touching
+// hotTables are reported with a higher severity where it matters.
+func builtinLintRules(hotTables []string) []LintRule {
+	hot := make(map[string]bool, len(hotTables))
+	for _, table := range hotTables {
+		hot[normalizeTableName(table)] = true
+	}
+
+	return []LintRule{
+		{
+			Name:        "drop-table",
+			Description: "DROP TABLE permanently deletes a table and its data",
+			Check: func(stmt ParsedStatement) []Finding {
+				match := dropTablePattern.FindStringSubmatch(stmt.Normalized)
+				if match == nil {
+					return nil
+				}
+				return []Finding{{
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("DROP TABLE permanently deletes %s and all of its data", strings.ToLower(match[2])),
+				}}
+			},
+		},
+		{
+			Name:        "drop-column",
+			Description: "DROP COLUMN permanently deletes data and breaks code still reading the column",
+			Check: alterTableRule(func(table, action string) *Finding {
+				match := dropColumnPattern.FindStringSubmatch(action)
+				if match == nil || (match[1] == "" && match[3] == "CONSTRAINT") {
+					return nil
+				}
+				return &Finding{
+					Severity: SeverityError,
+					Message: fmt.Sprintf("dropping column %s.%s permanently deletes its data and breaks code still reading it",
+						strings.ToLower(table), strings.ToLower(match[3])),
+				}
+			}),
+		},
+		{
+			Name:        "alter-column-type",
+			Description: "changing a column type usually rewrites the whole table under an ACCESS EXCLUSIVE lock",
+			Check: alterTableRule(func(table, action string) *Finding {
+				match := alterTypePattern.FindStringSubmatch(action)
+				if match == nil {
+					return nil
+				}
+				severity := SeverityWarning
+				if hot[normalizeTableName(table)] {
+					severity = SeverityError
+				}
+				return &Finding{
+					Severity: severity,
+					Message: fmt.Sprintf("changing the type of %s.%s may rewrite the table and block reads and writes until done",
+						strings.ToLower(table), strings.ToLower(match[2])),
+				}
+			}),
+		},
+		{
+			Name:        "add-not-null-without-default",
+			Description: "adding a NOT NULL column without a default fails on tables that already have rows",
+			Check: alterTableRule(func(table, action string) *Finding {
+				match := addColumnPattern.FindStringSubmatch(action)
+				if match == nil || (match[1] == "" && isConstraintKeyword(match[3])) {
+					return nil
+				}
+				if !strings.Contains(action, " NOT NULL") || strings.Contains(action, " DEFAULT ") ||
+					strings.Contains(action, " GENERATED ") {
+					return nil
+				}
+				return &Finding{
+					Severity: SeverityError,
+					Message: fmt.Sprintf("adding NOT NULL column %s.%s without a DEFAULT fails if the table has rows",
+						strings.ToLower(table), strings.ToLower(match[3])),
+				}
+			}),
+		},
+		enumOrderRule(),
+		{
+			Name:        "rename",
+			Description: "renaming tables or columns breaks application code still using the old name",
+			Check: alterTableRule(func(table, action string) *Finding {
+				match := renamePattern.FindStringSubmatch(action)
+				if match == nil {
+					return nil
+				}
+				severity := SeverityWarning
+				if hot[normalizeTableName(table)] {
+					severity = SeverityError
+				}
+				what := "table " + strings.ToLower(table)
+				if match[2] != "" {
+					what = fmt.Sprintf("%s.%s", strings.ToLower(table), strings.ToLower(strings.TrimSpace(match[2])))
+				}
+				return &Finding{
+					Severity: severity,
+					Message:  fmt.Sprintf("renaming %s breaks application code still using the old name", what),
+				}
+			}),
+		},
+	}
+}
+
+// enumChange is an enum value change made earlier in the migration being
+// linted.
+type enumChange struct {
+	typeName string
+	op       string
+	value    string
+	newValue string
+	line     int
+}
+
+// parseEnumChange returns the enum value change stmt makes, if any.
+func parseEnumChange(stmt ParsedStatement) (enumChange, bool) {
+	match := enumAlterPattern.FindStringSubmatch(stmt.SQL)
+	if match == nil {
+		return enumChange{}, false
+	}
+
+	change := enumChange{
+		typeName: strings.ToLower(match[1]),
+		op:       strings.ToUpper(match[2]),
+		value:    strings.ReplaceAll(match[3], "''", "'"),
+		line:     stmt.Line,
+	}
+	if change.op == "RENAME" {
+		change.newValue = strings.ReplaceAll(match[4], "''", "'")
+	}
+	return change, true
+}
+
+// enumOrderRule flags enum value changes PostgreSQL rejects or production
+// data breaks: ALTER TYPE ... DROP VALUE, which doesn't exist, and rows
+// written or filtered by a value after it was renamed, or in the same
+// transaction that adds it. The shadow database has no rows, so uses mostly
+// fail in production. Column types aren't known offline, so uses are only
+// warned about.
+func enumOrderRule() LintRule {
+	return LintRule{
+		Name:        "enum-order",
+		Description: "enum values must be migrated in an order that works with existing rows",
+		Check: func(stmt ParsedStatement) []Finding {
+			if change, ok := parseEnumChange(stmt); ok {
+				if change.op != "DROP" {
+					return nil
+				}
+				return []Finding{{
+					Severity: SeverityError,
+					Message: fmt.Sprintf("PostgreSQL cannot drop enum values, so ALTER TYPE %s DROP VALUE fails; create a new type without '%s', "+
+						"convert the columns to it with ALTER COLUMN ... TYPE ... USING column::text::new_type after updating rows away from '%s', then drop %s",
+						change.typeName, change.value, change.value, change.typeName),
+				}}
+			}
+			if !enumUsePattern.MatchString(stmt.Normalized) {
+				return nil
+			}
+
+			var changes []enumChange
+			for _, earlier := range stmt.Preceding {
+				change, ok := parseEnumChange(earlier)
+				// Without a transaction the added value is committed before its use
+				if !ok || change.op == "DROP" || (change.op == "ADD" && stmt.NoTransaction) {
+					continue
+				}
+				changes = append(changes, change)
+			}
+			if len(changes) == 0 {
+				return nil
+			}
+
+			used := make(map[string]bool)
+			for _, literal := range stringLiteral.FindAllStringSubmatch(stmt.SQL, -1) {
+				used[strings.ReplaceAll(literal[1], "''", "'")] = true
+			}
+
+			var findings []Finding
+			for _, change := range changes {
+				if !used[change.value] {
+					continue
+				}
+				var message string
+				switch change.op {
+				case "RENAME":
+					message = fmt.Sprintf("'%s' was renamed to '%s' on line %d; use '%s' here or move this statement before the rename",
+						change.value, change.newValue, change.line, change.newValue)
+				case "ADD":
+					message = fmt.Sprintf("'%s' is added to %s on line %d and cannot be used in the same transaction; move this statement to a later migration",
+						change.value, change.typeName, change.line)
+				}
+				findings = append(findings, Finding{Severity: SeverityWarning, Message: message})
+			}
+			return findings
+		},
+	}
+}
+
+// alterTableRule adapts a check of individual ALTER TABLE actions into a rule check.
+func alterTableRule(check func(table, action string) *Finding) func(ParsedStatement) []Finding {
+	return func(stmt ParsedStatement) []Finding {
+		match := alterTablePattern.FindStringSubmatch(stmt.Normalized)
+		if match == nil {
+			return nil
+		}
+
+		var findings []Finding
+		for _, action := range sqlparse.SplitList(match[4]) {
+			if f := check(match[3], action); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+		return findings
+	}
+}
+
+// isConstraintKeyword reports whether word starts a table constraint in ADD.
+func isConstraintKeyword(word string) bool {
+	switch word {
+	case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "EXCLUDE":
+		return true
+	}
+	return false
+}
+
+// normalizeTableName upper-cases a table name and strips the public schema,
+// matching the form found in normalized statements.
+func normalizeTableName(name string) string {
+	return strings.TrimPrefix(strings.ToUpper(name), "PUBLIC.")
+}
@@ -0,0 +1,142 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migrationLockKey is the advisory lock key that serializes migration runs
+// on a database ("go_mig" in ASCII).
+const migrationLockKey int64 = 0x676f5f6d6967
+
+// LockHolder describes the session holding the migration lock.
+type LockHolder struct {
+	PID             int       `json:"pid"`
+	User            string    `json:"user"`
+	ApplicationName string    `json:"application_name"`
+	ClientAddr      string    `json:"client_addr,omitempty"`
+	ClientHostname  string    `json:"client_hostname,omitempty"`
+	BackendStart    time.Time `json:"backend_start"`
+	State           string    `json:"state"`
+}
+
+func (h *LockHolder) String() string {
+	parts := []string{fmt.Sprintf("pid %d", h.PID)}
+	if h.ApplicationName != "" {
+		parts = append(parts, "application "+h.ApplicationName)
+	}
+	if h.User != "" {
+		parts = append(parts, "user "+h.User)
+	}
+	switch {
+	case h.ClientHostname != "":
+		parts = append(parts, "host "+h.ClientHostname)
+	case h.ClientAddr != "":
+		parts = append(parts, "host "+h.ClientAddr)
+	}
+	if !h.BackendStart.IsZero() {
+		parts = append(parts, fmt.Sprintf("connected since %s (%s ago)",
+			h.BackendStart.Format(time.RFC3339), time.Since(h.BackendStart).Round(time.Second)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// LockError is returned when another run holds the migration lock for longer
+// than LockTimeout.
+type LockError struct {
+	// Holder is the session holding the lock, nil if it couldn't be determined.
+	Holder *LockHolder
+
+	// Waited is how long the run waited for the lock.
+	Waited time.Duration
+}
+
+func (e *LockError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("another migration run holds the migration lock (waited %s)", e.Waited.Round(time.Second))
+	}
+	return fmt.Sprintf("another migration run holds the migration lock (waited %s): %s", e.Waited.Round(time.Second), e.Holder)
+}
+
+// acquireLock takes the session-level migration advisory lock, waiting up to
+// LockTimeout for a concurrent run to finish. The returned function releases
+// the lock.
+func (m *Migrator) acquireLock(ctx context.Context) (func(), error) {
+	start := time.Now()
+	waiting := false
+
+	for {
+		var acquired bool
+		if err := m.db.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockKey).Scan(&acquired); err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+
+		holder, err := m.lockHolder(ctx)
+		if err != nil {
+			m.log.Debugf("   %v", err)
+		}
+
+		waited := time.Since(start)
+		if m.lockTimeout < 0 || waited >= m.lockTimeout {
+			return nil, &LockError{Holder: holder, Waited: waited}
+		}
+
+		if !waiting && holder != nil {
+			m.log.Infof("🔒 Waiting for the migration lock held by %s...", holder)
+		} else if !waiting {
+			m.log.Infof("🔒 Waiting for the migration lock held by another run...")
+		}
+		waiting = true
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for migration lock: %w", ctx.Err())
+		case <-time.After(m.waitInterval):
+		}
+	}
+
+	release := func() {
+		// Unlock even if the run was cancelled; the session may go back to the pool
+		if _, err := m.db.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			m.log.Warnf("Failed to release migration lock: %v", err)
+		}
+	}
+	return release, nil
+}
+
+// lockHolder returns the session holding the migration lock, or nil if the
+// lock is free or held by a session this role can't see.
+func (m *Migrator) lockHolder(ctx context.Context) (*LockHolder, error) {
+	// A bigint advisory key is split into classid (high half) and objid (low half)
+	var holder LockHolder
+	var backendStart sql.NullTime
+	err := m.db.QueryRowContext(ctx, `
+		SELECT a.pid, COALESCE(a.usename, ''), COALESCE(a.application_name, ''),
+			COALESCE(host(a.client_addr), ''), COALESCE(a.client_hostname, ''),
+			a.backend_start, COALESCE(a.state, '')
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+			AND l.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+			AND l.classid = $1::bigint::oid AND l.objid = $2::bigint::oid AND l.objsubid = 1
+		LIMIT 1
+	`, migrationLockKey>>32, migrationLockKey&0xffffffff).Scan(
+		&holder.PID, &holder.User, &holder.ApplicationName,
+		&holder.ClientAddr, &holder.ClientHostname, &backendStart, &holder.State)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up migration lock holder: %w", err)
+	}
+
+	holder.BackendStart = backendStart.Time
+	return &holder, nil
+}
@@ -0,0 +1,118 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// LockQueueError is returned when a migration was cancelled because
+// Options.MaxLockQueue sessions were waiting on its locks.
+type LockQueueError struct {
+	Migration string
+
+	// Waiting is the number of sessions that were waiting.
+	Waiting int
+}
+
+func (e *LockQueueError) Error() string {
+	return fmt.Sprintf("migration %s cancelled: %d sessions were waiting on its locks", e.Migration, e.Waiting)
+}
+
+// applyWatched applies a migration while watching for sessions queued behind
+// it. Watching needs a connection besides the pinned one and is skipped
+// without one, or when nobody is interested in the result.
+func (m *Migrator) applyWatched(ctx context.Context, result *Result, event ProgressEvent, migration *validator.MigrationFile, batch int) ([]tracker.RowCount, error) {
+	if m.monitorDB == nil || (m.onProgress == nil && m.maxLockQueue <= 0) {
+		return migration.Apply(ctx, batch)
+	}
+
+	var pid int
+	if err := m.db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		return nil, fmt.Errorf("failed to get backend pid: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// A panicking OnProgress would crash the process, leaking the
+		// shadow database and the transaction; fail the migration instead
+		if err := catchPanic(func() error {
+			m.watchLockQueue(ctx, cancel, pid, result, event)
+			return nil
+		}); err != nil {
+			cancel(err)
+		}
+	}()
+
+	rowCounts, err := migration.Apply(ctx, batch)
+	cancel(nil)
+	wg.Wait()
+
+	var queueErr *LockQueueError
+	if err != nil && errors.As(context.Cause(ctx), &queueErr) {
+		return nil, fmt.Errorf("%w: %w", queueErr, err)
+	}
+	var panicErr *PanicError
+	if errors.As(context.Cause(ctx), &panicErr) {
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", panicErr, err)
+		}
+		m.log.Warnf("Lock queue monitoring stopped: %v", panicErr)
+	}
+	return rowCounts, err
+}
+
+// watchLockQueue polls, until ctx is done, the number of sessions waiting
+// for locks held or requested by the backend pid, reporting changes as
+// PhaseLockQueue events. Once Options.MaxLockQueue sessions wait, it cancels
+// the migration with a *LockQueueError.
+func (m *Migrator) watchLockQueue(ctx context.Context, cancel context.CancelCauseFunc, pid int, result *Result, event ProgressEvent) {
+	ticker := time.NewTicker(m.lockQueueInterval)
+	defer ticker.Stop()
+
+	event.Phase = PhaseLockQueue
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// pg_blocking_pids also lists sessions merely queued ahead, so a DDL
+		// still waiting for its own lock counts the traffic piling up behind it
+		var waiting int
+		err := m.monitorDB.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM pg_stat_activity WHERE $1 = ANY(pg_blocking_pids(pid))", pid,
+		).Scan(&waiting)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.log.Debugf("  Could not check lock queue: %v", err)
+			}
+			continue
+		}
+
+		if waiting != event.Waiting {
+			if waiting > 0 {
+				m.log.Infof("🔒 %d sessions waiting on locks of %s", waiting, event.Migration)
+			}
+			event.Waiting = waiting
+			m.progress(result, event)
+		}
+
+		if m.maxLockQueue > 0 && waiting >= m.maxLockQueue {
+			m.log.Warnf("Cancelling %s: %d sessions are waiting on its locks", event.Migration, waiting)
+			cancel(&LockQueueError{Migration: event.Migration, Waiting: waiting})
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package migrator
+
+import "github.com/hasirciogluhq/migrator/internal/output"
+
+// LogLevel controls how much the Migrator prints while it runs.
+type LogLevel int
+
+const (
+	// LogInfo prints the progress of a run and any warnings. This is the
+	// default.
+	LogInfo LogLevel = iota
+
+	// LogDebug additionally prints per-step detail, such as each migration
+	// replayed on the shadow database and shadow database housekeeping.
+	LogDebug
+
+	// LogError prints warnings only, for CI logs and embedding in
+	// applications with their own output.
+	LogError
+
+	// LogSilent prints nothing. Warnings are still collected in
+	// Result.Warnings and failures are returned as errors.
+	LogSilent
+)
+
+// LogFormat controls how the Migrator renders what it prints.
+type LogFormat int
+
+const (
+	// LogText prints each message as a line of text. This is the default.
+	LogText LogFormat = iota
+
+	// LogJSON prints each message as a JSON object on its own line, e.g.
+	// {"time":"2024-05-01T12:00:00Z","level":"info","msg":"✓ Applied 001_init.sql"}.
+	// Warnings have level "warn".
+	LogJSON
+)
+
+// newLogger returns the logger configured by opts.
+func newLogger(opts Options) *output.Logger {
+	level := output.LevelInfo
+	switch opts.LogLevel {
+	case LogDebug:
+		level = output.LevelDebug
+	case LogError:
+		level = output.LevelError
+	case LogSilent:
+		level = output.LevelSilent
+	}
+
+	format := output.FormatText
+	if opts.LogFormat == LogJSON {
+		format = output.FormatJSON
+	}
+
+	return output.NewWithOptions(output.Options{
+		Level:  level,
+		Writer: opts.Output,
+		Format: format,
+		ASCII:  opts.ASCIIOutput,
+	})
+}
@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// touchedTablePattern matches statements that create or write to a table,
+// capturing the table name as written.
+var touchedTablePattern = regexp.MustCompile(`(?is)^(?:` +
+	`CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` +
+	`|ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` +
+	`|INSERT\s+INTO\s+` +
+	`|UPDATE\s+(?:ONLY\s+)?` +
+	`|DELETE\s+FROM\s+(?:ONLY\s+)?` +
+	`|COPY\s+` +
+	`|CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:(?:IF\s+NOT\s+EXISTS\s+)?\S+\s+)?ON\s+(?:ONLY\s+)?` +
+	`)((?:"[^"]+"|[\w$]+)(?:\.(?:"[^"]+"|[\w$]+))?)`)
+
+// touchedTables returns the tables created or written to by migrations, in
+// order of first appearance. Tables loaded by copy directives are included.
+func touchedTables(migrations []*validator.MigrationFile) []string {
+	var tables []string
+	seen := make(map[string]bool)
+	add := func(table string) {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+
+	for _, migration := range migrations {
+		for _, stmt := range sqlparse.Split(migration.Content) {
+			if match := touchedTablePattern.FindStringSubmatch(stmt.Text); match != nil {
+				add(match[1])
+			}
+		}
+		copies, _ := migration.Copies()
+		for _, c := range copies {
+			add(c.Table)
+		}
+	}
+
+	return tables
+}
+
+// runMaintenance analyzes the tables touched by the applied migrations and
+// refreshes the configured materialized views. Failures are warnings: the
+// migrations are already applied.
+func (m *Migrator) runMaintenance(ctx context.Context, migrationFiles []*validator.MigrationFile, result *Result) {
+	if len(result.Applied) == 0 || (!m.analyzeAfterApply && len(m.refreshViews) == 0) {
+		return
+	}
+
+	if m.analyzeAfterApply {
+		applied := make(map[string]bool, len(result.Applied))
+		for _, a := range result.Applied {
+			applied[a.Name] = true
+		}
+		var migrations []*validator.MigrationFile
+		for _, migration := range migrationFiles {
+			if applied[migration.Name] {
+				migrations = append(migrations, migration)
+			}
+		}
+
+		analyzed := 0
+		for _, table := range touchedTables(migrations) {
+			ok, err := m.analyzeTable(ctx, table)
+			if err != nil {
+				result.warnf("failed to analyze %s: %v", table, err)
+			} else if ok {
+				analyzed++
+			}
+		}
+		if analyzed > 0 {
+			m.log.Infof("📊 Analyzed %d tables touched by the batch", analyzed)
+		}
+	}
+
+	for _, view := range m.refreshViews {
+		if _, err := m.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW "+quoteQualified(view)); err != nil {
+			result.warnf("failed to refresh materialized view %s: %v", view, err)
+			continue
+		}
+		m.log.Infof("🔄 Refreshed materialized view %s", view)
+	}
+}
+
+// analyzeTable runs ANALYZE on a table named as in the migration SQL,
+// resolved through the configured search_path. It reports false if the table
+// doesn't exist, e.g. because a later migration dropped it.
+func (m *Migrator) analyzeTable(ctx context.Context, table string) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if m.searchPath != "" {
+		if _, err := tx.ExecContext(ctx, "SELECT set_config('search_path', $1, true)", m.searchPath); err != nil {
+			return false, fmt.Errorf("failed to set search_path: %w", err)
+		}
+	}
+
+	// The name as PostgreSQL renders it is qualified and quoted as needed
+	var resolved sql.NullString
+	if err := tx.QueryRowContext(ctx, "SELECT to_regclass($1)::text", table).Scan(&resolved); err != nil {
+		return false, err
+	}
+	if !resolved.Valid {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "ANALYZE "+resolved.String); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
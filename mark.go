@@ -0,0 +1,100 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ErrMarkAppliedDisabled is returned by MarkApplied unless
+// Options.AllowMarkApplied is set.
+var ErrMarkAppliedDisabled = errors.New("marking migrations as applied requires Options.AllowMarkApplied")
+
+// MarkApplied records a pending migration as applied without running it, e.g.
+// when its change was already made by hand during an incident. name is the
+// migration's file name, with or without extension. The checksum of the file
+// is recorded, so VerifyChecksums still catches later edits.
+//
+// The migration is not tested on the shadow database and its SQL never runs
+// here; it does run when the shadow database replays applied migrations, so
+// it should match what was done by hand. MarkApplied fails with
+// ErrMarkAppliedDisabled unless Options.AllowMarkApplied is set, and for
+// migrations that don't exist or are already applied.
+func (m *Migrator) MarkApplied(ctx context.Context, name string) error {
+	if !m.allowMarkApplied {
+		return ErrMarkAppliedDisabled
+	}
+
+	run, release, err := m.pinSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return run.markApplied(ctx, name)
+}
+
+// markApplied records a migration as applied while holding the migration lock.
+func (m *Migrator) markApplied(ctx context.Context, name string) error {
+	if err := m.checkDatabaseIdentity(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	run, err := m.loadRunState(ctx)
+	if err != nil {
+		return err
+	}
+
+	migration, err := m.pendingFile(run, name)
+	if err != nil {
+		return err
+	}
+
+	if err := migration.Load(); err != nil {
+		return err
+	}
+
+	record := tracker.Migration{
+		Name:        migration.Name,
+		Content:     migration.Content,
+		Checksum:    migration.Checksum(),
+		OmitContent: migration.Compressed(),
+	}
+	if err := m.tracker.Record(ctx, record); err != nil {
+		return err
+	}
+
+	m.log.Warnf("Marked migration %s as applied without running it", migration.Name)
+	return nil
+}
+
+// pendingFile finds the pending migration file called name, with or without
+// extension.
+func (m *Migrator) pendingFile(run *runState, name string) (*validator.MigrationFile, error) {
+	for _, file := range run.files {
+		if file.Name != name && validator.TrimExtension(file.Name) != name {
+			continue
+		}
+		if run.applied[file.Name] {
+			return nil, fmt.Errorf("migration %s is already applied", file.Name)
+		}
+		if run.skipped[file.Name] {
+			return nil, fmt.Errorf("migration %s is skipped", file.Name)
+		}
+		return file, nil
+	}
+	return nil, fmt.Errorf("migration %s not found in %s", name, m.migrationsPath)
+}
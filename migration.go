@@ -0,0 +1,31 @@
+package migrator
+
+import "github.com/hasirciogluhq/migrator/internal/validator"
+
+// Migration is a migration file from the migrations directory.
+type Migration struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Checksum is the SHA-256 of the migration content, as recorded when
+	// it is applied.
+	Checksum string `json:"checksum"`
+
+	content string
+}
+
+// Content returns the migration's SQL, decompressed and normalized as it is
+// applied.
+func (m Migration) Content() string {
+	return m.content
+}
+
+// newMigration converts a migration file into its public representation.
+func newMigration(f *validator.MigrationFile) Migration {
+	return Migration{
+		Name:     f.Name,
+		Version:  f.Version(),
+		Checksum: f.Checksum(),
+		content:  f.Content,
+	}
+}
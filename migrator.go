@@ -26,38 +26,437 @@ package migrator
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"sync"
 	"time"
 
+	"github.com/hasirciogluhq/migrator/internal/dsn"
+	"github.com/hasirciogluhq/migrator/internal/output"
 	"github.com/hasirciogluhq/migrator/internal/shadowdb"
 	"github.com/hasirciogluhq/migrator/internal/tracker"
 	"github.com/hasirciogluhq/migrator/internal/validator"
 )
 
+// DB is the database handle used by the Migrator. *sql.DB satisfies it, as do
+// *sql.Conn and instrumented wrappers (e.g. otelsql) exposing the same methods.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+var (
+	_ DB = (*sql.DB)(nil)
+	_ DB = (*sql.Conn)(nil)
+)
+
 // Migrator handles database migrations with shadow database testing.
+//
+// A Migrator is safe for concurrent use. Runs on the same instance are
+// serialized, as are Clone, DropClone and CleanupOrphans, which share its
+// shadow database connections.
 type Migrator struct {
-	db             *sql.DB
+	db             DB
 	tracker        *tracker.Tracker
 	validator      *validator.Validator
 	shadowManager  *shadowdb.Manager
+	shadowMu       *sync.Mutex // guards shadowManager, whose state spans a run
 	migrationsPath string
+	legacy         *LegacyTracking
+	waitInterval   time.Duration
+	watchInterval  time.Duration
+	lockTimeout    time.Duration
+	runTimeout     time.Duration
+	cleanupTimeout time.Duration
+	notifiers      []Notifier
+	notifyChannel  string
+	lintMode       LintMode
+	lintRules      []LintRule
+	syntaxChecker  SyntaxChecker
+	naming         *NamingConvention
+	skipShadowDB   bool
+	profileShadow  bool
+
+	requireConfirmation bool
+	confirmFunc         ConfirmFunc
+	policy              PolicyFunc
+
+	expectedDatabase        string
+	expectedDatabasePattern *regexp.Regexp
+
+	verifyChecksumsEnabled bool
+	disallowOutOfOrder     bool
+	allowMarkApplied       bool
+	allowDestructive       bool
+
+	applicationName    string
+	role               string
+	searchPath         string
+	skipPrivilegeCheck bool
+	standbyWait        time.Duration
+	maxReplicationLag  time.Duration
+	minPostgresVersion string
+
+	blockingMode        BlockingMode
+	blockingThreshold   time.Duration
+	blockingWaitTimeout time.Duration
+
+	requiredExtensionNames []string
+	backupHook             BackupFunc
+	analyzeAfterApply      bool
+	refreshViews           []string
+	verifyQueries          []string
+	onProgress             ProgressFunc
+	onError                ErrorFunc
+	migrationTimeout       time.Duration
+	maxLockQueue           int
+	lockQueueInterval      time.Duration
+	monitorDB              DB
+	pauseBetween           time.Duration
+	shutdownGracePeriod    time.Duration
+	log                    *output.Logger
+
+	// applyOnly is the migration ApplyOne applies; empty applies all pending
+	applyOnly string
 }
 
 // Options configures the Migrator behavior.
 type Options struct {
 	// MigrationsPath is the directory containing SQL migration files.
-	// If empty, defaults to "./migrations" or MIGRATIONS_PATH env var.
+	// If empty, defaults to "./migrations" or MIGRATIONS_PATH env var, read
+	// once by NewWithOptions.
 	MigrationsPath string
 
-	// DatabaseURL is the PostgreSQL connection string used for shadow database operations.
-	// If empty, falls back to DATABASE_URL env var.
+	// LegacyTracking, when set, reads the tracking table of another migration
+	// tool (goose, golang-migrate, dbmate) during a transition, treating the
+	// versions it recorded as applied. The table is never written.
+	LegacyTracking *LegacyTracking
+
+	// IgnorePatterns are filepath.Match patterns of files in the migrations
+	// directory that are not migrations (e.g. "*.draft.sql"). Patterns listed
+	// in a .migratorignore file in the directory apply as well.
+	IgnorePatterns []string
+
+	// DatabaseURL is the PostgreSQL connection string used for shadow database operations,
+	// in URL or keyword/value ("host=... dbname=...") form.
+	// If empty, falls back to DATABASE_URL env var, read once by
+	// NewWithOptions; changing it later doesn't affect the Migrator.
 	// Required for shadow database testing feature.
 	DatabaseURL string
 
+	// DatabaseURLProvider resolves the connection string each time a
+	// connection is opened from it, e.g. from a secrets manager, so
+	// credentials rotated during a run are picked up. It takes precedence
+	// over DatabaseURL.
+	DatabaseURLProvider DatabaseURLProvider
+
+	// DriverName is the database/sql driver the migrator opens its own
+	// connections with, e.g. "pgx" for programs registering the pgx stdlib
+	// driver instead of lib/pq. Defaults to lib/pq, which is always linked in.
+	DriverName string
+
+	// ConfigureDB is called with each connection pool the migrator opens
+	// itself from DatabaseURL (shadow, clone and maintenance connections),
+	// e.g. to set pool sizes and connection lifetimes. The main connection
+	// is the caller's to configure.
+	ConfigureDB func(*sql.DB)
+
+	// CredentialsProvider supplies the password each time a connection is
+	// opened from DatabaseURL (shadow, clone and maintenance connections),
+	// replacing any password in it, for short-lived tokens like AWS RDS IAM
+	// authentication. Open the main connection with OpenDB to use it there too.
+	CredentialsProvider CredentialsProvider
+
 	// SkipShadowDB disables shadow database testing.
 	// Not recommended for production use.
 	SkipShadowDB bool
+
+	// ProfileShadow times each statement of the pending migrations during
+	// shadow testing and captures EXPLAIN plans of DML statements, reported in
+	// Result.ShadowProfile.
+	ProfileShadow bool
+
+	// VerifyQueries are run against production after a run applies
+	// migrations, in addition to those declared by the applied migrations with
+	// "-- migrator:verify <query>" directives. A query fails if it errors,
+	// returns no rows, or its first value is false or NULL; failures return a
+	// *VerificationError and mark the batch suspect.
+	VerifyQueries []string
+
+	// AnalyzeAfterApply runs ANALYZE on the tables created or written to by
+	// the applied migrations, so the planner doesn't work from stale
+	// statistics until autovacuum catches up.
+	AnalyzeAfterApply bool
+
+	// RefreshMaterializedViews lists materialized views, optionally
+	// schema-qualified, to refresh after a run applies migrations.
+	RefreshMaterializedViews []string
+
+	// RunTimeout bounds a whole migration run, including shadow testing and
+	// verification. Zero means no limit beyond the caller's context.
+	RunTimeout time.Duration
+
+	// MigrationTimeout bounds applying a single migration to production.
+	// Defaults to 5 minutes; a negative value disables the limit. Chunked
+	// migrations are bounded per batch instead.
+	//
+	// Deprecated: Use Timeouts.PerMigration, which takes precedence.
+	MigrationTimeout time.Duration
+
+	// Timeouts bounds each phase of a run: shadow setup and test, each
+	// production migration, shadow cleanup and waiting for the lock.
+	Timeouts Timeouts
+
+	// MaxLockQueue cancels a migration, rolling it back, once this many
+	// sessions wait for locks it holds or is queued for, so DDL stuck behind
+	// a long transaction doesn't stall all traffic to a table. The run fails
+	// with a *LockQueueError. Zero never cancels.
+	MaxLockQueue int
+
+	// LockQueueInterval is how often pg_stat_activity is polled, from a
+	// separate connection, for sessions waiting on the migration being
+	// applied. Changes are reported to OnProgress as PhaseLockQueue.
+	// Defaults to 1 second.
+	LockQueueInterval time.Duration
+
+	// ShutdownGracePeriod is how long a migration being applied when the
+	// run's context is cancelled (e.g. on SIGTERM, see SignalContext) may
+	// take to commit before it is cancelled and rolled back. No further
+	// migrations start either way. Zero cancels the migration immediately.
+	ShutdownGracePeriod time.Duration
+
+	// WaitInterval is how often WaitUntilCurrent polls the database.
+	// Defaults to 2 seconds.
+	WaitInterval time.Duration
+
+	// WatchInterval is how often Watch checks the migrations directory for
+	// changes. Defaults to 1 second.
+	WatchInterval time.Duration
+
+	// LockTimeout is how long a run waits, polling every WaitInterval, for a
+	// concurrent run holding the migration lock to finish. Defaults to 5
+	// minutes; a negative value fails immediately. On timeout the run fails
+	// with a *LockError naming the session holding the lock.
+	//
+	// Deprecated: Use Timeouts.LockWait, which takes precedence.
+	LockTimeout time.Duration
+
+	// LogLevel controls how much is printed while migrating: LogInfo (the
+	// default), LogDebug, LogError (warnings only) or LogSilent.
+	LogLevel LogLevel
+
+	// Output receives everything the Migrator prints. Defaults to os.Stdout.
+	Output io.Writer
+
+	// LogFormat selects text (the default) or JSON lines output, for services
+	// that ship their output to a structured log pipeline.
+	LogFormat LogFormat
+
+	// ASCIIOutput prints plain ASCII: symbols such as ✓ and ⚠️ become tags
+	// like "[ok]" and "WARNING:", for Windows consoles and log processors
+	// that garble UTF-8.
+	ASCIIOutput bool
+
+	// OnProgress is called as the run moves through its phases and before
+	// each migration is tested and applied, so CLIs and deploy UIs can show
+	// progress during long runs.
+	OnProgress ProgressFunc
+
+	// OnError is called with the details of a failed run, its phase,
+	// migration, SQL snippet and PostgreSQL error code, before Migrate
+	// returns the error, e.g. to report it to an error tracker.
+	OnError ErrorFunc
+
+	// Notifiers receive events as the migration run progresses
+	// (run started, migration applied, shadow test failed, run completed).
+	// See WebhookNotifier for a ready-made implementation.
+	Notifiers []Notifier
+
+	// SlackWebhookURL, when set, posts a summary of each migration run to Slack.
+	// Shorthand for adding NewSlackNotifier(url) to Notifiers.
+	SlackWebhookURL string
+
+	// NotifyChannel, when set, broadcasts a JSON payload on this PostgreSQL channel
+	// (via pg_notify) after a run applies migrations, so long-lived processes
+	// LISTENing on it can refresh prepared statements and caches.
+	NotifyChannel string
+
+	// AppVersion identifies the application build running the migrations
+	// (e.g. a git SHA or release tag). It is recorded with each applied migration
+	// together with the database role and hostname, so shared databases show
+	// which deploy applied what.
+	AppVersion string
+
+	// StoreContent stores the full SQL of each applied migration in the tracking
+	// table. Large values are compressed by PostgreSQL automatically (TOAST).
+	// Shadow testing falls back to the stored copy when a historical file is gone.
+	// The content of .sql.gz migrations is never stored, only their checksum.
+	StoreContent bool
+
+	// LintMode controls the lint phase that scans pending migrations for
+	// dangerous statements (DROP TABLE/COLUMN, table-rewriting type changes,
+	// NOT NULL columns without default, renames) before anything is applied.
+	// Defaults to LintWarn, which reports findings without failing the run.
+	LintMode LintMode
+
+	// HotTables lists heavily used tables. Renames and type changes on them
+	// are reported as errors instead of warnings.
+	HotTables []string
+
+	// DisabledLintRules lists built-in lint rules to skip, by name (e.g. "rename").
+	DisabledLintRules []string
+
+	// LintRules registers additional lint rules, checked after the built-in ones.
+	LintRules []LintRule
+
+	// LintSeverities overrides the severity of findings by rule name. Since
+	// LintBlock only fails on SeverityError, this decides which rules block:
+	// raise a rule to SeverityError to enforce it, lower it to let it pass.
+	LintSeverities map[string]Severity
+
+	// SyntaxChecker, when set, parses pending migrations offline before shadow
+	// testing so syntax errors fail fast, even when shadow testing is skipped.
+	// Use pgquery.Check for the PostgreSQL parser.
+	SyntaxChecker SyntaxChecker
+
+	// NamingConvention, when set, requires pending migration file names to
+	// follow it (see DefaultNamingConvention). All violations are reported at once.
+	NamingConvention *NamingConvention
+
+	// RequireConfirmation marks a protected environment: pending migrations
+	// are only applied after Confirm approves them, and the run fails if
+	// Confirm is nil. Leave it unset in development to apply automatically.
+	RequireConfirmation bool
+
+	// Confirm is asked before applying pending migrations when
+	// RequireConfirmation is set (see TerminalConfirm).
+	Confirm ConfirmFunc
+
+	// Policy, when set, can veto a run based on its plan before anything is
+	// tested or applied (see PolicyFunc).
+	Policy PolicyFunc
+
+	// ExpectedDatabase, when set, must equal current_database() or the run
+	// fails with ErrUnexpectedDatabase before anything is touched.
+	ExpectedDatabase string
+
+	// ExpectedDatabasePattern, when set, must match current_database(),
+	// e.g. regexp.MustCompile(`^app_prod(_\d+)?$`).
+	ExpectedDatabasePattern *regexp.Regexp
+
+	// VerifyChecksums fails the run with a *ChecksumError when applied
+	// migration files were modified after being applied.
+	VerifyChecksums bool
+
+	// DisallowOutOfOrder fails the run with an *OutOfOrderError when pending
+	// migrations sort before the latest applied one. By default they are
+	// applied anyway.
+	DisallowOutOfOrder bool
+
+	// AllowMarkApplied enables MarkApplied, which records migrations as
+	// applied without running them. It is unsafe: a migration marked by
+	// mistake silently never runs. Enable it only for the one-off tool or
+	// command that needs it.
+	AllowMarkApplied bool
+
+	// AllowDestructive enables Reset, which drops the database's schemas and
+	// applies all migrations again. Reset also requires ExpectedDatabase or
+	// ExpectedDatabasePattern; set them to development databases only.
+	AllowDestructive bool
+
+	// SearchPath is set (as with SET LOCAL search_path) in every migration
+	// transaction, on production and the shadow database, so unqualified
+	// objects are created in a non-public schema, e.g. "app, public". The
+	// tracking tables stay in the connection's default schema.
+	SearchPath string
+
+	// StatementSavepoints runs each statement of a migration under its own
+	// savepoint, so a failure returns a *StatementError naming the statement
+	// and its line. The whole migration is still rolled back.
+	StatementSavepoints bool
+
+	// IndexRetries is how many times a CREATE INDEX CONCURRENTLY statement
+	// of a no-transaction migration is retried after failing for a transient
+	// reason, such as a deadlock or lock timeout. Defaults to 2; a negative
+	// value disables retries.
+	IndexRetries int
+
+	// IndexRetryDelay is the time to wait before retrying an index build.
+	// Defaults to 10 seconds.
+	IndexRetryDelay time.Duration
+
+	// Isolation is the transaction isolation level migrations run with.
+	// Defaults to sql.LevelReadCommitted. A migration can override it with
+	// a "-- migrator:isolation serializable" directive.
+	Isolation sql.IsolationLevel
+
+	// Role is assumed with SET LOCAL ROLE in every migration transaction, so
+	// created objects are owned by it rather than by the (often superuser)
+	// connecting user, which must be a member of the role.
+	Role string
+
+	// ApplicationName is set as application_name on the migration session and
+	// shadow database connections, so DBAs can identify migration activity in
+	// pg_stat_activity and logs. Defaults to "migrator" (with "/AppVersion"
+	// appended when AppVersion is set).
+	ApplicationName string
+
+	// SkipPrivilegeCheck disables the preflight that verifies the migration
+	// role has the privileges pending migrations need (see CheckPrivileges).
+	SkipPrivilegeCheck bool
+
+	// StandbyWait is how long to wait for a hot standby to be promoted before
+	// failing with ErrStandby. By default a standby is refused immediately.
+	StandbyWait time.Duration
+
+	// MaxReplicationLag, when set, warns before applying if a streaming
+	// replica's replay lag exceeds it.
+	MaxReplicationLag time.Duration
+
+	// PauseBetweenMigrations is waited between applying consecutive
+	// migrations, giving checkpoints and replicas room to catch up after
+	// heavy DDL. An interrupted run stops during the pause.
+	PauseBetweenMigrations time.Duration
+
+	// BlockingQueries controls what happens when transactions open longer
+	// than BlockingThreshold hold locks on tables the pending migrations
+	// alter: BlockingWarn (the default), BlockingWait, BlockingTerminate or
+	// BlockingOff.
+	BlockingQueries BlockingMode
+
+	// BlockingThreshold is how long a transaction must have been open to
+	// count as blocking. Defaults to 1 minute.
+	BlockingThreshold time.Duration
+
+	// BlockingWaitTimeout bounds how long BlockingWait waits for blocking
+	// transactions to finish, polling every WaitInterval. Defaults to 5 minutes.
+	BlockingWaitTimeout time.Duration
+
+	// MinPostgresVersion, e.g. "14" or "13.4", fails the run early on older
+	// servers. Individual migrations can declare their own minimum with a
+	// "-- migrator:requires-pg>=14" comment.
+	MinPostgresVersion string
+
+	// RequiredExtensions lists extensions (e.g. "pgcrypto", "postgis") that
+	// must be installed on the target database. Migrations can declare their
+	// own with a "-- migrator:extension <name>" comment. Extensions not
+	// created by a migration are pre-installed in the shadow database.
+	RequiredExtensions []string
+
+	// BackupHook runs after shadow testing passes and before pending
+	// migrations are applied to production, e.g. PgDump. If it fails, nothing
+	// is applied.
+	BackupHook BackupFunc
+
+	// Strict turns on every safety check at once: VerifyChecksums,
+	// DisallowOutOfOrder, LintBlock, and DefaultNamingConvention unless
+	// NamingConvention is set. The default is permissive for compatibility.
+	Strict bool
 }
 
 // New creates a new Migrator instance with default options.
@@ -65,12 +464,21 @@ type Options struct {
 // The database connection should be properly configured and tested before
 // passing it to the migrator. The migrator will use this connection for
 // all migration operations.
-func New(db *sql.DB) *Migrator {
+func New(db DB) *Migrator {
 	return NewWithOptions(db, Options{})
 }
 
 // NewWithOptions creates a new Migrator instance with custom options.
-func NewWithOptions(db *sql.DB, opts Options) *Migrator {
+func NewWithOptions(db DB, opts Options) *Migrator {
+	if opts.Strict {
+		opts.VerifyChecksums = true
+		opts.DisallowOutOfOrder = true
+		opts.LintMode = LintBlock
+		if opts.NamingConvention == nil {
+			opts.NamingConvention = DefaultNamingConvention()
+		}
+	}
+
 	migrationsPath := opts.MigrationsPath
 	if migrationsPath == "" {
 		migrationsPath = os.Getenv("MIGRATIONS_PATH")
@@ -85,13 +493,110 @@ func NewWithOptions(db *sql.DB, opts Options) *Migrator {
 		databaseURL = os.Getenv("DATABASE_URL")
 	}
 
-	t := tracker.New(db)
-	v := validator.New(t, migrationsPath)
+	waitInterval := opts.WaitInterval
+	if waitInterval <= 0 {
+		waitInterval = 2 * time.Second
+	}
+
+	watchInterval := opts.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = time.Second
+	}
+
+	lockTimeout := opts.Timeouts.LockWait
+	if lockTimeout == 0 {
+		lockTimeout = opts.LockTimeout
+	}
+	if lockTimeout == 0 {
+		lockTimeout = 5 * time.Minute
+	}
+
+	migrationTimeout := opts.Timeouts.PerMigration
+	if migrationTimeout == 0 {
+		migrationTimeout = opts.MigrationTimeout
+	}
+	if migrationTimeout == 0 {
+		migrationTimeout = 5 * time.Minute
+	}
+
+	cleanupTimeout := opts.Timeouts.Cleanup
+	if cleanupTimeout <= 0 {
+		cleanupTimeout = shadowdb.DefaultCleanupTimeout
+	}
+
+	lockQueueInterval := opts.LockQueueInterval
+	if lockQueueInterval <= 0 {
+		lockQueueInterval = time.Second
+	}
+
+	blockingThreshold := opts.BlockingThreshold
+	if blockingThreshold <= 0 {
+		blockingThreshold = time.Minute
+	}
+
+	blockingWaitTimeout := opts.BlockingWaitTimeout
+	if blockingWaitTimeout <= 0 {
+		blockingWaitTimeout = 5 * time.Minute
+	}
+
+	indexRetries := opts.IndexRetries
+	if indexRetries == 0 {
+		indexRetries = 2
+	}
+
+	indexRetryDelay := opts.IndexRetryDelay
+	if indexRetryDelay <= 0 {
+		indexRetryDelay = 10 * time.Second
+	}
+
+	notifiers := opts.Notifiers
+	if opts.SlackWebhookURL != "" {
+		notifiers = append(notifiers[:len(notifiers):len(notifiers)], NewSlackNotifier(opts.SlackWebhookURL))
+	}
+
+	log := newLogger(opts)
+
+	t := tracker.NewWithOptions(db, tracker.Options{
+		AppVersion:   opts.AppVersion,
+		StoreContent: opts.StoreContent,
+		SearchPath:   opts.SearchPath,
+		Role:         opts.Role,
+		Isolation:    opts.Isolation,
+
+		StatementSavepoints: opts.StatementSavepoints,
+		Index:               tracker.IndexOptions{Retries: indexRetries, RetryDelay: indexRetryDelay},
+		Logger:              log,
+	})
+	v := validator.NewWithOptions(t, migrationsPath, validator.Options{IgnorePatterns: opts.IgnorePatterns, Logger: log})
+
+	applicationName := opts.ApplicationName
+	if applicationName == "" {
+		applicationName = "migrator"
+		if opts.AppVersion != "" {
+			applicationName += "/" + opts.AppVersion
+		}
+	}
 
 	// Initialize shadow manager with database URL if provided
 	var shadowMgr *shadowdb.Manager
-	if databaseURL != "" {
-		shadowMgr, _ = shadowdb.NewWithURL(db, databaseURL)
+	if databaseURL != "" || opts.DatabaseURLProvider != nil {
+		shadowOpts := shadowdb.Options{
+			DatabaseURL:     databaseURL,
+			MigrationsPath:  migrationsPath,
+			ApplicationName: applicationName,
+			Credentials:     dsn.Credentials(opts.CredentialsProvider),
+			ConfigureDB:     opts.ConfigureDB,
+			DriverName:      opts.DriverName,
+			SetupTimeout:    opts.Timeouts.ShadowSetup,
+			TestTimeout:     opts.Timeouts.ShadowTest,
+			CleanupTimeout:  cleanupTimeout,
+			Logger:          log,
+		}
+		if opts.DatabaseURLProvider != nil {
+			shadowOpts.Resolver = opts.DatabaseURLProvider.DatabaseURL
+		}
+		// An unparsable URL leaves shadow testing disabled, as without one
+		shadowMgr, _ = shadowdb.NewWithOptions(db, shadowOpts)
 	}
 
 	return &Migrator{
@@ -99,7 +604,60 @@ func NewWithOptions(db *sql.DB, opts Options) *Migrator {
 		tracker:        t,
 		validator:      v,
 		shadowManager:  shadowMgr,
+		shadowMu:       &sync.Mutex{},
 		migrationsPath: migrationsPath,
+		legacy:         opts.LegacyTracking,
+		waitInterval:   waitInterval,
+		watchInterval:  watchInterval,
+		lockTimeout:    lockTimeout,
+		runTimeout:     opts.RunTimeout,
+		cleanupTimeout: cleanupTimeout,
+		notifiers:      notifiers,
+		notifyChannel:  opts.NotifyChannel,
+		lintMode:       opts.LintMode,
+		lintRules:      configureLintRules(opts),
+		syntaxChecker:  opts.SyntaxChecker,
+		naming:         opts.NamingConvention,
+		skipShadowDB:   opts.SkipShadowDB,
+		profileShadow:  opts.ProfileShadow,
+
+		requireConfirmation: opts.RequireConfirmation,
+		confirmFunc:         opts.Confirm,
+		policy:              opts.Policy,
+
+		expectedDatabase:        opts.ExpectedDatabase,
+		expectedDatabasePattern: opts.ExpectedDatabasePattern,
+
+		verifyChecksumsEnabled: opts.VerifyChecksums,
+		disallowOutOfOrder:     opts.DisallowOutOfOrder,
+		allowMarkApplied:       opts.AllowMarkApplied,
+		allowDestructive:       opts.AllowDestructive,
+
+		applicationName:    applicationName,
+		role:               opts.Role,
+		searchPath:         opts.SearchPath,
+		skipPrivilegeCheck: opts.SkipPrivilegeCheck,
+		standbyWait:        opts.StandbyWait,
+		maxReplicationLag:  opts.MaxReplicationLag,
+		minPostgresVersion: opts.MinPostgresVersion,
+
+		blockingMode:        opts.BlockingQueries,
+		blockingThreshold:   blockingThreshold,
+		blockingWaitTimeout: blockingWaitTimeout,
+
+		requiredExtensionNames: opts.RequiredExtensions,
+		backupHook:             opts.BackupHook,
+		analyzeAfterApply:      opts.AnalyzeAfterApply,
+		refreshViews:           opts.RefreshMaterializedViews,
+		verifyQueries:          opts.VerifyQueries,
+		onProgress:             opts.OnProgress,
+		onError:                opts.OnError,
+		migrationTimeout:       migrationTimeout,
+		maxLockQueue:           opts.MaxLockQueue,
+		lockQueueInterval:      lockQueueInterval,
+		pauseBetween:           opts.PauseBetweenMigrations,
+		shutdownGracePeriod:    opts.ShutdownGracePeriod,
+		log:                    log,
 	}
 }
 
@@ -116,108 +674,399 @@ func NewWithOptions(db *sql.DB, opts Options) *Migrator {
 // Returns an error if any step fails. All migrations are applied in transactions
 // with automatic rollback on failure.
 func (m *Migrator) Migrate(ctx context.Context) error {
-	// Step 1: Ensure migrations table exists
-	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
-		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	_, err := m.MigrateWithResult(ctx)
+	return err
+}
+
+// MigrateWithResult runs the same process as Migrate and returns a report of
+// what the run did. The result is returned even when the run fails, describing
+// the work done up to the failure.
+func (m *Migrator) MigrateWithResult(ctx context.Context) (*Result, error) {
+	return m.run(ctx, nil)
+}
+
+// run runs a migration, applying exactly the migrations of plan when set.
+func (m *Migrator) run(ctx context.Context, plan *Plan) (*Result, error) {
+	return m.execute(ctx, func(ctx context.Context, result *Result) error {
+		return m.migratePinned(ctx, result, plan)
+	})
+}
+
+// execute runs the steps of a run, bounded by RunTimeout, with the
+// notifications, error reporting and panic recovery every run gets.
+func (m *Migrator) execute(ctx context.Context, steps func(ctx context.Context, result *Result) error) (*Result, error) {
+	result := &Result{
+		StartedAt:    time.Now(),
+		Applied:      []AppliedMigration{},
+		LintFindings: []Finding{},
+		Warnings:     []string{},
+		log:          m.log,
 	}
+	m.notify(ctx, Event{Type: EventRunStarted, Time: result.StartedAt})
 
-	// Step 2: Validate existing migrations
-	if err := m.validator.ValidateExistingMigrations(ctx); err != nil {
-		return fmt.Errorf("migration validation failed: %w", err)
+	runCtx := ctx
+	if m.runTimeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, m.runTimeout)
+		defer cancel()
 	}
 
-	// Step 3: Get all migration files
-	migrationFiles, err := m.validator.GetMigrationFiles(ctx)
+	// Deferred cleanup in the run, dropping the shadow database and
+	// releasing the lock, happens before a panic is turned into an error
+	err := interrupted(ctx, catchPanic(func() error {
+		return steps(runCtx, result)
+	}))
+	m.reportError(ctx, result, err)
+	if errors.Is(err, ErrInterrupted) {
+		result.warnf("Run interrupted after applying %d migrations; the remaining migrations stay pending", len(result.Applied))
+	}
+	result.FinishedAt = time.Now()
+	m.progress(result, ProgressEvent{Phase: PhaseDone, Total: len(result.Applied)})
+
+	completed := Event{
+		Type:       EventRunCompleted,
+		Applied:    result.Applied,
+		DurationMS: result.Duration().Milliseconds(),
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get migration files: %w", err)
+		completed.Error = err.Error()
+		failureDetails(&completed, err)
 	}
+	m.notify(ctx, completed)
 
-	// Step 4: Find new migrations
-	newMigrations, err := validator.FindNewMigrations(ctx, migrationFiles)
+	return result, err
+}
+
+// migratePinned runs the migration steps on a single dedicated session,
+// after any other run of the Migrator finished.
+func (m *Migrator) migratePinned(ctx context.Context, result *Result, plan *Plan) error {
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	run, release, err := m.pinSession(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to find new migrations: %w", err)
+		return err
 	}
+	defer release()
 
-	// Step 5: Test new migrations on shadow database
-	if len(newMigrations) > 0 {
-		// Initialize shadow manager lazily if not already initialized
-		if m.shadowManager == nil {
-			// Try to get DATABASE_URL from environment as fallback
-			databaseURL := os.Getenv("DATABASE_URL")
-			if databaseURL != "" {
-				shadowMgr, err := shadowdb.NewWithURL(m.db, databaseURL)
-				if err != nil {
-					return fmt.Errorf("failed to initialize shadow database manager: %w", err)
-				}
-				m.shadowManager = shadowMgr
-			} else {
-				fmt.Println("⚠️  Warning: DATABASE_URL not provided, skipping shadow database test")
-				fmt.Println("   To enable shadow database testing, provide DatabaseURL in Options or set DATABASE_URL env var")
-			}
-		}
+	return run.migrate(ctx, result, plan)
+}
 
-		if m.shadowManager != nil {
-			if err := m.shadowManager.TestNewMigrations(ctx, m.tracker, newMigrations); err != nil {
-				return fmt.Errorf("shadow database test failed: %w", err)
-			}
+// migrate runs the migration steps, recording progress in result. With a
+// plan, the run fails unless the pending migrations still match it.
+func (m *Migrator) migrate(ctx context.Context, result *Result, plan *Plan) error {
+	m.progress(result, ProgressEvent{Phase: PhasePreflight})
+
+	// Make sure this is the database we were meant to migrate
+	if err := m.checkDatabaseIdentity(ctx); err != nil {
+		return err
+	}
+
+	// A standby fails deep in the run with confusing read-only errors
+	if err := m.checkStandby(ctx); err != nil {
+		return err
+	}
+
+	// Concurrent runs, e.g. from several replicas starting at once, take turns
+	unlock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Step 1: Ensure migrations table exists
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	// Step 2: List migration files and applied migrations once for all
+	// phases; file contents are only read when needed
+	run, err := m.loadRunState(ctx)
+	if err != nil {
+		return err
+	}
+	if m.applyOnly != "" {
+		if err := m.selectApplyOnly(run); err != nil {
+			return err
 		}
-	} else {
-		fmt.Println("✓ No new migrations found, skipping shadow database test")
+	}
+	migrationFiles, newMigrations := run.files, run.pending
+
+	// Steps 3 and 4: Validate existing and new migrations, reporting every
+	// problem found rather than one per run, and check the run may proceed
+	shadow := !m.skipShadowDB && m.shadowManager != nil
+	if err := m.preflight(ctx, run, plan, shadow, result); err != nil {
+		return err
+	}
+
+	// Step 5: Test new migrations on shadow database
+	if err := m.testShadow(ctx, run, result); err != nil {
+		return err
+	}
+
+	if err := m.preApply(ctx, newMigrations, result); err != nil {
+		return err
 	}
 
 	// Step 6: Apply all pending migrations to production
-	if err := m.applyPendingMigrations(ctx, migrationFiles); err != nil {
+	if err := m.applyPendingMigrations(ctx, newMigrations, len(newMigrations), result); err != nil {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
 
-	// Step 7: Final cleanup - ensure shadow database is dropped
-	if m.shadowManager != nil {
-		if err := m.shadowManager.EnsureCleanup(ctx); err != nil {
-			fmt.Printf("⚠️  Warning: Final shadow database cleanup failed: %v\n", err)
-		}
+	// Fresh statistics keep query plans sane after large changes
+	m.runMaintenance(ctx, migrationFiles, result)
+
+	// Tell listening application processes that the schema changed
+	m.broadcastSchemaChange(ctx, result)
+
+	// Shadow passing doesn't guarantee production correctness with real data
+	verifyErr := m.verifyApplied(ctx, migrationFiles, result)
+	if verifyErr != nil {
+		m.notify(ctx, Event{Type: EventVerificationFailed, Error: verifyErr.Error()})
 	}
 
-	return nil
+	return verifyErr
+}
+
+// cleanupShadow ensures the shadow database is dropped, even when shadow
+// testing failed or was interrupted.
+func (m *Migrator) cleanupShadow(ctx context.Context, result *Result) {
+	if m.shadowManager == nil {
+		return
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.cleanupTimeout)
+	defer cancel()
+	if err := m.shadowManager.EnsureCleanup(cleanupCtx); err != nil {
+		result.warnf("Final shadow database cleanup failed: %v", err)
+	}
 }
 
-// applyPendingMigrations applies all pending migrations to production database.
-func (m *Migrator) applyPendingMigrations(ctx context.Context, migrations []*validator.MigrationFile) error {
-	fmt.Println("🚀 Applying migrations to production database...")
+// applyPendingMigrations applies all pending migrations to production database,
+// recording each applied migration in result. pending is the number of
+// migrations expected to be applied, for progress reporting.
+func (m *Migrator) applyPendingMigrations(ctx context.Context, migrations []*validator.MigrationFile, pending int, result *Result) error {
+	m.log.Infof("🚀 Applying migrations to production database...")
+
+	batch, err := m.tracker.NextBatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Re-read the applied set once: a concurrent run may have applied some
+	// migrations since they were found pending
+	applied, err := m.appliedSet(ctx, migrations, true)
+	if err != nil {
+		return err
+	}
+
+	// Record the batch with its WAL positions, even if it only partly applied
+	record := tracker.Batch{Batch: batch}
+	defer func() {
+		if len(result.Applied) == 0 {
+			return
+		}
+		record.FinishedAt = time.Now()
+		if lsn, err := m.tracker.CurrentLSN(context.WithoutCancel(ctx)); err != nil {
+			result.warnf("%v", err)
+		} else {
+			record.LSNAfter = lsn
+		}
+		result.LSNAfter = record.LSNAfter
+		if err := m.tracker.RecordBatch(context.WithoutCancel(ctx), record); err != nil {
+			result.warnf("%v", err)
+		}
+	}()
 
-	appliedCount := 0
 	for _, migration := range migrations {
-		isApplied, err := migration.IsApplied(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to check migration %s: %w", migration.Name, err)
+		// Stop between migrations rather than starting one that can't finish
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		if isApplied {
+		if applied[migration.Name] {
 			continue
 		}
 
+		if m.pauseBetween > 0 && len(result.Applied) > 0 {
+			m.log.Infof("⏳ Pausing %s before %s", m.pauseBetween, migration.Name)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(m.pauseBetween):
+			}
+		}
+
+		if record.StartedAt.IsZero() {
+			record.StartedAt = time.Now()
+			if lsn, err := m.tracker.CurrentLSN(ctx); err != nil {
+				result.warnf("%v", err)
+			} else {
+				record.LSNBefore = lsn
+				result.LSNBefore = lsn
+				m.log.Infof("📍 WAL position before batch %d: %s", batch, lsn)
+			}
+		}
+
+		event := ProgressEvent{Phase: PhaseApply, Migration: migration.Name, Index: len(result.Applied) + 1, Total: pending}
+		m.progress(result, event)
+
 		// Apply each migration in its own context with timeout
-		if err := m.applyMigrationWithTimeout(ctx, migration); err != nil {
+		attempt := tracker.Attempt{Name: migration.Name, Target: tracker.TargetProduction, StartedAt: time.Now()}
+		attempt.RowCounts, attempt.Err = m.applyMigrationWithTimeout(ctx, result, event, migration, batch)
+		attempt.FinishedAt = time.Now()
+
+		// Record the attempt even if the run was cancelled, so failures leave a trace
+		if err := m.tracker.LogAttempt(context.WithoutCancel(ctx), attempt); err != nil {
+			result.warnf("%v", err)
+		}
+
+		if attempt.Err != nil {
+			err := &migrationError{name: migration.Name, content: migration.Content, err: statementFailure(migration.Name, attempt.Err)}
 			return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
 		}
-		appliedCount++
+		duration := attempt.FinishedAt.Sub(attempt.StartedAt).Milliseconds()
+		rowCounts := newRowCounts(attempt.RowCounts)
+		result.Applied = append(result.Applied, AppliedMigration{Name: migration.Name, DurationMS: duration, RowCounts: rowCounts})
+		result.Batch = batch
+		m.log.Infof("✓ Applied %s", migration.Name)
+		m.reportRowCounts(result, rowCounts)
+
+		m.notify(ctx, Event{
+			Type:       EventMigrationApplied,
+			Migration:  migration.Name,
+			DurationMS: duration,
+		})
 	}
 
-	if appliedCount > 0 {
-		fmt.Printf("✓ Applied %d migrations successfully\n", appliedCount)
+	if len(result.Applied) > 0 {
+		m.log.Infof("✓ Applied %d migrations successfully", len(result.Applied))
 	} else {
-		fmt.Println("✓ All migrations are already applied")
+		m.log.Infof("✓ All migrations are already applied")
 	}
 
 	return nil
 }
 
+// shadowFailure attaches the failing migration's content to a shadow test error.
+func shadowFailure(err error, migrations []*validator.MigrationFile) error {
+	var shadowErr *shadowdb.MigrationError
+	if !errors.As(err, &shadowErr) {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.Name == shadowErr.Name {
+			return &migrationError{name: migration.Name, content: migration.Content, err: statementFailure(migration.Name, err)}
+		}
+	}
+
+	return err
+}
+
+// StatementError reports the statement that failed when StatementSavepoints
+// is set. It wraps the full error.
+type StatementError struct {
+	Migration string
+
+	// Index is the zero-based position of the statement in the migration.
+	Index int
+
+	// Line is the 1-based line on which the statement starts.
+	Line int
+
+	// Statement is the failing statement.
+	Statement string
+
+	Err error
+}
+
+func (e *StatementError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// statementFailure wraps err in a *StatementError if a single statement of
+// the migration failed.
+func statementFailure(name string, err error) error {
+	var stmtErr *tracker.StatementError
+	if !errors.As(err, &stmtErr) {
+		return err
+	}
+
+	return &StatementError{
+		Migration: name,
+		Index:     stmtErr.Index,
+		Line:      stmtErr.Line,
+		Statement: stmtErr.Statement,
+		Err:       err,
+	}
+}
+
 // applyMigrationWithTimeout applies a single migration with timeout protection.
-func (m *Migrator) applyMigrationWithTimeout(ctx context.Context, migration *validator.MigrationFile) error {
+func (m *Migrator) applyMigrationWithTimeout(ctx context.Context, result *Result, event ProgressEvent, migration *validator.MigrationFile, batch int) ([]tracker.RowCount, error) {
+	// Chunked migrations may run far longer; their batches are bounded instead
+	if _, chunked, _ := migration.Chunk(); chunked {
+		return m.applyWatched(ctx, result, event, migration, batch)
+	}
+
+	// An interrupted run may still commit the migration in flight
+	ctx, release := m.withGracePeriod(ctx)
+	defer release()
+
+	if m.migrationTimeout < 0 {
+		return m.applyWatched(ctx, result, event, migration, batch)
+	}
+
 	// Create a new context for this migration with timeout
-	migrationCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	migrationCtx, cancel := context.WithTimeout(ctx, m.migrationTimeout)
 	defer cancel()
 
-	return migration.Apply(migrationCtx)
+	return m.applyWatched(migrationCtx, result, event, migration, batch)
+}
+
+// GetMigrationHistory returns the full records of all applied migrations in apply order.
+func (m *Migrator) GetMigrationHistory(ctx context.Context) ([]MigrationRecord, error) {
+	// Ensure migrations table exists first
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	records, err := m.tracker.GetAppliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]MigrationRecord, 0, len(records))
+	for _, record := range records {
+		history = append(history, newMigrationRecord(record))
+	}
+
+	return history, nil
+}
+
+// GetBatchHistory returns the batches of applied migrations in order, with the
+// WAL positions captured around each one.
+func (m *Migrator) GetBatchHistory(ctx context.Context) ([]BatchRecord, error) {
+	// Ensure migrations table exists first
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	batches, err := m.tracker.GetBatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]BatchRecord, 0, len(batches))
+	for _, batch := range batches {
+		history = append(history, newBatchRecord(batch))
+	}
+
+	return history, nil
 }
 
 // GetAppliedMigrations returns a list of all applied migration names.
@@ -230,17 +1079,44 @@ func (m *Migrator) GetAppliedMigrations(ctx context.Context) ([]string, error) {
 	return m.tracker.GetAppliedMigrations(ctx)
 }
 
-// GetPendingMigrations returns a list of migrations that haven't been applied yet.
-func (m *Migrator) GetPendingMigrations(ctx context.Context) ([]*validator.MigrationFile, error) {
+// GetPendingMigrations returns the migrations that haven't been applied yet,
+// in apply order.
+func (m *Migrator) GetPendingMigrations(ctx context.Context) ([]Migration, error) {
+	files, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, len(files))
+	for i, file := range files {
+		pending[i] = newMigration(file)
+	}
+
+	return pending, nil
+}
+
+// pendingMigrationFiles returns the migration files that haven't been applied
+// yet, in apply order.
+func (m *Migrator) pendingMigrationFiles(ctx context.Context) ([]*validator.MigrationFile, error) {
 	// Ensure migrations table exists first
 	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ensure migrations table: %w", err)
 	}
 
-	allMigrations, err := m.validator.GetMigrationFiles(ctx)
+	allMigrations, err := m.validator.ListMigrationFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	applied, err := m.appliedSet(ctx, allMigrations, true)
 	if err != nil {
+		return nil, err
+	}
+	pending := validator.NewMigrations(allMigrations, applied)
+
+	if err := validator.LoadAll(ctx, pending); err != nil {
 		return nil, fmt.Errorf("failed to get migration files: %w", err)
 	}
 
-	return validator.FindNewMigrations(ctx, allMigrations)
+	return pending, nil
 }
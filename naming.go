@@ -0,0 +1,126 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// maxNameLength is the size of the tracker's name column.
+const maxNameLength = 255
+
+// NamingConvention describes the required shape of migration file names,
+// e.g. "001_create_users.sql".
+type NamingConvention struct {
+	// PrefixDigits is the exact width of the zero-padded numeric prefix.
+	// Zero allows any numeric prefix; names must still start with one.
+	PrefixDigits int
+
+	// SnakeCase requires the description after the prefix to be lowercase
+	// snake_case.
+	SnakeCase bool
+
+	// MaxLength limits the file name length. Zero (or a larger value) uses
+	// the tracker column size of 255.
+	MaxLength int
+}
+
+// DefaultNamingConvention returns the convention used throughout the docs:
+// three-digit prefixes and lowercase snake_case descriptions.
+func DefaultNamingConvention() *NamingConvention {
+	return &NamingConvention{PrefixDigits: 3, SnakeCase: true, MaxLength: maxNameLength}
+}
+
+// NamingViolation describes a migration file name that breaks the convention.
+type NamingViolation struct {
+	Migration string `json:"migration"`
+	Message   string `json:"message"`
+}
+
+// String formats the violation for display.
+func (v NamingViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Migration, v.Message)
+}
+
+// NamingError is returned when pending migration names break the naming
+// convention. It lists every violation, not just the first.
+type NamingError struct {
+	Violations []NamingViolation
+}
+
+func (e *NamingError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		lines[i] = "  " + v.String()
+	}
+	return fmt.Sprintf("%d migration file names break the naming convention:\n%s",
+		len(e.Violations), strings.Join(lines, "\n"))
+}
+
+var snakeCasePattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// Check returns the violations of the convention in names.
+func (c *NamingConvention) Check(names []string) []NamingViolation {
+	maxLength := c.MaxLength
+	if maxLength <= 0 || maxLength > maxNameLength {
+		maxLength = maxNameLength
+	}
+
+	var violations []NamingViolation
+	for _, name := range names {
+		violate := func(format string, args ...any) {
+			violations = append(violations, NamingViolation{Migration: name, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if len(name) > maxLength {
+			violate("name is %d characters long, the maximum is %d", len(name), maxLength)
+		}
+
+		base := validator.TrimExtension(name)
+		prefix, description, _ := strings.Cut(base, "_")
+		if prefix == "" || strings.Trim(prefix, "0123456789") != "" {
+			violate("name must start with a numeric prefix followed by '_' (e.g. 001_create_users.sql)")
+			continue
+		}
+		if c.PrefixDigits > 0 && len(prefix) != c.PrefixDigits {
+			violate("prefix %q must be zero-padded to %d digits", prefix, c.PrefixDigits)
+		}
+		if c.SnakeCase && !snakeCasePattern.MatchString(description) {
+			violate("description %q must be lowercase snake_case", description)
+		}
+	}
+
+	return violations
+}
+
+// CheckNames validates pending migration file names against
+// Options.NamingConvention without applying anything. Applied migrations are
+// not checked since renaming them would break validation.
+func (m *Migrator) CheckNames(ctx context.Context) error {
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.checkNames(pending)
+}
+
+// checkNames reports every naming violation in migrations as a single error.
+func (m *Migrator) checkNames(migrations []*validator.MigrationFile) error {
+	if m.naming == nil {
+		return nil
+	}
+
+	names := make([]string, len(migrations))
+	for i, migration := range migrations {
+		names[i] = migration.Name
+	}
+
+	if violations := m.naming.Check(names); len(violations) > 0 {
+		return &NamingError{Violations: violations}
+	}
+	return nil
+}
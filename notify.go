@@ -0,0 +1,161 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType identifies a stage of the migration run reported to notifiers.
+type EventType string
+
+const (
+	// EventRunStarted is sent when Migrate begins.
+	EventRunStarted EventType = "run_started"
+
+	// EventMigrationApplied is sent after each migration is committed to production.
+	EventMigrationApplied EventType = "migration_applied"
+
+	// EventShadowTestFailed is sent when a new migration fails on the shadow database.
+	EventShadowTestFailed EventType = "shadow_test_failed"
+
+	// EventVerificationFailed is sent when verification queries fail after
+	// migrations were applied to production.
+	EventVerificationFailed EventType = "verification_failed"
+
+	// EventRunCompleted is sent when Migrate returns, whether it succeeded or not.
+	EventRunCompleted EventType = "run_completed"
+)
+
+// Event describes something that happened during a migration run.
+type Event struct {
+	// Type is the kind of event.
+	Type EventType `json:"type"`
+
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+
+	// Migration is the migration the event refers to, if any.
+	Migration string `json:"migration,omitempty"`
+
+	// Applied lists the migrations applied during the run (EventRunCompleted only).
+	Applied []AppliedMigration `json:"applied,omitempty"`
+
+	// DurationMS is how long the migration or run took, in milliseconds.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+
+	// Error is the failure message, empty on success.
+	Error string `json:"error,omitempty"`
+
+	// SQL is a snippet of the statement that failed, when it can be determined.
+	SQL string `json:"sql,omitempty"`
+}
+
+// AppliedMigration describes a migration applied during a run.
+type AppliedMigration struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+
+	// RowCounts are the rows affected by the migration's DML statements.
+	RowCounts []RowCount `json:"row_counts,omitempty"`
+}
+
+// Notifier receives migration events, e.g. to forward them to an alerting system.
+//
+// Notify is called synchronously from the migration run. Errors are logged as
+// warnings and never fail the run.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// notifyTimeout bounds how long a single notifier may take to deliver an event.
+const notifyTimeout = 30 * time.Second
+
+// notify delivers an event to all configured notifiers.
+func (m *Migrator) notify(ctx context.Context, event Event) {
+	if len(m.notifiers) == 0 {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	// Deliver even if the run itself was cancelled, so failures still get reported
+	notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+
+	for _, n := range m.notifiers {
+		err := catchPanic(func() error { return n.Notify(notifyCtx, event) })
+		if err != nil {
+			m.log.Warnf("Failed to send %s notification: %v", event.Type, err)
+		}
+	}
+}
+
+// migrationError ties a failure to the migration that caused it, so failure
+// events can name the migration and quote the offending SQL.
+type migrationError struct {
+	name    string
+	content string
+	err     error
+}
+
+func (e *migrationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *migrationError) Unwrap() error {
+	return e.err
+}
+
+// failureDetails fills in the migration name and SQL snippet of a failure event.
+func failureDetails(event *Event, err error) {
+	var migErr *migrationError
+	if errors.As(err, &migErr) {
+		event.Migration = migErr.name
+		event.SQL = sqlSnippet(migErr.content, err)
+	}
+
+	// Error positions of a failed statement are relative to the statement
+	var stmtErr *StatementError
+	if errors.As(err, &stmtErr) {
+		event.SQL = sqlSnippet(stmtErr.Statement, err)
+	}
+}
+
+// maxSnippetLength bounds the size of SQL snippets included in events.
+const maxSnippetLength = 500
+
+// sqlSnippet extracts the part of content that err points at. PostgreSQL reports
+// the character position of syntax errors, in which case the line containing it
+// is returned; otherwise the beginning of the migration is used.
+func sqlSnippet(content string, err error) string {
+	var posErr interface{ Get(byte) string }
+	if errors.As(err, &posErr) {
+		if pos, convErr := strconv.Atoi(posErr.Get('P')); convErr == nil && pos > 0 {
+			runes := []rune(content)
+			if pos <= len(runes) {
+				offset := len(string(runes[:pos-1]))
+				start := strings.LastIndex(content[:offset], "\n") + 1
+				end := len(content)
+				if idx := strings.Index(content[offset:], "\n"); idx != -1 {
+					end = offset + idx
+				}
+				return truncate(strings.TrimSpace(content[start:end]), maxSnippetLength)
+			}
+		}
+	}
+
+	return truncate(strings.TrimSpace(content), maxSnippetLength)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
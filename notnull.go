@@ -0,0 +1,213 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NotNullColumn describes a NOT NULL column with a default added to a large
+// table without scanning it under an exclusive lock.
+//
+// Rather than one ALTER TABLE, the column is added in phases: it is added as
+// nullable with the default for new rows, existing rows are backfilled in
+// batches, and the NOT NULL constraint is added as a NOT VALID check that is
+// validated without blocking writes, which lets SET NOT NULL skip its scan.
+type NotNullColumn struct {
+	// Name identifies the change in the progress table; a change with the
+	// same name resumes where it stopped. Defaults to "<table>.<column>".
+	Name string
+
+	// Table is the table to change, optionally schema-qualified.
+	Table string
+
+	// Column is the column to add.
+	Column string
+
+	// Type is the column's type, e.g. "text".
+	Type string
+
+	// Default is the column's default expression, e.g. "'active'".
+	Default string
+
+	// Backfill is the expression existing rows are set to, referring to
+	// columns by name. Defaults to Default.
+	Backfill string
+
+	// Key is a unique, ordered column used to backfill in batches. Defaults
+	// to "id".
+	Key string
+
+	// BatchSize is the number of rows updated per backfill batch. Defaults
+	// to 1000.
+	BatchSize int
+
+	// Pause is the time to wait between backfill batches.
+	Pause time.Duration
+
+	// LockTimeout bounds the wait for the locks taken to add the column and
+	// set it NOT NULL. Defaults to 5 seconds.
+	LockTimeout time.Duration
+}
+
+const (
+	stepAddConstraint = "add-constraint"
+	stepValidate      = "validate"
+	stepSetNotNull    = "set-not-null"
+)
+
+// AddNotNullColumn adds a NOT NULL column with a default in the steps
+// described on NotNullColumn. Each step is recorded in the progress table,
+// so an interrupted change picks up where it left off when called again,
+// and a completed one is a no-op.
+//
+// Setting NOT NULL without a scan relies on the validated check constraint,
+// which PostgreSQL uses from version 12; earlier versions still scan the
+// table, under an exclusive lock, in the last step.
+func (m *Migrator) AddNotNullColumn(ctx context.Context, change NotNullColumn) error {
+	if change.Table == "" || change.Column == "" || change.Type == "" || change.Default == "" {
+		return fmt.Errorf("NOT NULL column requires Table, Column, Type and Default")
+	}
+	if change.Name == "" {
+		change.Name = change.Table + "." + change.Column
+	}
+	if change.Backfill == "" {
+		change.Backfill = change.Default
+	}
+	if change.Key == "" {
+		change.Key = "id"
+	}
+	if change.BatchSize <= 0 {
+		change.BatchSize = 1000
+	}
+	if change.LockTimeout <= 0 {
+		change.LockTimeout = 5 * time.Second
+	}
+
+	if err := m.tracker.EnsureProgressTable(ctx); err != nil {
+		return err
+	}
+
+	progress, _, err := m.tracker.GetProgress(ctx, change.Name)
+	if err != nil {
+		return err
+	}
+	if progress.Completed {
+		m.log.Infof("✓ NOT NULL column %s already added", change.Name)
+		return nil
+	}
+
+	table := quoteQualified(change.Table)
+	column := pq.QuoteIdentifier(change.Column)
+	constraint := pq.QuoteIdentifier(notNullConstraintName(change))
+	lockTimeout := fmt.Sprintf("SET LOCAL lock_timeout = %d", change.LockTimeout.Milliseconds())
+
+	m.log.Infof("🔁 Adding NOT NULL column %s.%s %s", change.Table, change.Column, change.Type)
+
+	if progress.Step == "" || progress.Step == stepAddColumn {
+		// New rows get the default from here on, so the backfill only has
+		// to catch up with the rows that exist now
+		statements := []string{
+			lockTimeout,
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, change.Type),
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", table, column, change.Default),
+		}
+		if err := m.execInTx(ctx, statements); err != nil {
+			return fmt.Errorf("failed to add column for %s: %w", change.Name, err)
+		}
+
+		progress.Step = stepBackfill
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Added %s as nullable with default %s", change.Column, change.Default)
+	}
+
+	if progress.Step == stepBackfill {
+		key := pq.QuoteIdentifier(change.Key)
+		update := func(bound string) string {
+			return fmt.Sprintf(`
+				WITH batch AS (
+					SELECT %[2]s FROM %[1]s WHERE %[6]s ORDER BY %[2]s LIMIT %[3]d
+				), updated AS (
+					UPDATE %[1]s AS t SET %[4]s = (SELECT %[5]s FROM (SELECT t.*) AS src)
+					FROM batch WHERE t.%[2]s = batch.%[2]s AND t.%[4]s IS NULL
+				)
+				SELECT COUNT(*), COALESCE(MAX(%[2]s)::text, '') FROM batch
+			`, table, key, change.BatchSize, column, change.Backfill, bound)
+		}
+
+		if err := m.runBatches(ctx, &progress, update, key, change.Pause); err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", change.Name, err)
+		}
+
+		progress.Step = stepAddConstraint
+		progress.LastKey = ""
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Backfilled %d rows", progress.Rows)
+	}
+
+	if progress.Step == stepAddConstraint {
+		// NOT VALID skips checking existing rows, so the lock is brief
+		statements := []string{
+			lockTimeout,
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", table, constraint),
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", table, constraint, column),
+		}
+		if err := m.execInTx(ctx, statements); err != nil {
+			return fmt.Errorf("failed to add constraint for %s: %w", change.Name, err)
+		}
+
+		progress.Step = stepValidate
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Added NOT VALID check on %s", change.Column)
+	}
+
+	if progress.Step == stepValidate {
+		// Validation scans the table but only takes a SHARE UPDATE EXCLUSIVE
+		// lock, so reads and writes continue
+		query := fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, constraint)
+		if _, err := m.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to validate constraint for %s: %w", change.Name, err)
+		}
+
+		progress.Step = stepSetNotNull
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Validated check on %s", change.Column)
+	}
+
+	if progress.Step == stepSetNotNull {
+		statements := []string{
+			lockTimeout,
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column),
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", table, constraint),
+		}
+		if err := m.execInTx(ctx, statements); err != nil {
+			return fmt.Errorf("failed to set %s NOT NULL: %w", change.Name, err)
+		}
+
+		progress.Completed = true
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Set %s NOT NULL", change.Column)
+	}
+
+	m.log.Infof("✅ NOT NULL column %s added", change.Name)
+	return nil
+}
+
+// notNullConstraintName returns the name of the temporary check constraint
+// for a change.
+func notNullConstraintName(change NotNullColumn) string {
+	_, table := splitQualified(change.Table)
+	return truncateIdentifier(fmt.Sprintf("%s_%s_not_null", table, change.Column))
+}
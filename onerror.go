@@ -0,0 +1,68 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInfo describes a failed run for Options.OnError.
+type ErrInfo struct {
+	// Err is the error the run returns.
+	Err error
+
+	// Phase is the phase the run was in when it failed. Failures before
+	// any migration was tested, e.g. validation or the lock, are
+	// PhasePreflight.
+	Phase Phase
+
+	// Migration is the migration being tested or applied, if any.
+	Migration string
+
+	// SQL is a snippet of the statement that failed, when it can be
+	// determined.
+	SQL string
+
+	// Code is the PostgreSQL error code (SQLSTATE), e.g. "42P01" for an
+	// undefined table, when the database reported the failure.
+	Code string
+}
+
+// ErrorFunc receives failed runs, see Options.OnError.
+type ErrorFunc func(ctx context.Context, info ErrInfo)
+
+// reportError calls Options.OnError for a failed run.
+func (m *Migrator) reportError(ctx context.Context, result *Result, err error) {
+	if m.onError == nil || err == nil {
+		return
+	}
+
+	var event Event
+	failureDetails(&event, err)
+	info := ErrInfo{
+		Err:       err,
+		Phase:     result.lastProgress.Phase,
+		Migration: event.Migration,
+		SQL:       event.SQL,
+	}
+	if info.Phase == "" {
+		info.Phase = PhasePreflight
+	}
+	if info.Migration == "" && (info.Phase == PhaseShadowTest || info.Phase == PhaseApply) {
+		info.Migration = result.lastProgress.Migration
+	}
+
+	// Reported by lib/pq and pgx errors alike
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		info.Code = stateErr.SQLState()
+	}
+
+	// Report even if the run itself was cancelled
+	err = catchPanic(func() error {
+		m.onError(context.WithoutCancel(ctx), info)
+		return nil
+	})
+	if err != nil {
+		m.log.Warnf("OnError failed: %v", err)
+	}
+}
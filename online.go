@@ -0,0 +1,317 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/lib/pq"
+)
+
+// ColumnTypeChange describes a zero-downtime change of a column's type.
+//
+// Rather than rewriting the table under an ACCESS EXCLUSIVE lock, the change
+// runs in steps: a new column is added, a trigger keeps it in sync with
+// writes, existing rows are backfilled in batches, and finally the columns
+// are swapped by renaming them in one short transaction.
+type ColumnTypeChange struct {
+	// Name identifies the change in the progress table; a change with the
+	// same name resumes where it stopped. Defaults to "<table>.<column>".
+	Name string
+
+	// Table is the table to change, optionally schema-qualified.
+	Table string
+
+	// Column is the column whose type changes.
+	Column string
+
+	// NewType is the column's new type, e.g. "bigint".
+	NewType string
+
+	// Using is the expression converting a row's old value, referring to
+	// columns by name. Defaults to "<column>::<new type>".
+	Using string
+
+	// Key is a unique, ordered column used to backfill in batches. Defaults
+	// to "id".
+	Key string
+
+	// BatchSize is the number of rows updated per backfill batch. Defaults
+	// to 1000.
+	BatchSize int
+
+	// Pause is the time to wait between backfill batches.
+	Pause time.Duration
+
+	// LockTimeout bounds the wait for the locks taken by adding the column
+	// and by the swap. Defaults to 5 seconds.
+	LockTimeout time.Duration
+
+	// DropOld drops the old column after the swap. Otherwise it is kept as
+	// "<column>_old".
+	DropOld bool
+}
+
+const (
+	stepAddColumn = "add-column"
+	stepBackfill  = "backfill"
+	stepSwap      = "swap"
+)
+
+// ChangeColumnType changes a column's type without blocking reads and
+// writes for the duration of a table rewrite. Each step is recorded in the
+// progress table, so an interrupted change picks up where it left off when
+// called again, and a completed one is a no-op.
+//
+// Indexes, constraints, defaults and views that depend on the column stay on
+// the old column; recreate them on "<column>_new" before the swap if needed.
+func (m *Migrator) ChangeColumnType(ctx context.Context, change ColumnTypeChange) error {
+	if change.Table == "" || change.Column == "" || change.NewType == "" {
+		return fmt.Errorf("column type change requires Table, Column and NewType")
+	}
+	if change.Name == "" {
+		change.Name = change.Table + "." + change.Column
+	}
+	if change.Using == "" {
+		change.Using = fmt.Sprintf("%s::%s", pq.QuoteIdentifier(change.Column), change.NewType)
+	}
+	if change.Key == "" {
+		change.Key = "id"
+	}
+	if change.BatchSize <= 0 {
+		change.BatchSize = 1000
+	}
+	if change.LockTimeout <= 0 {
+		change.LockTimeout = 5 * time.Second
+	}
+
+	if err := m.tracker.EnsureProgressTable(ctx); err != nil {
+		return err
+	}
+
+	progress, _, err := m.tracker.GetProgress(ctx, change.Name)
+	if err != nil {
+		return err
+	}
+	if progress.Completed {
+		m.log.Infof("✓ Column type change %s already completed", change.Name)
+		return nil
+	}
+
+	table := quoteQualified(change.Table)
+	column := pq.QuoteIdentifier(change.Column)
+	newColumn := pq.QuoteIdentifier(change.Column + "_new")
+	oldColumn := pq.QuoteIdentifier(change.Column + "_old")
+	function := quoteQualified(onlineFunctionName(change))
+	trigger := pq.QuoteIdentifier(onlineTriggerName(change))
+	lockTimeout := fmt.Sprintf("SET LOCAL lock_timeout = %d", change.LockTimeout.Milliseconds())
+
+	m.log.Infof("🔁 Changing type of %s.%s to %s", change.Table, change.Column, change.NewType)
+
+	if progress.Step == "" || progress.Step == stepAddColumn {
+		// The sync trigger must be in place before the backfill starts, so
+		// rows written during the backfill are never missed
+		statements := []string{
+			lockTimeout,
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, newColumn, change.NewType),
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $migrator$
+BEGIN
+	SELECT %s INTO NEW.%s FROM (SELECT NEW.*) AS src;
+	RETURN NEW;
+END
+$migrator$ LANGUAGE plpgsql`, function, change.Using, newColumn),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", trigger, table),
+			fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", trigger, table, function),
+		}
+		if err := m.execInTx(ctx, statements); err != nil {
+			return fmt.Errorf("failed to add column for %s: %w", change.Name, err)
+		}
+
+		progress.Step = stepBackfill
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Added %s_new with sync trigger", change.Column)
+	}
+
+	if progress.Step == stepBackfill {
+		key := pq.QuoteIdentifier(change.Key)
+		update := func(bound string) string {
+			return fmt.Sprintf(`
+				WITH batch AS (
+					SELECT %[2]s FROM %[1]s WHERE %[6]s ORDER BY %[2]s LIMIT %[3]d
+				), updated AS (
+					UPDATE %[1]s AS t SET %[4]s = (SELECT %[5]s FROM (SELECT t.*) AS src)
+					FROM batch WHERE t.%[2]s = batch.%[2]s
+					RETURNING t.%[2]s
+				)
+				SELECT COUNT(*), COALESCE(MAX(%[2]s)::text, '') FROM updated
+			`, table, key, change.BatchSize, newColumn, change.Using, bound)
+		}
+
+		if err := m.runBatches(ctx, &progress, update, key, change.Pause); err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", change.Name, err)
+		}
+
+		progress.Step = stepSwap
+		progress.LastKey = ""
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Backfilled %d rows", progress.Rows)
+	}
+
+	if progress.Step == stepSwap {
+		// The swap may have committed without its progress being saved
+		swapped, err := m.columnsSwapped(ctx, change)
+		if err != nil {
+			return err
+		}
+		if swapped {
+			progress.Completed = true
+			if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+				return err
+			}
+			m.log.Infof("✅ Column type change %s completed", change.Name)
+			return nil
+		}
+
+		statements := []string{
+			lockTimeout,
+			fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", table),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", trigger, table),
+			fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", function),
+			fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, column, oldColumn),
+			fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, newColumn, column),
+		}
+		if change.DropOld {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, oldColumn))
+		}
+		if err := m.execInTx(ctx, statements); err != nil {
+			return fmt.Errorf("failed to swap columns for %s: %w", change.Name, err)
+		}
+
+		progress.Completed = true
+		if err := m.tracker.SaveProgress(ctx, progress); err != nil {
+			return err
+		}
+		m.log.Infof("  ✓ Swapped %s to %s", change.Column, change.NewType)
+	}
+
+	m.log.Infof("✅ Column type change %s completed", change.Name)
+	return nil
+}
+
+// columnsSwapped reports whether the swap of change already happened: the
+// old column was renamed to "<column>_old", or "<column>_new" is gone.
+func (m *Migrator) columnsSwapped(ctx context.Context, change ColumnTypeChange) (bool, error) {
+	var oldExists, newExists bool
+	err := m.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = $2 AND NOT attisdropped),
+			EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = $3 AND NOT attisdropped)
+	`, quoteQualified(change.Table), change.Column+"_old", change.Column+"_new").Scan(&oldExists, &newExists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check columns of %s: %w", change.Table, err)
+	}
+	return oldExists || !newExists, nil
+}
+
+// runBatches runs a batch query until it processes no more rows, saving the
+// progress after each batch. query builds the statement around a condition
+// bounding the batch by key; the statement must return the number of rows it
+// processed and the batch's highest key as text.
+func (m *Migrator) runBatches(ctx context.Context, progress *tracker.Progress, query func(bound string) string, key string, pause time.Duration) error {
+	for {
+		bound, args := "TRUE", []any{}
+		if progress.LastKey != "" {
+			bound, args = fmt.Sprintf("%s > $1", key), []any{progress.LastKey}
+		}
+
+		var count int64
+		var lastKey string
+		if err := m.db.QueryRowContext(ctx, query(bound), args...).Scan(&count, &lastKey); err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+
+		progress.LastKey = lastKey
+		progress.Rows += count
+		if err := m.tracker.SaveProgress(ctx, *progress); err != nil {
+			return err
+		}
+
+		if pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pause):
+			}
+		}
+	}
+}
+
+// execInTx runs statements in a single transaction.
+func (m *Migrator) execInTx(ctx context.Context, statements []string) error {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// onlineTriggerName returns the name of the sync trigger for a change.
+func onlineTriggerName(change ColumnTypeChange) string {
+	_, table := splitQualified(change.Table)
+	return truncateIdentifier(fmt.Sprintf("%s_%s_sync", table, change.Column))
+}
+
+// onlineFunctionName returns the name of the sync trigger function for a
+// change, in the table's schema.
+func onlineFunctionName(change ColumnTypeChange) string {
+	schema, _ := splitQualified(change.Table)
+	name := truncateIdentifier("migrator_" + onlineTriggerName(change))
+	if schema != "" {
+		return schema + "." + name
+	}
+	return name
+}
+
+// splitQualified splits "schema.name" into its parts. The schema is empty for
+// unqualified names.
+func splitQualified(name string) (string, string) {
+	if schema, rest, ok := strings.Cut(name, "."); ok {
+		return schema, rest
+	}
+	return "", name
+}
+
+// quoteQualified quotes a possibly schema-qualified name.
+func quoteQualified(name string) string {
+	schema, rest := splitQualified(name)
+	if schema != "" {
+		return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(rest)
+	}
+	return pq.QuoteIdentifier(rest)
+}
+
+// truncateIdentifier shortens a name to PostgreSQL's 63-byte identifier limit.
+func truncateIdentifier(name string) string {
+	if len(name) > 63 {
+		return name[:63]
+	}
+	return name
+}
@@ -0,0 +1,56 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CleanupOrphans drops the shadow databases on the server created more than
+// olderThan ago, e.g. left behind by CI jobs killed mid-run, and returns
+// their names. It covers the shadow databases of every migrated database on
+// the server, not just this one's.
+//
+// Shadow databases with sessions connected are left alone, since a run may
+// be testing on them. Shadow databases created before creation times were
+// recorded count as old. Cleaning up requires Options.DatabaseURL, like
+// shadow testing; a failure to drop one database doesn't stop the others.
+func (m *Migrator) CleanupOrphans(ctx context.Context, olderThan time.Duration) ([]string, error) {
+	if m.shadowManager == nil {
+		return nil, errors.New("cleaning up shadow databases requires Options.DatabaseURL")
+	}
+
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	orphans, err := m.shadowManager.Orphans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := []string{}
+	var errs []error
+	for _, orphan := range orphans {
+		switch {
+		case orphan.Connections > 0:
+			m.log.Debugf("  Keeping %s: %d sessions connected", orphan.Name, orphan.Connections)
+			continue
+		case !orphan.CreatedAt.IsZero() && time.Since(orphan.CreatedAt) < olderThan:
+			m.log.Debugf("  Keeping %s: created %s ago", orphan.Name, time.Since(orphan.CreatedAt).Round(time.Second))
+			continue
+		}
+
+		m.log.Infof("🧹 Dropping orphaned shadow database %s", orphan.Name)
+		if err := m.shadowManager.DropOrphan(ctx, orphan.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dropped = append(dropped, orphan.Name)
+	}
+
+	if len(errs) > 0 {
+		return dropped, fmt.Errorf("failed to drop %d shadow databases: %w", len(errs), errors.Join(errs...))
+	}
+	return dropped, nil
+}
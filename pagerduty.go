@@ -0,0 +1,135 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyNotifier triggers a PagerDuty incident when a migration run fails,
+// using the Events API v2.
+//
+// Incidents are deduplicated per Source, so repeated failures (e.g. from retried
+// deploy jobs) update a single open incident instead of paging again.
+type PagerDutyNotifier struct {
+	// RoutingKey is the integration key of the PagerDuty service.
+	RoutingKey string
+
+	// Source identifies the affected system. Set it when several databases
+	// report to the same service: without it all runs share one incident,
+	// whichever host they ran on, and the hostname is reported as the source.
+	Source string
+
+	// Severity is one of critical, error, warning or info. Defaults to "critical".
+	Severity string
+
+	// ResolveOnSuccess resolves the run's open incident once a later run succeeds.
+	ResolveOnSuccess bool
+
+	// EventsURL overrides the Events API endpoint. Mainly useful for testing.
+	EventsURL string
+
+	// Client is the HTTP client used for delivery. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// NewPagerDutyNotifier creates a PagerDutyNotifier for the given integration key.
+func NewPagerDutyNotifier(routingKey string) *PagerDutyNotifier {
+	return &PagerDutyNotifier{RoutingKey: routingKey}
+}
+
+// Notify implements Notifier.
+func (p *PagerDutyNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != EventRunCompleted {
+		return nil
+	}
+
+	var payload map[string]interface{}
+	switch {
+	case event.Error != "":
+		payload = map[string]interface{}{
+			"routing_key":  p.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    p.dedupKey(),
+			"payload": map[string]interface{}{
+				"summary":   truncate("Migration run failed: "+event.Error, 1024),
+				"source":    p.source(),
+				"severity":  p.severity(),
+				"component": "migrator",
+				"custom_details": map[string]interface{}{
+					"migration":   event.Migration,
+					"sql":         event.SQL,
+					"applied":     event.Applied,
+					"duration_ms": event.DurationMS,
+				},
+			},
+		}
+	case p.ResolveOnSuccess:
+		payload = map[string]interface{}{
+			"routing_key":  p.RoutingKey,
+			"event_action": "resolve",
+			"dedup_key":    p.dedupKey(),
+		}
+	default:
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode pagerduty event: %w", err)
+	}
+
+	eventsURL := p.EventsURL
+	if eventsURL == "" {
+		eventsURL = pagerDutyEventsURL
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return postWithRetry(ctx, client, 3, time.Second, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, eventsURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// dedupKey identifies the incident of the run. It never depends on the host,
+// since each pod or deploy job has its own: a retried job would page again
+// and a successful run wouldn't resolve the incident.
+func (p *PagerDutyNotifier) dedupKey() string {
+	if p.Source == "" {
+		return "migrator"
+	}
+	return "migrator:" + p.Source
+}
+
+func (p *PagerDutyNotifier) source() string {
+	if p.Source != "" {
+		return p.Source
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "migrator"
+	}
+	return hostname
+}
+
+func (p *PagerDutyNotifier) severity() string {
+	if p.Severity != "" {
+		return p.Severity
+	}
+	return "critical"
+}
@@ -0,0 +1,38 @@
+package migrator
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned by a run when driver code or a hook (OnProgress,
+// BackupHook, a Policy, ...) panicked. The run is stopped as if it failed:
+// the current migration is rolled back, the shadow database is dropped and
+// the lock released.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic during migration run: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// catchPanic runs fn, returning a *PanicError if it panics.
+func catchPanic(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
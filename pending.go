@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"context"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// Directive is a "-- migrator:<key> <value>" comment in a migration, such as
+// "-- migrator:requires-pg>=14" or "-- migrator:isolation serializable".
+type Directive struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	Line  int    `json:"line"`
+}
+
+// PendingMigration describes a migration file that hasn't been applied yet,
+// for deploy tooling that reviews or reports what a run will do.
+type PendingMigration struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Checksum is the SHA-256 of the migration content, as it will be
+	// recorded when applied.
+	Checksum string `json:"checksum"`
+
+	// Size is the length of the migration's SQL in bytes, after
+	// decompressing .sql.gz files.
+	Size int `json:"size"`
+
+	// SQL is the migration's content, for review.
+	SQL string `json:"sql"`
+
+	// Directives lists the migration's directive comments, in order.
+	Directives []Directive `json:"directives"`
+
+	// Metadata is the migration's front matter, as it will be recorded.
+	Metadata MigrationMetadata `json:"metadata"`
+
+	// Impact predicts the migration's locking impact, sized with the
+	// planner's row estimates for the affected tables.
+	Impact MigrationImpact `json:"impact"`
+}
+
+// DescribePendingMigrations returns metadata about each pending migration, in
+// apply order. Nothing is applied.
+func (m *Migrator) DescribePendingMigrations(ctx context.Context) ([]PendingMigration, error) {
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	impacts, err := m.analyzeImpact(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	described := make([]PendingMigration, len(pending))
+	for i, migration := range pending {
+		described[i] = describePending(migration, impacts[i])
+	}
+
+	return described, nil
+}
+
+// describePending describes a loaded pending migration with its impact.
+func describePending(migration *validator.MigrationFile, impact MigrationImpact) PendingMigration {
+	directives := []Directive{}
+	for _, d := range sqlparse.Directives(migration.Content) {
+		directives = append(directives, Directive{Key: d.Key, Value: d.Value, Line: d.Line})
+	}
+
+	// Malformed front matter fails the run; describe what can be parsed
+	metadata, _ := migration.Metadata()
+
+	return PendingMigration{
+		Name:       migration.Name,
+		Version:    migration.Version(),
+		Checksum:   migration.Checksum(),
+		Size:       len(migration.Content),
+		SQL:        migration.Content,
+		Directives: directives,
+		Metadata:   newMigrationMetadata(metadata),
+		Impact:     impact,
+	}
+}
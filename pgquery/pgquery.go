@@ -0,0 +1,58 @@
+// Package pgquery provides an offline syntax checker for migrations built on
+// libpg_query, the parser extracted from the PostgreSQL server source.
+//
+// It catches syntax errors instantly, without a database connection, which is
+// useful in fast CI checks and in environments where shadow database testing
+// is skipped:
+//
+//	m := migrator.NewWithOptions(db, migrator.Options{
+//		SyntaxChecker: pgquery.Check,
+//	})
+//
+// The package requires cgo. It lives in its own package so programs that don't
+// import it keep building without a C toolchain.
+package pgquery
+
+import (
+	"errors"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"github.com/pganalyze/pg_query_go/v5/parser"
+)
+
+// Error is a syntax error reported by the PostgreSQL parser.
+type Error struct {
+	// Message is the parser's error message, e.g. `syntax error at or near "name"`.
+	Message string
+
+	// Cursor is the 1-based character position of the error in the SQL, or 0 if unknown.
+	Cursor int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Position returns the 1-based character position of the error.
+func (e *Error) Position() int {
+	return e.Cursor
+}
+
+// Check parses sql with the PostgreSQL parser and returns an *Error describing
+// the first syntax error, or nil if the SQL parses.
+//
+// Only syntax is checked: references to missing tables or columns are still
+// caught by shadow database testing.
+func Check(sql string) error {
+	_, err := pg_query.Parse(sql)
+	if err == nil {
+		return nil
+	}
+
+	var parseErr *parser.Error
+	if errors.As(err, &parseErr) {
+		return &Error{Message: parseErr.Message, Cursor: parseErr.Cursorpos}
+	}
+
+	return &Error{Message: err.Error()}
+}
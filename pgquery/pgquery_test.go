@@ -0,0 +1,23 @@
+package pgquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(`
+		CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+		CREATE INDEX idx_users_name ON users (name);
+	`))
+
+	err := Check("CREATE TABLE invalid (\n\tid SERIAL PRIMARY KEY\n\tname VARCHAR(255)\n);")
+	require.Error(t, err)
+
+	var parseErr *Error
+	require.ErrorAs(t, err, &parseErr)
+	assert.Contains(t, parseErr.Message, "syntax error")
+	assert.Greater(t, parseErr.Position(), 0)
+}
@@ -0,0 +1,188 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ErrPlanChanged is returned by Apply when the database or the migration
+// files changed since the plan was made.
+var ErrPlanChanged = errors.New("pending migrations changed since the plan was made")
+
+// Plan is a reviewable record of the migrations a run will apply to a
+// database. Write it to a file with WriteFile, have it approved, then run
+// exactly those migrations with Migrator.Apply.
+type Plan struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Database is the current_database() the plan was made for.
+	Database string `json:"database"`
+
+	// Head is the last applied migration when the plan was made, empty if
+	// none was applied.
+	Head string `json:"head,omitempty"`
+
+	// Migrations are the pending migrations, in apply order.
+	Migrations []PendingMigration `json:"migrations"`
+
+	// Hash covers Database, Head and the names and checksums of Migrations,
+	// so edits to the plan file are detected.
+	Hash string `json:"hash"`
+}
+
+// hash returns the hash of the plan's approved content.
+func (p *Plan) hash() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "database %s\nhead %s\n", p.Database, p.Head)
+	for _, migration := range p.Migrations {
+		fmt.Fprintf(&b, "%s %s\n", migration.Checksum, migration.Name)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteFile writes the plan to path as JSON, failing if the file exists.
+func (p *Plan) WriteFile(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	if err := writeNewFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// ReadPlan reads a plan written by WriteFile and checks its hash.
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode plan %s: %w", path, err)
+	}
+	if plan.Hash != plan.hash() {
+		return nil, fmt.Errorf("plan %s was modified after it was made: hash mismatch", path)
+	}
+
+	return &plan, nil
+}
+
+// Plan describes the pending migrations of the database, for review before
+// Apply. Nothing is applied.
+func (m *Migrator) Plan(ctx context.Context) (*Plan, error) {
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	impacts, err := m.analyzeImpact(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.tracker.GetAppliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newPlan(ctx, records, pending, impacts)
+}
+
+// newPlan builds the plan for applying the loaded pending migrations, given
+// the applied records and the impact of each migration.
+func (m *Migrator) newPlan(ctx context.Context, records []tracker.Record, pending []*validator.MigrationFile, impacts []MigrationImpact) (*Plan, error) {
+	plan := &Plan{CreatedAt: time.Now().UTC(), Migrations: make([]PendingMigration, len(pending))}
+	for i, migration := range pending {
+		plan.Migrations[i] = describePending(migration, impacts[i])
+	}
+
+	if err := m.db.QueryRowContext(ctx, "SELECT current_database()").Scan(&plan.Database); err != nil {
+		return nil, fmt.Errorf("failed to get current database: %w", err)
+	}
+	if len(records) > 0 {
+		plan.Head = records[len(records)-1].Name
+	}
+
+	plan.Hash = plan.hash()
+	return plan, nil
+}
+
+// Apply runs the migration process like MigrateWithResult, applying the
+// migrations of a plan file written by Plan.WriteFile. It refuses to run,
+// with ErrPlanChanged, if the database, the applied migrations, or the set
+// or content of the pending migrations changed since the plan was made.
+func (m *Migrator) Apply(ctx context.Context, planFile string) (*Result, error) {
+	plan, err := ReadPlan(planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.run(ctx, plan)
+}
+
+// verifyPlan checks that run would apply exactly the migrations of plan.
+func (m *Migrator) verifyPlan(ctx context.Context, plan *Plan, run *runState) error {
+	var changes []string
+
+	var database string
+	if err := m.db.QueryRowContext(ctx, "SELECT current_database()").Scan(&database); err != nil {
+		return fmt.Errorf("failed to get current database: %w", err)
+	}
+	if database != plan.Database {
+		changes = append(changes, fmt.Sprintf("plan is for database %q, connected to %q", plan.Database, database))
+	}
+
+	head := ""
+	if len(run.records) > 0 {
+		head = run.records[len(run.records)-1].Name
+	}
+	if head != plan.Head {
+		changes = append(changes, fmt.Sprintf("last applied migration is %q, plan expected %q", head, plan.Head))
+	}
+
+	if err := validator.LoadAll(ctx, run.pending); err != nil {
+		return fmt.Errorf("failed to get migration files: %w", err)
+	}
+	planned := make(map[string]string, len(plan.Migrations))
+	for _, migration := range plan.Migrations {
+		planned[migration.Name] = migration.Checksum
+	}
+	pending := make(map[string]bool, len(run.pending))
+	for _, migration := range run.pending {
+		pending[migration.Name] = true
+		checksum, ok := planned[migration.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s is pending but not in the plan", migration.Name))
+		case checksum != migration.Checksum():
+			changes = append(changes, fmt.Sprintf("%s was modified", migration.Name))
+		}
+	}
+	for _, migration := range plan.Migrations {
+		if !pending[migration.Name] {
+			changes = append(changes, fmt.Sprintf("%s is in the plan but no longer pending", migration.Name))
+		}
+	}
+
+	if len(changes) > 0 {
+		return fmt.Errorf("%w: %s", ErrPlanChanged, strings.Join(changes, "; "))
+	}
+
+	m.log.Infof("✓ Pending migrations match the plan (%d migrations)", len(plan.Migrations))
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPolicyDenied is returned when Options.Policy vetoes a run.
+var ErrPolicyDenied = errors.New("migration run denied by policy")
+
+// PolicyFunc decides whether a run may apply its plan, e.g. "no DROP
+// statements during business hours" or "data migrations require a second
+// approver". Returning an error vetoes the run; it is wrapped with
+// ErrPolicyDenied. The plan lists the pending migrations with their
+// SQL, checksums, directives and predicted lock impact.
+type PolicyFunc func(ctx context.Context, plan *Plan) error
+
+// checkPolicy asks the configured policy whether the pending migrations of
+// run may be applied.
+func (m *Migrator) checkPolicy(ctx context.Context, run *runState, result *Result) error {
+	if m.policy == nil || len(run.pending) == 0 {
+		return nil
+	}
+
+	// Impacts were already analyzed unless linting is off
+	impacts := result.Impacts
+	if len(impacts) != len(run.pending) {
+		var err error
+		if impacts, err = m.analyzeImpact(ctx, run.pending); err != nil {
+			result.warnf("Impact analysis failed: %v", err)
+			impacts = make([]MigrationImpact, len(run.pending))
+		}
+	}
+
+	plan, err := m.newPlan(ctx, run.records, run.pending, impacts)
+	if err != nil {
+		return err
+	}
+
+	if err := m.policy(ctx, plan); err != nil {
+		return fmt.Errorf("%w: %w", ErrPolicyDenied, err)
+	}
+
+	m.log.Infof("✓ Policy approved %d migrations", len(run.pending))
+	return nil
+}
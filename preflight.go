@@ -0,0 +1,282 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ErrStandby is returned when the target database is a hot standby (read-only
+// replica) and cannot be migrated.
+var ErrStandby = errors.New("database is a hot standby and cannot be migrated")
+
+// checkStandby refuses to run against a hot standby. With Options.StandbyWait
+// set it polls every WaitInterval until the server is promoted or the wait
+// expires, e.g. while a failover completes.
+func (m *Migrator) checkStandby(ctx context.Context) error {
+	var deadline time.Time
+	if m.standbyWait > 0 {
+		deadline = time.Now().Add(m.standbyWait)
+	}
+
+	for {
+		var inRecovery bool
+		if err := m.db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+			return fmt.Errorf("failed to check recovery status: %w", err)
+		}
+		if !inRecovery {
+			return nil
+		}
+
+		if deadline.IsZero() || time.Now().After(deadline) {
+			return fmt.Errorf("%w (connect to the primary instead)", ErrStandby)
+		}
+
+		m.log.Infof("⏳ Database is a hot standby, waiting for promotion...")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for primary: %w", ctx.Err())
+		case <-time.After(m.waitInterval):
+		}
+	}
+}
+
+// checkReplicationLag warns when a streaming replica lags behind by more than
+// Options.MaxReplicationLag, since heavy DDL will only widen the gap.
+func (m *Migrator) checkReplicationLag(ctx context.Context, migrations []*validator.MigrationFile, result *Result) {
+	if m.maxReplicationLag <= 0 || len(migrations) == 0 {
+		return
+	}
+
+	var lagSeconds float64
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(EXTRACT(EPOCH FROM replay_lag)), 0)::float8 FROM pg_stat_replication
+	`).Scan(&lagSeconds)
+	if err != nil {
+		result.warnf("Failed to check replication lag: %v", err)
+		return
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	if lag > m.maxReplicationLag {
+		heavy := ""
+		for _, impact := range result.Impacts {
+			if impact.Level == ImpactHigh {
+				heavy = " and pending migrations contain heavy DDL"
+				break
+			}
+		}
+		result.warnf("Replica lag is %s (threshold %s)%s", lag.Round(time.Millisecond), m.maxReplicationLag, heavy)
+	}
+}
+
+// ServerVersionError is returned when the server doesn't satisfy the version
+// required by Options.MinPostgresVersion or by "-- migrator:requires-pg"
+// directives in pending migrations.
+type ServerVersionError struct {
+	ServerVersion string
+	Unmet         []string
+}
+
+func (e *ServerVersionError) Error() string {
+	return fmt.Sprintf("PostgreSQL %s does not satisfy: %s", e.ServerVersion, strings.Join(e.Unmet, "; "))
+}
+
+// pgVersion is a PostgreSQL version: major is 906 for 9.6 and 1400 for 14, so
+// majors compare correctly across the 10.0 numbering change. minor is -1 when
+// a constraint doesn't specify it.
+type pgVersion struct {
+	major, minor int
+}
+
+// versionFromNum converts server_version_num (e.g. 140005) to a pgVersion.
+func versionFromNum(num int) pgVersion {
+	if num >= 100000 {
+		return pgVersion{major: num / 10000 * 100, minor: num % 10000}
+	}
+	return pgVersion{major: num / 100, minor: num % 100}
+}
+
+// parseVersion parses "14", "14.2", "9.6" or "9.6.3".
+func parseVersion(s string) (pgVersion, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return pgVersion{}, fmt.Errorf("invalid PostgreSQL version %q", s)
+		}
+		nums[i] = n
+	}
+
+	switch {
+	case len(nums) == 1:
+		return pgVersion{major: nums[0] * 100, minor: -1}, nil
+	case len(nums) == 2 && nums[0] >= 10:
+		return pgVersion{major: nums[0] * 100, minor: nums[1]}, nil
+	case len(nums) == 2:
+		return pgVersion{major: nums[0]*100 + nums[1], minor: -1}, nil
+	case len(nums) == 3 && nums[0] < 10:
+		return pgVersion{major: nums[0]*100 + nums[1], minor: nums[2]}, nil
+	}
+	return pgVersion{}, fmt.Errorf("invalid PostgreSQL version %q", s)
+}
+
+// compare compares the server version v with a constraint version c, at the
+// precision c specifies.
+func (v pgVersion) compare(c pgVersion) int {
+	switch {
+	case v.major != c.major:
+		return v.major - c.major
+	case c.minor < 0:
+		return 0
+	default:
+		return v.minor - c.minor
+	}
+}
+
+// satisfiesVersion reports whether server satisfies a constraint such as
+// ">=14", "<16" or "9.6" (which means ">=9.6").
+func satisfiesVersion(server pgVersion, constraint string) (bool, error) {
+	constraint = strings.TrimSpace(constraint)
+	op := ">="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(constraint, candidate) {
+			op, constraint = candidate, constraint[len(candidate):]
+			break
+		}
+	}
+
+	required, err := parseVersion(constraint)
+	if err != nil {
+		return false, err
+	}
+
+	cmp := server.compare(required)
+	switch op {
+	case ">=":
+		return cmp >= 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<":
+		return cmp < 0, nil
+	default:
+		return cmp == 0, nil
+	}
+}
+
+// checkServerVersion verifies the server against Options.MinPostgresVersion
+// and the "requires-pg" directives of pending migrations, reporting every
+// unmet requirement at once.
+func (m *Migrator) checkServerVersion(ctx context.Context, migrations []*validator.MigrationFile) error {
+	var (
+		versionNum int
+		version    string
+	)
+	err := m.db.QueryRowContext(ctx,
+		"SELECT current_setting('server_version_num')::int, current_setting('server_version')",
+	).Scan(&versionNum, &version)
+	if err != nil {
+		return fmt.Errorf("failed to get server version: %w", err)
+	}
+	server := versionFromNum(versionNum)
+
+	var unmet []string
+	require := func(constraint, source string) error {
+		ok, err := satisfiesVersion(server, constraint)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		if !ok {
+			unmet = append(unmet, fmt.Sprintf("%s requires PostgreSQL %s", source, constraint))
+		}
+		return nil
+	}
+
+	if m.minPostgresVersion != "" {
+		if err := require(">="+m.minPostgresVersion, "MinPostgresVersion"); err != nil {
+			return err
+		}
+	}
+	for _, migration := range migrations {
+		for _, directive := range sqlparse.Directives(migration.Content) {
+			if directive.Key != "requires-pg" {
+				continue
+			}
+			source := fmt.Sprintf("%s (line %d)", migration.Name, directive.Line)
+			if err := require(directive.Value, source); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(unmet) > 0 {
+		return &ServerVersionError{ServerVersion: version, Unmet: unmet}
+	}
+
+	m.log.Infof("✓ PostgreSQL %s", version)
+	return nil
+}
+
+// preflight runs the checks every run makes before testing anything:
+// validation of the existing and new migrations, server version and
+// extension requirements, the policy, and the privileges the run needs,
+// including those for a shadow database when shadow is set.
+func (m *Migrator) preflight(ctx context.Context, run *runState, plan *Plan, shadow bool, result *Result) error {
+	if err := m.validateRun(ctx, run, plan, result); err != nil {
+		return err
+	}
+
+	// Newer syntax on an old cluster should fail clearly, not as a syntax error
+	if err := m.checkServerVersion(ctx, run.pending); err != nil {
+		return err
+	}
+
+	if len(run.pending) > 0 {
+		if err := m.checkExtensions(ctx, m.requiredExtensions(run.files), run.pending); err != nil {
+			return err
+		}
+	}
+
+	m.reportImpact(ctx, run.pending, result)
+
+	// Organizational rules can veto the run before anything is tested or applied
+	if err := m.checkPolicy(ctx, run, result); err != nil {
+		return err
+	}
+
+	// Fail with actionable errors instead of halfway through with raw permission errors
+	if !m.skipPrivilegeCheck {
+		if err := m.checkPrivileges(ctx, run.pending, shadow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preApply runs the checks every run makes right before changing the
+// database, once the migrations were tested.
+func (m *Migrator) preApply(ctx context.Context, migrations []*validator.MigrationFile, result *Result) error {
+	m.checkReplicationLag(ctx, migrations, result)
+
+	// Protected environments need an explicit go-ahead
+	if err := m.confirm(ctx, migrations, result); err != nil {
+		return err
+	}
+
+	// Policy may require a fresh backup before any DDL
+	if err := m.backup(ctx, len(migrations)); err != nil {
+		return err
+	}
+
+	// DDL queued behind an idle transaction blocks all traffic on the table
+	return m.checkBlockingQueries(ctx, migrations, result)
+}
@@ -0,0 +1,211 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/sqlparse"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// PrivilegeError is returned when the migration role lacks privileges that
+// pending migrations need. Each problem includes the statement that needs it
+// and the GRANT that fixes it.
+type PrivilegeError struct {
+	Role     string
+	Problems []string
+}
+
+func (e *PrivilegeError) Error() string {
+	return fmt.Sprintf("role %s lacks privileges required by pending migrations:\n  %s",
+		e.Role, strings.Join(e.Problems, "\n  "))
+}
+
+// privilegeKind is the kind of privilege a statement needs.
+type privilegeKind int
+
+const (
+	needSchemaCreate privilegeKind = iota
+	needDatabaseCreate
+	needOwnership
+	needTablePrivilege
+)
+
+// privilegeRequirement is a privilege needed by a pending statement.
+type privilegeRequirement struct {
+	kind      privilegeKind
+	object    string // schema or table, as written (upper-cased by normalization)
+	privilege string // for needTablePrivilege
+	migration string
+	line      int
+}
+
+var (
+	createObjectPattern = regexp.MustCompile(`^CREATE (?:OR REPLACE )?(?:UNLOGGED )?(TABLE|VIEW|MATERIALIZED VIEW|SEQUENCE|FUNCTION|PROCEDURE|TYPE|DOMAIN) (?:IF NOT EXISTS )?([^\s(]+)`)
+	createSchemaPattern = regexp.MustCompile(`^CREATE SCHEMA `)
+	ownedTablePattern   = regexp.MustCompile(`^(?:ALTER TABLE (?:IF EXISTS )?(?:ONLY )?|DROP TABLE (?:IF EXISTS )?|CREATE (?:UNIQUE )?INDEX .*? ON (?:ONLY )?)([^\s(,;]+)`)
+	dmlPattern          = regexp.MustCompile(`^(?:(INSERT) INTO|(UPDATE)(?: ONLY)?|(DELETE) FROM(?: ONLY)?|(TRUNCATE)(?: TABLE)?(?: ONLY)?) ([^\s(,;]+)`)
+)
+
+// privilegeRequirements lists the privileges needed by the statements in migrations.
+func privilegeRequirements(migrations []*validator.MigrationFile) []privilegeRequirement {
+	var reqs []privilegeRequirement
+
+	for _, migration := range migrations {
+		for _, stmt := range sqlparse.Split(migration.Content) {
+			normalized := sqlparse.Normalize(stmt.Text)
+			req := privilegeRequirement{migration: migration.Name, line: stmt.Line}
+
+			switch {
+			case createSchemaPattern.MatchString(normalized):
+				req.kind = needDatabaseCreate
+			case createObjectPattern.MatchString(normalized):
+				name := createObjectPattern.FindStringSubmatch(normalized)[2]
+				req.kind = needSchemaCreate
+				if schema, _, ok := strings.Cut(name, "."); ok {
+					req.object = schema
+				}
+			case ownedTablePattern.MatchString(normalized):
+				req.kind = needOwnership
+				req.object = ownedTablePattern.FindStringSubmatch(normalized)[1]
+			case dmlPattern.MatchString(normalized):
+				match := dmlPattern.FindStringSubmatch(normalized)
+				req.kind = needTablePrivilege
+				req.privilege = match[1] + match[2] + match[3] + match[4]
+				req.object = match[5]
+			default:
+				continue
+			}
+			reqs = append(reqs, req)
+		}
+	}
+
+	return reqs
+}
+
+// CheckPrivileges verifies that the migration role (Options.Role, or the
+// connecting user) has the privileges pending migrations need: CREATE on the
+// target schemas, ownership of altered tables, privileges for data changes,
+// and CREATEDB for the shadow database. Nothing is applied.
+func (m *Migrator) CheckPrivileges(ctx context.Context) error {
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.checkPrivileges(ctx, pending, !m.skipShadowDB)
+}
+
+// checkPrivileges reports every missing privilege at once. Objects that don't
+// exist yet (created earlier in the same batch) can't be checked and are skipped.
+func (m *Migrator) checkPrivileges(ctx context.Context, migrations []*validator.MigrationFile, shadow bool) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	var connectedAs string
+	if err := m.db.QueryRowContext(ctx, "SELECT current_user").Scan(&connectedAs); err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	role := connectedAs
+	if m.role != "" {
+		role = m.role
+	}
+
+	defaultSchema, err := m.defaultSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	check := func(query string, args ...any) (bool, error) {
+		var ok sql.NullBool
+		err := m.db.QueryRowContext(ctx, query, args...).Scan(&ok)
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil
+		}
+		// NULL means the object doesn't exist yet
+		return !ok.Valid || ok.Bool, err
+	}
+
+	for _, req := range privilegeRequirements(migrations) {
+		where := fmt.Sprintf("%s line %d", req.migration, req.line)
+		object := strings.ToLower(req.object)
+
+		var ok bool
+		var problem string
+		switch req.kind {
+		case needDatabaseCreate:
+			ok, err = check("SELECT has_database_privilege($1, current_database(), 'CREATE')", role)
+			problem = fmt.Sprintf("cannot create schemas (%s): GRANT CREATE ON DATABASE <database> TO %s", where, role)
+		case needSchemaCreate:
+			if object == "" {
+				object = defaultSchema
+			}
+			if object == "" {
+				continue
+			}
+			ok, err = check(`SELECT has_schema_privilege($1, n.oid, 'CREATE') FROM pg_namespace n WHERE n.nspname = $2`, role, object)
+			problem = fmt.Sprintf("cannot create objects in schema %s (%s): GRANT CREATE ON SCHEMA %s TO %s", object, where, object, role)
+		case needOwnership:
+			ok, err = check(`SELECT pg_has_role($1, c.relowner, 'USAGE') FROM pg_class c WHERE c.oid = to_regclass($2)`, role, object)
+			problem = fmt.Sprintf("does not own table %s (%s): ALTER TABLE %s OWNER TO %s", object, where, object, role)
+		case needTablePrivilege:
+			ok, err = check(`SELECT has_table_privilege($1, c.oid, $3) FROM pg_class c WHERE c.oid = to_regclass($2)`, role, object, req.privilege)
+			problem = fmt.Sprintf("cannot %s table %s (%s): GRANT %s ON %s TO %s", req.privilege, object, where, req.privilege, object, role)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check privileges for %s: %w", where, err)
+		}
+		if !ok && !seen[problem] {
+			seen[problem] = true
+			problems = append(problems, problem)
+		}
+	}
+
+	// The shadow database is created by the connecting user, not the role
+	if shadow {
+		ok, err := check("SELECT rolcreatedb OR rolsuper FROM pg_roles WHERE rolname = current_user")
+		if err != nil {
+			return fmt.Errorf("failed to check CREATEDB privilege: %w", err)
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("user %s cannot create the shadow database: ALTER ROLE %s CREATEDB (or set SkipShadowDB)",
+				connectedAs, connectedAs))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PrivilegeError{Role: role, Problems: problems}
+	}
+
+	m.log.Infof("✓ Privilege check passed for role %s", role)
+	return nil
+}
+
+// defaultSchema returns the schema unqualified objects are created in:
+// the first entry of Options.SearchPath, or the connection's current schema.
+func (m *Migrator) defaultSchema(ctx context.Context) (string, error) {
+	if m.searchPath != "" {
+		first, _, _ := strings.Cut(m.searchPath, ",")
+		first = strings.TrimSpace(first)
+		if strings.HasPrefix(first, `"`) {
+			return strings.Trim(first, `"`), nil
+		}
+		if strings.HasPrefix(first, "$") {
+			return "", nil
+		}
+		return strings.ToLower(first), nil
+	}
+
+	var schema sql.NullString
+	if err := m.db.QueryRowContext(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+		return "", fmt.Errorf("failed to get current schema: %w", err)
+	}
+	return schema.String, nil
+}
@@ -0,0 +1,62 @@
+package migrator
+
+import (
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+)
+
+// StatementProfile describes how a statement of a pending migration performed
+// on the shadow database.
+type StatementProfile struct {
+	// Migration is the name of the migration containing the statement.
+	Migration string `json:"migration"`
+
+	// Statement is the 1-based position of the statement in the migration.
+	Statement int `json:"statement"`
+
+	// Line is the 1-based line on which the statement starts.
+	Line int `json:"line"`
+
+	// SQL is the statement as written.
+	SQL string `json:"sql"`
+
+	// DurationMS is how long the statement took to execute.
+	DurationMS int64 `json:"duration_ms"`
+
+	// Plan is the EXPLAIN output of INSERT, UPDATE, DELETE, MERGE and WITH
+	// statements. Plans reflect the shadow database's data, not production's.
+	Plan string `json:"plan,omitempty"`
+}
+
+// Duration returns how long the statement took to execute.
+func (p StatementProfile) Duration() time.Duration {
+	return time.Duration(p.DurationMS) * time.Millisecond
+}
+
+// recordShadowProfile adds the statement profiles of a shadow test to the
+// result and reports the slowest statement.
+func (m *Migrator) recordShadowProfile(result *Result, profiles []tracker.StatementProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+
+	slowest := profiles[0]
+	for _, p := range profiles {
+		m.log.Debugf("  ⏱️  %s statement %d (line %d): %s", p.Migration, p.Index+1, p.Line, p.Duration.Round(time.Microsecond))
+		result.ShadowProfile = append(result.ShadowProfile, StatementProfile{
+			Migration:  p.Migration,
+			Statement:  p.Index + 1,
+			Line:       p.Line,
+			SQL:        p.Statement,
+			DurationMS: p.Duration.Milliseconds(),
+			Plan:       p.Plan,
+		})
+		if p.Duration > slowest.Duration {
+			slowest = p
+		}
+	}
+
+	m.log.Infof("⏱️  Profiled %d statements on shadow database, slowest: %s statement %d (%s)",
+		len(profiles), slowest.Migration, slowest.Index+1, slowest.Duration.Round(time.Millisecond))
+}
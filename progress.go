@@ -0,0 +1,69 @@
+package migrator
+
+import "time"
+
+// Phase is a stage of a migration run reported to OnProgress.
+type Phase string
+
+const (
+	// PhasePreflight covers validation and the checks run before any
+	// migration is tested or applied.
+	PhasePreflight Phase = "preflight"
+
+	// PhaseShadowTest is reported before each new migration is tested on the
+	// shadow database.
+	PhaseShadowTest Phase = "shadow_test"
+
+	// PhaseApply is reported before each migration is applied to production.
+	PhaseApply Phase = "apply"
+
+	// PhaseLockQueue is reported while a migration is being applied, each
+	// time the number of sessions waiting on its locks changes. These events
+	// come from a monitoring goroutine, see Options.LockQueueInterval.
+	PhaseLockQueue Phase = "lock_queue"
+
+	// PhaseVerify is reported before post-apply verification queries run.
+	PhaseVerify Phase = "verify"
+
+	// PhaseDone is reported when the run ends, whether it succeeded or not.
+	PhaseDone Phase = "done"
+)
+
+// ProgressEvent reports how far a migration run has got.
+type ProgressEvent struct {
+	Phase Phase
+
+	// Migration is the migration being tested or applied, if any.
+	Migration string
+
+	// Index is the 1-based position of Migration among the Total migrations
+	// of the phase. For PhaseVerify, Total is the number of verification
+	// queries, and for PhaseDone the number of migrations applied.
+	Index int
+	Total int
+
+	// Waiting is the number of sessions waiting for locks the migration holds
+	// or is queued for. Only set for PhaseLockQueue.
+	Waiting int
+
+	// Elapsed is the time since the run started.
+	Elapsed time.Duration
+}
+
+// ProgressFunc receives progress events. It is called synchronously from the
+// run, or for PhaseLockQueue from a goroutine while the run waits, never
+// concurrently, and should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// progress reports a progress event to Options.OnProgress.
+func (m *Migrator) progress(result *Result, event ProgressEvent) {
+	// Lock queue events come from the monitoring goroutine
+	if event.Phase != PhaseLockQueue {
+		result.lastProgress = event
+	}
+	if m.onProgress == nil {
+		return
+	}
+	event.Elapsed = time.Since(result.StartedAt)
+	m.onProgress(event)
+}
@@ -0,0 +1,136 @@
+package migrator
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// MigrationRecord describes a migration that has been applied to the database.
+type MigrationRecord struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	AppliedAt time.Time `json:"applied_at"`
+
+	// Checksum is the SHA-256 of the migration content when it was applied.
+	// Empty for migrations applied before checksums were recorded.
+	Checksum string `json:"checksum,omitempty"`
+
+	// Batch numbers the run that applied the migration; migrations applied
+	// by the same Migrate call share a batch. Zero if unknown.
+	Batch int `json:"batch,omitempty"`
+
+	// DurationMS is how long the migration took to execute, in milliseconds.
+	// Zero for migrations applied before durations were recorded.
+	DurationMS int64 `json:"duration_ms"`
+
+	// AppliedBy is the database role that applied the migration.
+	AppliedBy string `json:"applied_by,omitempty"`
+
+	// Hostname is the host the migrator ran on.
+	Hostname string `json:"hostname,omitempty"`
+
+	// AppVersion is the Options.AppVersion of the migrator that applied the migration.
+	AppVersion string `json:"app_version,omitempty"`
+
+	// Metadata is the front matter the migration declared when it was applied.
+	Metadata MigrationMetadata `json:"metadata"`
+}
+
+// MigrationMetadata is the front matter a migration declares with
+// "-- migrator:description", "author", "ticket" and "risk" directives,
+// recorded in the tracking table when it is applied.
+type MigrationMetadata struct {
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+
+	// Ticket is the issue or pull request the migration belongs to.
+	Ticket string `json:"ticket,omitempty"`
+
+	// Risk is "low", "medium" or "high", or empty if not declared.
+	Risk string `json:"risk,omitempty"`
+}
+
+// String summarizes the metadata on one line, e.g.
+// "Split names (PROJ-12, jane, high risk)". Empty if nothing is declared.
+func (md MigrationMetadata) String() string {
+	var details []string
+	for _, detail := range []string{md.Ticket, md.Author} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if md.Risk != "" {
+		details = append(details, md.Risk+" risk")
+	}
+
+	summary := md.Description
+	if len(details) > 0 {
+		summary = strings.TrimSpace(summary + " (" + strings.Join(details, ", ") + ")")
+	}
+	return summary
+}
+
+// newMigrationMetadata converts tracker metadata into its public representation.
+func newMigrationMetadata(md tracker.Metadata) MigrationMetadata {
+	return MigrationMetadata{
+		Description: md.Description,
+		Author:      md.Author,
+		Ticket:      md.Ticket,
+		Risk:        md.Risk,
+	}
+}
+
+// Duration returns how long the migration took to execute.
+func (r MigrationRecord) Duration() time.Duration {
+	return time.Duration(r.DurationMS) * time.Millisecond
+}
+
+// newMigrationRecord converts a tracker row into its public representation.
+func newMigrationRecord(r tracker.Record) MigrationRecord {
+	return MigrationRecord{
+		Name:       r.Name,
+		Version:    validator.VersionFromName(r.Name),
+		AppliedAt:  r.AppliedAt,
+		Checksum:   r.Checksum,
+		Batch:      r.Batch,
+		DurationMS: r.Duration.Milliseconds(),
+		AppliedBy:  r.AppliedBy,
+		Hostname:   r.Hostname,
+		AppVersion: r.AppVersion,
+		Metadata:   newMigrationMetadata(r.Metadata),
+	}
+}
+
+// BatchRecord describes a run that applied migrations, with the WAL positions
+// around it. To restore a database to just before batch N with point-in-time
+// recovery, use recovery_target_lsn = LSNBefore (with
+// recovery_target_inclusive = off).
+type BatchRecord struct {
+	Batch      int       `json:"batch"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// LSNBefore and LSNAfter are the WAL positions before the first and after
+	// the last migration of the batch. Empty if they couldn't be captured.
+	LSNBefore string `json:"lsn_before,omitempty"`
+	LSNAfter  string `json:"lsn_after,omitempty"`
+
+	// Suspect explains why the batch is suspect, e.g. a failed verification
+	// query after apply. Empty for batches that passed.
+	Suspect string `json:"suspect,omitempty"`
+}
+
+// newBatchRecord converts a tracker batch into its public representation.
+func newBatchRecord(b tracker.Batch) BatchRecord {
+	return BatchRecord{
+		Batch:      b.Batch,
+		StartedAt:  b.StartedAt,
+		FinishedAt: b.FinishedAt,
+		LSNBefore:  b.LSNBefore,
+		LSNAfter:   b.LSNAfter,
+		Suspect:    b.Suspect,
+	}
+}
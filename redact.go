@@ -0,0 +1,11 @@
+package migrator
+
+import "github.com/hasirciogluhq/migrator/internal/dsn"
+
+// Redact returns connString with its password masked, for logging connection
+// strings in URL or keyword/value form:
+//
+//	migrator.Redact("postgres://app:secret@db/app") // postgres://app:xxxxx@db/app
+func Redact(connString string) string {
+	return dsn.Redact(connString)
+}
@@ -0,0 +1,256 @@
+package migrator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/shadowdb"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// recordShadowTests records the outcome of each pending migration's shadow
+// test in result. Migrations the test didn't get to are recorded as skipped
+// with the reason.
+func (m *Migrator) recordShadowTests(result *Result, migrations []*validator.MigrationFile, tested []shadowdb.TestResult, err error) {
+	for _, test := range tested {
+		shadowTest := ShadowTest{Migration: test.Name, DurationMS: test.Duration.Milliseconds()}
+		if test.Err != nil {
+			shadowTest.Error = test.Err.Error()
+		}
+		result.ShadowTests = append(result.ShadowTests, shadowTest)
+	}
+
+	reason := "not tested: an earlier migration failed"
+	var migrationErr *shadowdb.MigrationError
+	if err != nil && !errors.As(err, &migrationErr) {
+		reason = fmt.Sprintf("not tested: %v", err)
+	}
+	for _, migration := range migrations[len(tested):] {
+		result.ShadowTests = append(result.ShadowTests, ShadowTest{Migration: migration.Name, Error: reason, Skipped: true})
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders the shadow tests of the run as JUnit XML, one test case
+// per pending migration, so CI systems show them next to the application's
+// tests:
+//
+//	f, _ := os.Create("migrations-junit.xml")
+//	defer f.Close()
+//	result.WriteJUnit(f)
+func (r *Result) WriteJUnit(w io.Writer) error {
+	seconds := func(d time.Duration) string {
+		return fmt.Sprintf("%.3f", d.Seconds())
+	}
+
+	suite := junitTestSuite{
+		Name:      "shadow database",
+		Timestamp: r.StartedAt.UTC().Format("2006-01-02T15:04:05"),
+		Cases:     []junitTestCase{},
+	}
+	var total time.Duration
+	for _, test := range r.ShadowTests {
+		duration := time.Duration(test.DurationMS) * time.Millisecond
+		total += duration
+
+		testCase := junitTestCase{ClassName: "migrator.shadow", Name: test.Migration, Time: seconds(duration)}
+		switch {
+		case test.Skipped:
+			testCase.Skipped = &junitMessage{Message: test.Error}
+			suite.Skipped++
+		case test.Error != "":
+			message, _, _ := strings.Cut(test.Error, "\n")
+			testCase.Failure = &junitMessage{Message: message, Text: test.Error}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = seconds(total)
+
+	report := junitTestSuites{
+		Name:     "migrator",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int           `json:"startLine"`
+	Snippet   *sarifMessage `json:"snippet,omitempty"`
+}
+
+// WriteSARIF renders lint findings, from Lint or Result.LintFindings, as a
+// SARIF 2.1.0 log, so code-scanning UIs such as GitHub's annotate the
+// offending lines of the migration files in pull requests. File locations
+// are the migrations path joined with the migration name; run from the
+// repository root with a relative MigrationsPath for UIs that resolve them
+// against the repository.
+func (m *Migrator) WriteSARIF(w io.Writer, findings []Finding) error {
+	descriptions := map[string]string{}
+	for _, rule := range m.lintRules {
+		descriptions[rule.Name] = rule.Description
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "migrator",
+			InformationURI: "https://github.com/hasirciogluhq/migrator",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	seen := map[string]bool{}
+	for _, f := range findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			description := descriptions[f.Rule]
+			if description == "" {
+				description = f.Rule
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: f.Rule, ShortDescription: sarifMessage{Text: description}})
+		}
+
+		level := "note"
+		switch f.Severity {
+		case SeverityError:
+			level = "error"
+		case SeverityWarning:
+			level = "warning"
+		}
+
+		uri := filepath.ToSlash(filepath.Join(m.migrationsPath, f.Migration))
+		if filepath.IsAbs(m.migrationsPath) {
+			uri = "file://" + uri
+		}
+		region := sarifRegion{StartLine: max(f.Line, 1)}
+		if f.Statement != "" {
+			region.Snippet = &sarifMessage{Text: f.Statement}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  f.Rule,
+			Level:   level,
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region:           region,
+			}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ErrResetDisabled is returned by Reset unless Options.AllowDestructive and a
+// database name guard are set, or when RequireConfirmation is set.
+var ErrResetDisabled = errors.New("resetting the database requires Options.AllowDestructive and Options.ExpectedDatabase or Options.ExpectedDatabasePattern")
+
+// Reset drops everything the migrations created and applies all migrations
+// again from scratch, for development and test databases.
+//
+// The connection's default schema, which holds the tracking tables, and the
+// schemas of Options.SearchPath are dropped with CASCADE and recreated empty
+// with their owner and privileges; migrations creating one of them should
+// use CREATE SCHEMA IF NOT EXISTS. Other schemas are left alone.
+//
+// It fails with ErrResetDisabled unless Options.AllowDestructive is set and
+// Options.ExpectedDatabase or Options.ExpectedDatabasePattern names the
+// database, and with ErrUnexpectedDatabase when connected to another one. It
+// always fails when RequireConfirmation is set, which marks a protected
+// environment.
+func (m *Migrator) Reset(ctx context.Context) (*Result, error) {
+	if !m.allowDestructive || (m.expectedDatabase == "" && m.expectedDatabasePattern == nil) {
+		return nil, ErrResetDisabled
+	}
+	if m.requireConfirmation {
+		return nil, fmt.Errorf("%w: refusing to reset a database that requires confirmation", ErrResetDisabled)
+	}
+
+	run, release, err := m.pinSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = run.dropSchemas(ctx)
+	release()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.MigrateWithResult(ctx)
+}
+
+// dropSchemas drops and recreates the schemas Reset covers while holding the
+// migration lock.
+func (m *Migrator) dropSchemas(ctx context.Context) error {
+	if err := m.checkDatabaseIdentity(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	schemas, err := m.resetSchemas(ctx)
+	if err != nil {
+		return err
+	}
+
+	var statements []string
+	for _, schema := range schemas {
+		recreate, err := m.recreateSchemaStatements(ctx, schema)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, recreate...)
+	}
+
+	m.log.Infof("🗑️  Dropping schemas %s", strings.Join(schemas, ", "))
+	if err := m.execInTx(ctx, statements); err != nil {
+		return fmt.Errorf("failed to drop schemas: %w", err)
+	}
+
+	return nil
+}
+
+// resetSchemas returns the existing schemas among the connection's default
+// schema and those of Options.SearchPath. System schemas and $user are left
+// out.
+func (m *Migrator) resetSchemas(ctx context.Context) ([]string, error) {
+	var current *string
+	if err := m.db.QueryRowContext(ctx, "SELECT current_schema()").Scan(&current); err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+
+	var names []string
+	if current != nil {
+		names = append(names, *current)
+	}
+	for _, entry := range strings.Split(m.searchPath, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "" || strings.HasPrefix(entry, "$"):
+			continue
+		case strings.HasPrefix(entry, `"`):
+			names = append(names, strings.Trim(entry, `"`))
+		default:
+			names = append(names, strings.ToLower(entry))
+		}
+	}
+
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT nspname FROM pg_namespace
+		WHERE nspname = ANY($1::text[]) AND nspname NOT IN ('pg_catalog', 'information_schema') AND nspname NOT LIKE 'pg\_%'
+		ORDER BY array_position($1::text[], nspname::text)
+	`, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schemas: %w", err)
+	}
+	if len(schemas) == 0 {
+		return nil, errors.New("no schema to reset: the connection has no default schema")
+	}
+
+	return schemas, nil
+}
+
+// recreateSchemaStatements returns the statements dropping schema and
+// creating it again with the same owner and privileges.
+func (m *Migrator) recreateSchemaStatements(ctx context.Context, schema string) ([]string, error) {
+	var owner string
+	if err := m.db.QueryRowContext(ctx, "SELECT nspowner::regrole::text FROM pg_namespace WHERE nspname = $1", schema).Scan(&owner); err != nil {
+		return nil, fmt.Errorf("failed to get owner of schema %s: %w", schema, err)
+	}
+
+	quoted := pq.QuoteIdentifier(schema)
+	statements := []string{
+		"DROP SCHEMA " + quoted + " CASCADE",
+		fmt.Sprintf("CREATE SCHEMA %s AUTHORIZATION %s", quoted, owner),
+	}
+
+	// Grantee 0 is PUBLIC; regrole renders other roles already quoted
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE a.grantee::regrole::text END, a.privilege_type, a.is_grantable
+		FROM pg_namespace n, aclexplode(n.nspacl) a
+		WHERE n.nspname = $1 AND a.grantee <> n.nspowner
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get privileges on schema %s: %w", schema, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grantee, privilege string
+		var grantable bool
+		if err := rows.Scan(&grantee, &privilege, &grantable); err != nil {
+			return nil, fmt.Errorf("failed to scan privilege: %w", err)
+		}
+		grant := fmt.Sprintf("GRANT %s ON SCHEMA %s TO %s", privilege, quoted, grantee)
+		if grantable {
+			grant += " WITH GRANT OPTION"
+		}
+		statements = append(statements, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating privileges: %w", err)
+	}
+
+	return statements, nil
+}
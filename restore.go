@@ -0,0 +1,92 @@
+package migrator
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RestoreFiles writes back applied migrations that are missing from dir, using
+// the content stored in the tracking table (see Options.StoreContent).
+// If dir is empty, the configured migrations path is used.
+//
+// Existing files are never overwritten. It returns the names of the restored
+// files; migrations applied without stored content can't be restored and are
+// reported in the error.
+func (m *Migrator) RestoreFiles(ctx context.Context, dir string) ([]string, error) {
+	if dir == "" {
+		dir = m.migrationsPath
+	}
+
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	applied, err := m.tracker.GetAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	var restored, unrecoverable []string
+	for _, name := range applied {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return restored, fmt.Errorf("failed to check migration file %s: %w", name, err)
+		}
+
+		content, ok, err := m.tracker.GetContent(ctx, name)
+		if err != nil {
+			return restored, err
+		}
+		if !ok {
+			unrecoverable = append(unrecoverable, name)
+			continue
+		}
+
+		if err := writeNewFile(path, []byte(content)); err != nil {
+			return restored, fmt.Errorf("failed to restore migration %s: %w", name, err)
+		}
+		restored = append(restored, name)
+		m.log.Infof("♻️  Restored migration file: %s", name)
+	}
+
+	if len(unrecoverable) > 0 {
+		return restored, fmt.Errorf("%d missing migrations have no stored content and can't be restored: %v",
+			len(unrecoverable), unrecoverable)
+	}
+
+	return restored, nil
+}
+
+// writeNewFile writes data to path, failing if the file already exists.
+// Data written to a .gz path is gzip-compressed.
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		if _, err = gz.Write(data); err == nil {
+			err = gz.Close()
+		}
+	} else {
+		_, err = f.Write(data)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
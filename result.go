@@ -0,0 +1,102 @@
+package migrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/output"
+)
+
+// Result describes what a migration run did.
+type Result struct {
+	// StartedAt is when the run began.
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt is when the run ended.
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Applied lists the migrations applied to production, in order.
+	Applied []AppliedMigration `json:"applied"`
+
+	// Batch is the batch number recorded with the applied migrations.
+	// Zero if nothing was applied.
+	Batch int `json:"batch"`
+
+	// LSNBefore and LSNAfter are the WAL positions around the applied batch,
+	// for point-in-time recovery. Empty if nothing was applied.
+	LSNBefore string `json:"lsn_before,omitempty"`
+	LSNAfter  string `json:"lsn_after,omitempty"`
+
+	// ShadowTested is true when pending migrations were tested on a shadow database.
+	ShadowTested bool `json:"shadow_tested"`
+
+	// ShadowTests lists the outcome of testing each pending migration on the
+	// shadow database, see WriteJUnit. Empty when shadow testing didn't run.
+	ShadowTests []ShadowTest `json:"shadow_tests,omitempty"`
+
+	// ShadowProfile lists the statements of the pending migrations in the
+	// order they ran on the shadow database, with timings and DML plans.
+	// Empty unless ProfileShadow is set.
+	ShadowProfile []StatementProfile `json:"shadow_profile,omitempty"`
+
+	// ShadowRowCounts are the rows affected by the DML statements of the
+	// pending migrations on the shadow database.
+	ShadowRowCounts []RowCount `json:"shadow_row_counts,omitempty"`
+
+	// LintFindings lists the problems found by linting the pending migrations.
+	LintFindings []Finding `json:"lint_findings"`
+
+	// Impacts predicts the locking impact of each pending migration.
+	Impacts []MigrationImpact `json:"impacts,omitempty"`
+
+	// Warnings collects non-fatal problems encountered during the run,
+	// such as skipped shadow testing or failed cleanup.
+	Warnings []string `json:"warnings"`
+
+	log *output.Logger
+
+	// lastProgress is the last progress event of the run's own goroutine,
+	// telling OnError where it failed.
+	lastProgress ProgressEvent
+}
+
+// ShadowTest is the outcome of testing a pending migration on the shadow
+// database.
+type ShadowTest struct {
+	Migration  string `json:"migration"`
+	DurationMS int64  `json:"duration_ms"`
+
+	// Error is the migration's failure, or why it was skipped. Empty if it
+	// passed.
+	Error string `json:"error,omitempty"`
+
+	// Skipped is true for migrations not tested because the shadow database
+	// couldn't be set up or an earlier migration failed.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Duration returns how long the run took.
+func (r *Result) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// AppliedNames returns the names of the applied migrations, in order.
+func (r *Result) AppliedNames() []string {
+	names := make([]string, 0, len(r.Applied))
+	for _, applied := range r.Applied {
+		names = append(names, applied.Name)
+	}
+	return names
+}
+
+// warnf prints a warning and records it in the result.
+func (r *Result) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	r.log.Warnf("%s", msg)
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// Duration returns how long the migration took to apply.
+func (a AppliedMigration) Duration() time.Duration {
+	return time.Duration(a.DurationMS) * time.Millisecond
+}
@@ -0,0 +1,56 @@
+package migrator
+
+import (
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+)
+
+// RowCount is the number of rows affected by a DML statement (INSERT, UPDATE,
+// DELETE, MERGE or WITH) of a migration.
+type RowCount struct {
+	Migration string `json:"migration"`
+
+	// Statement is the 1-based position of the statement in the migration.
+	Statement int `json:"statement"`
+
+	// Line is the 1-based line on which the statement starts.
+	Line int `json:"line"`
+
+	// Command is the statement's leading keyword, e.g. "UPDATE".
+	Command string `json:"command"`
+
+	// Rows is the number of rows the statement affected. For chunked
+	// migrations it totals all batches.
+	Rows int64 `json:"rows"`
+}
+
+func newRowCounts(counts []tracker.RowCount) []RowCount {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	rowCounts := make([]RowCount, len(counts))
+	for i, count := range counts {
+		rowCounts[i] = RowCount{
+			Migration: count.Migration,
+			Statement: count.Index + 1,
+			Line:      count.Line,
+			Command:   count.Command,
+			Rows:      count.Rows,
+		}
+	}
+	return rowCounts
+}
+
+// reportRowCounts logs the rows affected by a migration applied to
+// production. Statements that affected no rows are recorded as warnings: a
+// data migration that changes nothing was often written against the wrong
+// assumptions about the data.
+func (m *Migrator) reportRowCounts(result *Result, counts []RowCount) {
+	for _, count := range counts {
+		if count.Rows == 0 {
+			result.warnf("%s: %s at line %d affected 0 rows", count.Migration, count.Command, count.Line)
+			continue
+		}
+		m.log.Infof("   %s at line %d affected %d rows", count.Command, count.Line, count.Rows)
+	}
+}
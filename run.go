@@ -0,0 +1,119 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// runState is what a migration run learned about the migrations directory
+// and the database, loaded once and shared by its phases.
+type runState struct {
+	// files lists every migration file, in name order. Contents are read
+	// lazily, see validator.LoadAll.
+	files []*validator.MigrationFile
+
+	// records are the applied migrations, in apply order.
+	records []tracker.Record
+
+	// legacy are the files applied by the legacy tool and not in records, in
+	// name order.
+	legacy []string
+
+	// applied holds the names in records and legacy.
+	applied map[string]bool
+
+	// skipped holds the names of migrations recorded as never to be applied
+	// to this database, see Migrator.Skip.
+	skipped map[string]bool
+
+	// pending are the files neither applied nor skipped, in name order.
+	pending []*validator.MigrationFile
+}
+
+// loadRunState lists the migration files and fetches the applied migrations.
+func (m *Migrator) loadRunState(ctx context.Context) (*runState, error) {
+	files, err := m.validator.ListMigrationFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	records, err := m.tracker.GetAppliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.Name] = true
+	}
+
+	legacy, err := m.legacyApplied(ctx, files, applied)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range legacy {
+		applied[name] = true
+	}
+	if len(legacy) > 0 {
+		m.log.Infof("✓ Treating %d migrations recorded by the legacy tool as applied", len(legacy))
+	}
+
+	skips, err := m.tracker.GetSkipped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	skipped := make(map[string]bool, len(skips))
+	done := make(map[string]bool, len(applied)+len(skips))
+	for name := range applied {
+		done[name] = true
+	}
+	for _, skip := range skips {
+		skipped[skip.Name] = true
+		done[skip.Name] = true
+	}
+	if len(skips) > 0 {
+		m.log.Infof("✓ Leaving %d skipped migrations unapplied", len(skips))
+	}
+
+	return &runState{
+		files:   files,
+		records: records,
+		legacy:  legacy,
+		applied: applied,
+		skipped: skipped,
+		pending: validator.NewMigrations(files, done),
+	}, nil
+}
+
+// loadExistingRunState is loadRunState for read-only checks, which must not
+// create the migrations table: without it, every file is pending.
+func (m *Migrator) loadExistingRunState(ctx context.Context) (*runState, error) {
+	exists, err := m.tracker.TableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return m.loadRunState(ctx)
+	}
+
+	files, err := m.validator.ListMigrationFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration files: %w", err)
+	}
+	return &runState{files: files, applied: map[string]bool{}, skipped: map[string]bool{}, pending: files}, nil
+}
+
+// appliedNames returns the names of the applied migrations, in apply order.
+// Legacy migrations come first, since they were applied before the migrator
+// took over.
+func (s *runState) appliedNames() []string {
+	names := make([]string, 0, len(s.legacy)+len(s.records))
+	names = append(names, s.legacy...)
+	for _, record := range s.records {
+		names = append(names, record.Name)
+	}
+	return names
+}
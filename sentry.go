@@ -0,0 +1,126 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// SentryNotifier reports failed migration runs to Sentry as error events.
+//
+// It talks to Sentry's store endpoint directly using the project DSN, so no
+// Sentry SDK is required. Successful runs are not reported.
+type SentryNotifier struct {
+	// DSN is the Sentry project DSN (https://<key>@<host>/<project>).
+	DSN string
+
+	// Environment is attached to events (e.g. "production"). Optional.
+	Environment string
+
+	// Client is the HTTP client used for delivery. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// NewSentryNotifier creates a SentryNotifier for the given DSN.
+func NewSentryNotifier(dsn string) *SentryNotifier {
+	return &SentryNotifier{DSN: dsn}
+}
+
+// Notify implements Notifier.
+func (s *SentryNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != EventRunCompleted || event.Error == "" {
+		return nil
+	}
+
+	storeURL, authHeader, err := parseSentryDSN(s.DSN)
+	if err != nil {
+		return err
+	}
+
+	eventID := make([]byte, 16)
+	if _, err := rand.Read(eventID); err != nil {
+		return fmt.Errorf("failed to generate sentry event id: %w", err)
+	}
+
+	tags := map[string]string{"component": "migrator"}
+	if event.Migration != "" {
+		tags["migration"] = event.Migration
+	}
+	hostname, _ := os.Hostname()
+
+	payload := map[string]interface{}{
+		"event_id":    hex.EncodeToString(eventID),
+		"timestamp":   event.Time.UTC().Format(time.RFC3339),
+		"level":       "error",
+		"logger":      "migrator",
+		"platform":    "go",
+		"server_name": hostname,
+		"message":     map[string]string{"formatted": "Migration run failed: " + event.Error},
+		"tags":        tags,
+		"extra": map[string]interface{}{
+			"migration":   event.Migration,
+			"sql":         event.SQL,
+			"applied":     event.Applied,
+			"duration_ms": event.DurationMS,
+		},
+	}
+	if s.Environment != "" {
+		payload["environment"] = s.Environment
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode sentry event: %w", err)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return postWithRetry(ctx, client, 3, time.Second, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, storeURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Sentry-Auth", authHeader)
+		return req, nil
+	})
+}
+
+// parseSentryDSN returns the store endpoint and auth header for a Sentry DSN.
+func parseSentryDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid sentry DSN")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	projectID := path[idx+1:]
+	if projectID == "" {
+		return "", "", fmt.Errorf("invalid sentry DSN: missing project id")
+	}
+
+	prefix := ""
+	if idx != -1 {
+		prefix = "/" + path[:idx]
+	}
+
+	storeURL := fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, projectID)
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=migrator/1.0, sentry_key=%s", u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	return storeURL, auth, nil
+}
@@ -0,0 +1,61 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// connector is implemented by *sql.DB, which can hand out dedicated connections.
+type connector interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// pinSession returns a Migrator whose database operations all run on a single
+// dedicated connection, so session state (advisory locks, SET ROLE,
+// search_path) survives between statements. The returned release function
+// returns the connection to the pool.
+//
+// Handles that can't hand out connections (a *sql.Conn, custom wrappers) are
+// already a single session or manage sessions themselves and are used as is.
+func (m *Migrator) pinSession(ctx context.Context) (*Migrator, func(), error) {
+	pool, ok := m.db.(connector)
+	if !ok {
+		return m, func() {}, nil
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire database connection: %w", err)
+	}
+
+	// Identify the session in pg_stat_activity while it migrates
+	tagged := false
+	if m.applicationName != "" {
+		if _, err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", m.applicationName); err != nil {
+			m.log.Warnf("Failed to set application_name: %v", err)
+		} else {
+			tagged = true
+		}
+	}
+
+	pinned := *m
+	pinned.db = conn
+	pinned.monitorDB = m.db
+	pinned.tracker = m.tracker.WithDB(conn)
+	pinned.validator = m.validator.WithTracker(pinned.tracker)
+
+	release := func() {
+		if tagged {
+			// The connection goes back to the pool; restore the name it was opened with
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), "RESET application_name"); err != nil {
+				m.log.Warnf("Failed to reset application_name: %v", err)
+			}
+		}
+		if err := conn.Close(); err != nil {
+			m.log.Warnf("Failed to release database connection: %v", err)
+		}
+	}
+
+	return &pinned, release, nil
+}
@@ -0,0 +1,128 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// TestShadow tests the pending migrations on a shadow database, like the
+// shadow testing step of Migrate, and returns the result of the test: the
+// outcome of each migration in ShadowTests, and with ProfileShadow the
+// statement profile. It is meant for a CI job separate from the deployment
+// applying the migrations.
+//
+// The migrated database is only read: nothing is applied and the migrations
+// table is never created; without it, every file is pending. The migration
+// lock is held during the test, since runs share the shadow database name.
+//
+// It fails when no shadow database can be used: without Options.DatabaseURL
+// or with SkipShadowDB.
+func (m *Migrator) TestShadow(ctx context.Context) (*Result, error) {
+	result := &Result{
+		StartedAt:    time.Now(),
+		Applied:      []AppliedMigration{},
+		LintFindings: []Finding{},
+		Warnings:     []string{},
+		log:          m.log,
+	}
+
+	var err error
+	switch {
+	case m.skipShadowDB:
+		err = errors.New("shadow testing is disabled by SkipShadowDB")
+	case m.shadowManager == nil:
+		err = errors.New("shadow testing requires Options.DatabaseURL")
+	default:
+		err = interrupted(ctx, catchPanic(func() error {
+			return m.testShadowPinned(ctx, result)
+		}))
+		m.reportError(ctx, result, err)
+	}
+	result.FinishedAt = time.Now()
+
+	return result, err
+}
+
+// testShadowPinned runs TestShadow on a single dedicated session, after any
+// other run of the Migrator finished.
+func (m *Migrator) testShadowPinned(ctx context.Context, result *Result) error {
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+
+	run, release, err := m.pinSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	m.progress(result, ProgressEvent{Phase: PhasePreflight})
+	if err := run.checkDatabaseIdentity(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := run.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := run.loadExistingRunState(ctx)
+	if err != nil {
+		return err
+	}
+	if err := validator.LoadAll(ctx, state.files); err != nil {
+		return fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	return run.testShadow(ctx, state, result)
+}
+
+// testShadow tests the pending migrations of run on the shadow database,
+// recording the outcome in result. The shadow database is dropped before it
+// returns, also when the test fails or is interrupted.
+func (m *Migrator) testShadow(ctx context.Context, run *runState, result *Result) error {
+	newMigrations := run.pending
+	switch {
+	case len(newMigrations) == 0:
+		m.log.Infof("✓ No new migrations found, skipping shadow database test")
+		return nil
+	case m.skipShadowDB:
+		m.log.Warnf("Shadow database testing disabled by SkipShadowDB")
+		return nil
+	case m.shadowManager == nil:
+		result.warnf("DATABASE_URL not provided, skipping shadow database test")
+		m.log.Infof("   To enable shadow database testing, provide DatabaseURL in Options or set DATABASE_URL env var")
+		return nil
+	}
+
+	m.shadowManager.SetExtensions(m.shadowExtensions(run.files))
+	m.shadowManager.SetProfile(m.profileShadow)
+	m.shadowManager.SetOnTest(func(migration string, index, total int) {
+		m.progress(result, ProgressEvent{Phase: PhaseShadowTest, Migration: migration, Index: index, Total: total})
+	})
+
+	// It runs before the lock is released, since all runs share the shadow
+	// database name
+	defer m.cleanupShadow(ctx, result)
+
+	// A panicking hook is recorded as a failed shadow test
+	err := catchPanic(func() error {
+		return m.shadowManager.TestNewMigrations(ctx, m.tracker, run.appliedNames(), run.files, newMigrations)
+	})
+	m.recordShadowTests(result, newMigrations, m.shadowManager.Results(), err)
+	m.recordShadowProfile(result, m.shadowManager.Profiles())
+	result.ShadowRowCounts = newRowCounts(m.shadowManager.RowCounts())
+	if err != nil {
+		err = shadowFailure(err, newMigrations)
+		failed := Event{Type: EventShadowTestFailed, Error: err.Error()}
+		failureDetails(&failed, err)
+		m.notify(ctx, failed)
+		return fmt.Errorf("shadow database test failed: %w", err)
+	}
+	result.ShadowTested = true
+	return nil
+}
@@ -0,0 +1,102 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ErrInterrupted is returned when a run stops because its context was
+// cancelled, e.g. by SIGTERM during a node drain. Migrations applied before
+// the interruption stay applied and are recorded; the interrupted migration
+// was either committed or rolled back as a whole; the rest remain pending.
+var ErrInterrupted = errors.New("migration run interrupted")
+
+// Exit codes returned by ExitCode.
+const (
+	// ExitOK means the run succeeded.
+	ExitOK = 0
+
+	// ExitFailure means the run failed.
+	ExitFailure = 1
+
+	// ExitInterrupted means the run was interrupted and shut down cleanly:
+	// the database is consistent and a later run continues where it stopped.
+	ExitInterrupted = 3
+
+	// ExitPending means CheckPending found migrations to apply.
+	ExitPending = 4
+
+	// ExitDrift means CheckPending found applied migrations that are missing
+	// or modified, or pending migrations out of order.
+	ExitDrift = 5
+)
+
+// ExitCode maps the error of a migration run to a process exit code, so
+// orchestrators can tell a clean interruption from a failure, and CI gates
+// running CheckPending can tell pending migrations from drift.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrInterrupted):
+		return ExitInterrupted
+	case errors.Is(err, ErrDrift):
+		return ExitDrift
+	case errors.Is(err, ErrPendingMigrations):
+		return ExitPending
+	default:
+		return ExitFailure
+	}
+}
+
+// SignalContext returns a context cancelled on SIGINT or SIGTERM, for
+// running migrations from a command or a Kubernetes job:
+//
+//	ctx, stop := migrator.SignalContext(context.Background())
+//	defer stop()
+//	err := m.Migrate(ctx)
+//	os.Exit(migrator.ExitCode(err))
+//
+// Calling stop restores the default signal behavior.
+func SignalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
+
+// withGracePeriod returns a context for applying a migration that outlives
+// the cancellation of ctx by ShutdownGracePeriod, so an interrupted run can
+// commit the migration in flight instead of rolling it back. The returned
+// function releases its resources.
+func (m *Migrator) withGracePeriod(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.shutdownGracePeriod <= 0 {
+		return ctx, func() {}
+	}
+
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		m.log.Infof("⏳ Interrupted, waiting up to %s for the current migration to finish...", m.shutdownGracePeriod)
+		select {
+		case <-time.After(m.shutdownGracePeriod):
+			cancel()
+		case <-detached.Done():
+		}
+	})
+
+	return detached, func() {
+		stop()
+		cancel()
+	}
+}
+
+// interrupted marks err as an interruption when the caller's context was
+// cancelled.
+func interrupted(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ErrInterrupted) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrInterrupted, err)
+}
@@ -0,0 +1,118 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// SkippedMigration describes a migration recorded as never to be applied to
+// the database, see Migrator.Skip.
+type SkippedMigration struct {
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	SkippedAt time.Time `json:"skipped_at"`
+
+	// SkippedBy is the database role that recorded the skip.
+	SkippedBy string `json:"skipped_by,omitempty"`
+
+	// Hostname is the host the migrator ran on.
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// newSkippedMigration converts a tracker skip into its public representation.
+func newSkippedMigration(s tracker.Skip) SkippedMigration {
+	return SkippedMigration{
+		Name:      s.Name,
+		Reason:    s.Reason,
+		SkippedAt: s.SkippedAt,
+		SkippedBy: s.SkippedBy,
+		Hostname:  s.Hostname,
+	}
+}
+
+// Skip records a pending migration as never to be applied to this database,
+// e.g. a PostGIS migration in a region without the extension. name is the
+// migration's file name, with or without extension, and reason is required.
+//
+// Skipped migrations are neither pending nor applied: Migrate, IsUpToDate and
+// GetPendingMigrations ignore them, the shadow database doesn't replay them,
+// and Status lists them with their reason. Unskip makes one pending again.
+func (m *Migrator) Skip(ctx context.Context, name, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("skipping migration %s requires a reason", name)
+	}
+
+	run, release, err := m.pinSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return run.skip(ctx, name, reason)
+}
+
+// skip records a skipped migration while holding the migration lock.
+func (m *Migrator) skip(ctx context.Context, name, reason string) error {
+	if err := m.checkDatabaseIdentity(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	run, err := m.loadRunState(ctx)
+	if err != nil {
+		return err
+	}
+
+	migration, err := m.pendingFile(run, name)
+	if err != nil {
+		return err
+	}
+
+	if err := m.tracker.RecordSkip(ctx, migration.Name, reason); err != nil {
+		return err
+	}
+
+	m.log.Infof("✓ Skipped migration %s: %s", migration.Name, reason)
+	return nil
+}
+
+// Unskip removes the skip of a migration recorded with Skip, so the next
+// Migrate applies it. name is the migration's file name, with or without
+// extension.
+func (m *Migrator) Unskip(ctx context.Context, name string) error {
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	skips, err := m.tracker.GetSkipped(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, skip := range skips {
+		if skip.Name != name && validator.TrimExtension(skip.Name) != name {
+			continue
+		}
+		if _, err := m.tracker.RemoveSkip(ctx, skip.Name); err != nil {
+			return err
+		}
+		m.log.Infof("✓ Migration %s is pending again", skip.Name)
+		return nil
+	}
+
+	return fmt.Errorf("migration %s is not skipped", name)
+}
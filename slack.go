@@ -0,0 +1,103 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackNotifier posts a summary of each migration run to a Slack incoming webhook.
+//
+// Only EventRunCompleted is posted: the message lists the applied migrations with
+// their durations and, on failure, the error and the offending SQL snippet.
+type SlackNotifier struct {
+	// WebhookURL is the Slack incoming webhook URL.
+	WebhookURL string
+
+	// Channel overrides the webhook's default channel. Optional.
+	Channel string
+
+	// Username overrides the webhook's default bot name. Optional.
+	Username string
+
+	// NotifyEmptyRuns also posts runs that applied nothing. Off by default,
+	// since most deploys have no pending migrations.
+	NotifyEmptyRuns bool
+
+	// Client is the HTTP client used for delivery. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// NewSlackNotifier creates a SlackNotifier posting to the given incoming webhook URL.
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{WebhookURL: webhookURL}
+}
+
+// Notify implements Notifier.
+func (s *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != EventRunCompleted {
+		return nil
+	}
+	if event.Error == "" && len(event.Applied) == 0 && !s.NotifyEmptyRuns {
+		return nil
+	}
+
+	payload := map[string]string{"text": slackMessage(event)}
+	if s.Channel != "" {
+		payload["channel"] = s.Channel
+	}
+	if s.Username != "" {
+		payload["username"] = s.Username
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode slack message: %w", err)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return postWithRetry(ctx, client, 3, time.Second, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// slackMessage renders a run summary in Slack mrkdwn.
+func slackMessage(event Event) string {
+	var b strings.Builder
+
+	duration := time.Duration(event.DurationMS) * time.Millisecond
+	if event.Error != "" {
+		fmt.Fprintf(&b, ":x: *Migration run failed* after %s\n", duration)
+	} else {
+		fmt.Fprintf(&b, ":white_check_mark: *Migration run completed* in %s, %d applied\n", duration, len(event.Applied))
+	}
+
+	for _, applied := range event.Applied {
+		fmt.Fprintf(&b, "• `%s` (%s)\n", applied.Name, time.Duration(applied.DurationMS)*time.Millisecond)
+	}
+
+	if event.Error != "" {
+		if event.Migration != "" {
+			fmt.Fprintf(&b, "Failed migration: `%s`\n", event.Migration)
+		}
+		fmt.Fprintf(&b, "Error: %s\n", event.Error)
+		if event.SQL != "" {
+			fmt.Fprintf(&b, "```%s```\n", strings.ReplaceAll(event.SQL, "```", "'''"))
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,215 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// Status is a point-in-time report of the migration state of a database
+// compared to the migration files on disk.
+type Status struct {
+	// GeneratedAt is when the report was produced.
+	GeneratedAt time.Time `json:"generated_at"`
+
+	// UpToDate is true when there is nothing pending and nothing drifted.
+	UpToDate bool `json:"up_to_date"`
+
+	// Applied lists the applied migrations in apply order.
+	Applied []MigrationRecord `json:"applied"`
+
+	// Pending lists migration files that haven't been applied yet.
+	Pending []string `json:"pending"`
+
+	// Legacy lists migration files applied by the tool configured in
+	// Options.LegacyTracking and not recorded by the migrator.
+	Legacy []string `json:"legacy"`
+
+	// Skipped lists migrations recorded as never to be applied to this
+	// database, see Migrator.Skip.
+	Skipped []SkippedMigration `json:"skipped"`
+
+	// Missing lists applied migrations whose files no longer exist on disk.
+	Missing []string `json:"missing"`
+
+	// ChecksumMismatches lists applied migrations whose files changed after
+	// they were applied.
+	ChecksumMismatches []ChecksumMismatch `json:"checksum_mismatches"`
+
+	// InvalidObjects lists INVALID indexes and NOT VALID constraints, see
+	// Migrator.InvalidObjects. They don't affect UpToDate.
+	InvalidObjects []InvalidObject `json:"invalid_objects"`
+}
+
+// ChecksumMismatch describes an applied migration whose file content no longer
+// matches the checksum recorded when it was applied.
+type ChecksumMismatch struct {
+	Name     string `json:"name"`
+	Recorded string `json:"recorded"`
+	Current  string `json:"current"`
+}
+
+// Status compares the database with the migration files and reports applied,
+// pending, missing and modified migrations, along with invalid indexes and
+// constraints left by failed concurrent operations.
+//
+// It only reads from the database and never creates the tracking table.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	migrationFiles, err := m.validator.GetMigrationFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	status := &Status{
+		GeneratedAt:        time.Now(),
+		Applied:            []MigrationRecord{},
+		Pending:            []string{},
+		Legacy:             []string{},
+		Skipped:            []SkippedMigration{},
+		Missing:            []string{},
+		ChecksumMismatches: []ChecksumMismatch{},
+	}
+
+	exists, err := m.tracker.TableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[string]bool{}
+	if exists {
+		records, err := m.tracker.GetAppliedRecords(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		files := make(map[string]bool, len(migrationFiles))
+		for _, migration := range migrationFiles {
+			files[migration.Name] = true
+		}
+
+		for _, record := range records {
+			applied[record.Name] = true
+			status.Applied = append(status.Applied, newMigrationRecord(record))
+			if !files[record.Name] {
+				status.Missing = append(status.Missing, record.Name)
+			}
+		}
+		status.ChecksumMismatches = checksumMismatches(records, migrationFiles)
+	}
+
+	legacy, err := m.legacyApplied(ctx, migrationFiles, applied)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range legacy {
+		applied[name] = true
+	}
+	status.Legacy = append(status.Legacy, legacy...)
+
+	skips, err := m.tracker.GetSkipped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, skip := range skips {
+		applied[skip.Name] = true
+		status.Skipped = append(status.Skipped, newSkippedMigration(skip))
+	}
+
+	for _, migration := range validator.NewMigrations(migrationFiles, applied) {
+		status.Pending = append(status.Pending, migration.Name)
+	}
+
+	status.InvalidObjects, err = m.InvalidObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status.UpToDate = len(status.Pending) == 0 && len(status.Missing) == 0 &&
+		len(status.ChecksumMismatches) == 0
+
+	return status, nil
+}
+
+// WriteText renders the status as a human-readable table.
+func (s *Status) WriteText(w io.Writer) error {
+	type row struct {
+		name, state, appliedAt, duration, description string
+	}
+
+	modified := make(map[string]bool, len(s.ChecksumMismatches))
+	for _, mismatch := range s.ChecksumMismatches {
+		modified[mismatch.Name] = true
+	}
+	missing := make(map[string]bool, len(s.Missing))
+	for _, name := range s.Missing {
+		missing[name] = true
+	}
+
+	var rows []row
+	for _, record := range s.Applied {
+		state := "applied"
+		switch {
+		case missing[record.Name]:
+			state = "MISSING"
+		case modified[record.Name]:
+			state = "MODIFIED"
+		}
+		rows = append(rows, row{
+			name:        record.Name,
+			state:       state,
+			appliedAt:   record.AppliedAt.Format("2006-01-02 15:04:05"),
+			duration:    record.Duration().String(),
+			description: record.Metadata.String(),
+		})
+	}
+	for _, name := range s.Legacy {
+		rows = append(rows, row{name: name, state: "legacy", appliedAt: "-", duration: "-"})
+	}
+	for _, skip := range s.Skipped {
+		rows = append(rows, row{name: skip.Name, state: "skipped: " + skip.Reason, appliedAt: "-", duration: "-"})
+	}
+	for _, name := range s.Pending {
+		rows = append(rows, row{name: name, state: "pending", appliedAt: "-", duration: "-"})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].name < rows[j].name })
+
+	fmt.Fprintf(w, "Migration status at %s\n", s.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Applied: %d  Pending: %d  Missing: %d  Modified: %d",
+		len(s.Applied), len(s.Pending), len(s.Missing), len(s.ChecksumMismatches))
+	if len(s.Legacy) > 0 {
+		fmt.Fprintf(w, "  Legacy: %d", len(s.Legacy))
+	}
+	if len(s.Skipped) > 0 {
+		fmt.Fprintf(w, "  Skipped: %d", len(s.Skipped))
+	}
+	if len(s.InvalidObjects) > 0 {
+		fmt.Fprintf(w, "  Invalid: %d", len(s.InvalidObjects))
+	}
+	fmt.Fprint(w, "\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MIGRATION\tSTATUS\tAPPLIED AT\tDURATION\tDESCRIPTION")
+	for _, r := range rows {
+		if r.description == "" {
+			r.description = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.name, r.state, r.appliedAt, r.duration, r.description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(s.InvalidObjects) > 0 {
+		fmt.Fprint(w, "\nInvalid objects (drop and recreate, or VALIDATE CONSTRAINT):\n")
+		for _, object := range s.InvalidObjects {
+			fmt.Fprintf(w, "  %s: %s\n", object, object.Definition)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// SyntaxChecker parses migration SQL offline and returns an error describing
+// the first syntax error, or nil if the SQL is valid.
+//
+// If the returned error has a Position() int method reporting the 1-based
+// character offset of the problem, the failing line is included in reports.
+// See the pgquery subpackage for a checker built on the PostgreSQL parser.
+type SyntaxChecker func(sql string) error
+
+// SyntaxError reports a migration rejected by the configured SyntaxChecker.
+type SyntaxError struct {
+	// Migration is the file containing the error.
+	Migration string
+
+	// Line is the 1-based line of the error, or 0 if unknown.
+	Line int
+
+	// Err is the error returned by the checker.
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("syntax error in %s at line %d: %v", e.Migration, e.Line, e.Err)
+	}
+	return fmt.Sprintf("syntax error in %s: %v", e.Migration, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// CheckSyntax runs the configured SyntaxChecker over pending migrations without
+// connecting to a shadow database or applying anything. It returns nil when no
+// checker is configured.
+func (m *Migrator) CheckSyntax(ctx context.Context) error {
+	if m.syntaxChecker == nil {
+		return nil
+	}
+
+	pending, err := m.pendingMigrationFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	return checkSyntax(m.syntaxChecker, pending)
+}
+
+// checkSyntax returns an error for each migration the checker rejects,
+// joined with errors.Join: a *migrationError, carrying the migration's content
+// for failure reports, that wraps a *SyntaxError.
+func checkSyntax(checker SyntaxChecker, migrations []*validator.MigrationFile) error {
+	var errs []error
+	for _, migration := range migrations {
+		err := checker(migration.Content)
+		if err == nil {
+			continue
+		}
+
+		syntaxErr := &SyntaxError{Migration: migration.Name, Err: err}
+		var posErr interface{ Position() int }
+		if errors.As(err, &posErr) && posErr.Position() > 0 {
+			runes := []rune(migration.Content)
+			if pos := posErr.Position(); pos <= len(runes) {
+				syntaxErr.Line = strings.Count(string(runes[:pos-1]), "\n") + 1
+			}
+		}
+
+		errs = append(errs, &migrationError{name: migration.Name, content: migration.Content, err: syntaxErr})
+	}
+
+	return errors.Join(errs...)
+}
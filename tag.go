@@ -0,0 +1,148 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+)
+
+// ErrTagExists is returned by Migrator.Tag for a tag name already in use.
+var ErrTagExists = tracker.ErrTagExists
+
+// ErrTagNotFound is returned for a tag that was never recorded.
+var ErrTagNotFound = errors.New("tag not found")
+
+// Tag is a named marker in the migration history, typically an application
+// release, recorded with Migrator.Tag.
+type Tag struct {
+	Name string `json:"name"`
+
+	// Head is the last applied migration when the tag was recorded, empty if
+	// none was applied.
+	Head string `json:"head,omitempty"`
+
+	// Batch is the latest batch when the tag was recorded, zero if none.
+	Batch int `json:"batch,omitempty"`
+
+	// LSN is the WAL position when the tag was recorded, empty if it couldn't
+	// be captured.
+	LSN string `json:"lsn,omitempty"`
+
+	TaggedAt time.Time `json:"tagged_at"`
+
+	// TaggedBy is the database role that recorded the tag.
+	TaggedBy string `json:"tagged_by,omitempty"`
+
+	// Hostname is the host the migrator ran on.
+	Hostname string `json:"hostname,omitempty"`
+
+	// AppVersion is the Options.AppVersion of the migrator that recorded the tag.
+	AppVersion string `json:"app_version,omitempty"`
+}
+
+// newTag converts a tracker tag into its public representation.
+func newTag(t tracker.Tag) Tag {
+	return Tag{
+		Name:       t.Name,
+		Head:       t.Head,
+		Batch:      t.Batch,
+		LSN:        t.LSN,
+		TaggedAt:   t.TaggedAt,
+		TaggedBy:   t.TaggedBy,
+		Hostname:   t.Hostname,
+		AppVersion: t.AppVersion,
+	}
+}
+
+// Tag records a named marker, e.g. "v2.3.0", at the current head of the
+// migration history, tying the schema to an application release. Tags are
+// immutable; reusing a name fails with ErrTagExists.
+func (m *Migrator) Tag(ctx context.Context, name string) (*Tag, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("tag name is required")
+	}
+
+	if err := m.tracker.EnsureMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure migrations table: %w", err)
+	}
+
+	lsn, err := m.tracker.CurrentLSN(ctx)
+	if err != nil {
+		m.log.Warnf("%v", err)
+	}
+
+	recorded, err := m.tracker.RecordTag(ctx, name, lsn)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := newTag(recorded)
+	head := tag.Head
+	if head == "" {
+		head = "an empty history"
+	}
+	m.log.Infof("📍 Tagged %s at %s", tag.Name, head)
+	return &tag, nil
+}
+
+// GetTags returns the recorded tags, oldest first.
+func (m *Migrator) GetTags(ctx context.Context) ([]Tag, error) {
+	recorded, err := m.tracker.GetTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]Tag, 0, len(recorded))
+	for _, tag := range recorded {
+		tags = append(tags, newTag(tag))
+	}
+	return tags, nil
+}
+
+// TagRollback describes what rolling the schema back to a tag involves.
+type TagRollback struct {
+	Tag Tag `json:"tag"`
+
+	// Migrations are the migrations applied after the tag, newest first: the
+	// changes a rollback has to undo.
+	Migrations []MigrationRecord `json:"migrations"`
+}
+
+// PlanRollbackToTag reports the migrations applied since a tag was recorded.
+// It doesn't change the database: migrations are forward-only, so a rollback
+// is either a point-in-time recovery with recovery_target_lsn set to the
+// tag's LSN, or new migrations that revert the listed ones. It fails with
+// ErrTagNotFound for unknown tags.
+func (m *Migrator) PlanRollbackToTag(ctx context.Context, name string) (*TagRollback, error) {
+	tags, err := m.tracker.GetTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *TagRollback
+	for _, tag := range tags {
+		if tag.Name == name {
+			plan = &TagRollback{Tag: newTag(tag), Migrations: []MigrationRecord{}}
+			break
+		}
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTagNotFound, name)
+	}
+
+	records, err := m.tracker.GetAppliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are in apply order; everything after the head came later
+	for i := len(records) - 1; i >= 0 && records[i].Name != plan.Tag.Head; i-- {
+		plan.Migrations = append(plan.Migrations, newMigrationRecord(records[i]))
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,31 @@
+package migrator
+
+import "time"
+
+// Timeouts bounds each phase of a run, so they can be tuned to the workload
+// and cluster size. Zero fields keep the defaults below.
+type Timeouts struct {
+	// ShadowSetup bounds creating the shadow database and replaying the
+	// applied migrations on it, which grows with the migration history. No
+	// limit by default or when negative.
+	ShadowSetup time.Duration
+
+	// ShadowTest bounds testing the pending migrations on the shadow
+	// database. No limit by default or when negative.
+	ShadowTest time.Duration
+
+	// PerMigration bounds applying a single migration to production,
+	// replacing Options.MigrationTimeout. Defaults to 5 minutes; a negative
+	// value disables the limit. Chunked migrations are bounded per batch
+	// instead.
+	PerMigration time.Duration
+
+	// Cleanup bounds dropping the shadow database after a test, which
+	// proceeds even when the run was cancelled. Defaults to 30 seconds.
+	Cleanup time.Duration
+
+	// LockWait is how long a run waits for a concurrent run holding the
+	// migration lock, replacing Options.LockTimeout. Defaults to 5 minutes;
+	// a negative value fails immediately.
+	LockWait time.Duration
+}
@@ -0,0 +1,137 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// Validate checks the migration files against the database without testing
+// or applying anything, for CI jobs validating a branch cheaply: applied
+// migrations missing from the directory or modified since (checksums are
+// always compared), out-of-order migrations with DisallowOutOfOrder, and the
+// naming, directives, lint rules and, with SyntaxChecker, syntax of pending
+// migrations. No shadow database is needed.
+//
+// It returns the lint findings of the pending migrations, and an error
+// joining every problem found, or nil. Like Status, it never creates the
+// migrations table; without it, every file is pending.
+func (m *Migrator) Validate(ctx context.Context) ([]Finding, error) {
+	m.log.Infof("🔍 Validating migrations against the database...")
+
+	run, err := m.loadExistingRunState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	check := *m
+	check.verifyChecksumsEnabled = true
+	result := &Result{LintFindings: []Finding{}, Warnings: []string{}, log: m.log}
+	if err := check.validateRun(ctx, run, nil, result); err != nil {
+		return result.LintFindings, err
+	}
+
+	m.log.Infof("✅ %d pending migrations are valid", len(run.pending))
+	return result.LintFindings, nil
+}
+
+// validateRun checks the migration files against the database and each
+// other before anything is tested or applied. Rather than stopping at the
+// first problem, it returns all of them joined with errors.Join, so a large
+// repository can be fixed in one go: missing files, checksum mismatches,
+// out-of-order and misnamed migrations, invalid directives, lint errors and
+// syntax errors.
+func (m *Migrator) validateRun(ctx context.Context, run *runState, plan *Plan, result *Result) error {
+	var errs []error
+
+	if err := checkRenames(run); err != nil {
+		errs = append(errs, err)
+	}
+	if err := m.validator.ValidateApplied(run.appliedNames(), run.files); err != nil {
+		errs = append(errs, fmt.Errorf("migration validation failed: %w", err))
+	}
+	if err := m.verifyChecksums(ctx, run); err != nil {
+		errs = append(errs, err)
+	}
+
+	// With nothing pending no file needs to be read. Otherwise the shadow
+	// database replays every applied migration, and their directives
+	// declare required extensions, so all are loaded
+	if len(run.pending) > 0 {
+		if err := validator.LoadAll(ctx, run.files); err != nil {
+			return errors.Join(append(errs, fmt.Errorf("failed to get migration files: %w", err))...)
+		}
+	}
+
+	// An approved plan only covers the exact migrations it listed
+	if plan != nil {
+		if err := m.verifyPlan(ctx, plan, run); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	if err := m.checkOrder(run); err != nil {
+		errs = append(errs, err)
+	}
+	if err := m.checkNames(run.pending); err != nil {
+		errs = append(errs, err)
+	}
+	for _, migration := range run.pending {
+		errs = append(errs, checkDirectives(migration)...)
+	}
+
+	// Lint new migrations before they touch any database
+	if err := m.lintPending(run.pending, result); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Catch syntax errors without a database round trip
+	if m.syntaxChecker != nil && len(run.pending) > 0 {
+		if err := checkSyntax(m.syntaxChecker, run.pending); err != nil {
+			errs = append(errs, fmt.Errorf("syntax check failed: %w", err))
+		} else {
+			m.log.Infof("✓ Syntax check passed for %d new migrations", len(run.pending))
+		}
+	}
+
+	if len(errs) > 1 {
+		m.log.Warnf("Validation found %d problems", len(errs))
+	}
+	return errors.Join(errs...)
+}
+
+// checkDirectives returns the problems with the directives and data files of
+// migration, which would otherwise only fail when it is applied.
+func checkDirectives(migration *validator.MigrationFile) []error {
+	var errs []error
+	invalid := func(err error) {
+		errs = append(errs, fmt.Errorf("invalid migration %s: %w", migration.Name, err))
+	}
+
+	_, chunked, err := migration.Chunk()
+	if err != nil {
+		invalid(err)
+	}
+	copies, err := migration.Copies()
+	if err != nil {
+		invalid(err)
+	}
+	if chunked && len(copies) > 0 {
+		invalid(errors.New("chunked migrations can't load data files"))
+	}
+	if _, err := migration.Isolation(); err != nil {
+		invalid(err)
+	}
+	if _, err := migration.Metadata(); err != nil {
+		invalid(err)
+	}
+	if migration.NoTransaction() && (chunked || len(copies) > 0) {
+		invalid(errors.New("no-transaction migrations can't be chunked or load data files"))
+	}
+	if err := validator.CheckTransactionControl(migration.Content); err != nil {
+		invalid(err)
+	}
+	return errs
+}
@@ -0,0 +1,145 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// VerificationError is returned when verification queries fail after a batch
+// was applied. The migrations stay applied; the batch is marked suspect in
+// the batches table and each failure is recorded in the attempt log.
+type VerificationError struct {
+	Batch    int
+	Failures []VerificationFailure
+}
+
+// VerificationFailure is a single verification query that failed.
+type VerificationFailure struct {
+	// Migration declared the query; empty for Options.VerifyQueries.
+	Migration string
+
+	Query string
+	Err   error
+}
+
+func (e *VerificationError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		source := "run"
+		if failure.Migration != "" {
+			source = failure.Migration
+		}
+		failures[i] = fmt.Sprintf("%s: %q: %v", source, failure.Query, failure.Err)
+	}
+	return fmt.Sprintf("post-apply verification of batch %d failed (%d queries): %s",
+		e.Batch, len(e.Failures), strings.Join(failures, "; "))
+}
+
+// verifyApplied runs the verification queries of the applied migrations and
+// Options.VerifyQueries against the database.
+func (m *Migrator) verifyApplied(ctx context.Context, migrationFiles []*validator.MigrationFile, result *Result) error {
+	if len(result.Applied) == 0 {
+		return nil
+	}
+
+	applied := make(map[string]bool, len(result.Applied))
+	for _, a := range result.Applied {
+		applied[a.Name] = true
+	}
+
+	var checks []VerificationFailure
+	for _, migration := range migrationFiles {
+		if !applied[migration.Name] {
+			continue
+		}
+		for _, query := range migration.Verifications() {
+			checks = append(checks, VerificationFailure{Migration: migration.Name, Query: query})
+		}
+	}
+	for _, query := range m.verifyQueries {
+		checks = append(checks, VerificationFailure{Query: query})
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	m.progress(result, ProgressEvent{Phase: PhaseVerify, Total: len(checks)})
+
+	verifyErr := &VerificationError{Batch: result.Batch}
+	for _, check := range checks {
+		started := time.Now()
+		check.Err = m.runVerification(ctx, check.Query)
+		if check.Err == nil {
+			continue
+		}
+
+		verifyErr.Failures = append(verifyErr.Failures, check)
+		name := check.Migration
+		if name == "" {
+			name = "run"
+		}
+		attempt := tracker.Attempt{Name: name, Target: tracker.TargetVerification, StartedAt: started, FinishedAt: time.Now(),
+			Err: fmt.Errorf("%s: %w", check.Query, check.Err)}
+		if err := m.tracker.LogAttempt(context.WithoutCancel(ctx), attempt); err != nil {
+			result.warnf("%v", err)
+		}
+	}
+
+	if len(verifyErr.Failures) == 0 {
+		m.log.Infof("✓ %d verification queries passed", len(checks))
+		return nil
+	}
+
+	reason := fmt.Sprintf("%d verification queries failed", len(verifyErr.Failures))
+	if err := m.tracker.MarkBatchSuspect(context.WithoutCancel(ctx), result.Batch, reason); err != nil {
+		result.warnf("%v", err)
+	}
+
+	return verifyErr
+}
+
+// runVerification runs a verification query. It fails if the query errors,
+// returns no rows, or the first column of its first row is false or NULL, so
+// both "SELECT 1 FROM countries LIMIT 1" and "SELECT count(*) > 0 FROM
+// countries" check that countries were loaded.
+func (m *Migrator) runVerification(ctx context.Context, query string) error {
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("returned no rows")
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+
+	switch value := values[0]; value {
+	case nil:
+		return fmt.Errorf("returned NULL")
+	case false:
+		return fmt.Errorf("returned false")
+	}
+
+	return rows.Close()
+}
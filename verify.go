@@ -0,0 +1,154 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hasirciogluhq/migrator/internal/tracker"
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// ChecksumError is returned when VerifyChecksums is set and applied migration
+// files were modified after they were applied.
+type ChecksumError struct {
+	Mismatches []ChecksumMismatch
+}
+
+func (e *ChecksumError) Error() string {
+	names := make([]string, len(e.Mismatches))
+	for i, mismatch := range e.Mismatches {
+		names[i] = mismatch.Name
+	}
+	return fmt.Sprintf("%d applied migrations were modified after being applied: %s",
+		len(e.Mismatches), strings.Join(names, ", "))
+}
+
+// OutOfOrderError is returned when DisallowOutOfOrder is set and pending
+// migrations sort before the latest applied migration, e.g. after merging a
+// branch created before newer migrations were deployed.
+type OutOfOrderError struct {
+	Migrations    []string
+	LatestApplied string
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf("%d pending migrations sort before the latest applied migration %s: %s (rename them to run after it)",
+		len(e.Migrations), e.LatestApplied, strings.Join(e.Migrations, ", "))
+}
+
+// RenamedError is returned when pending migrations are applied migrations
+// whose file was renamed, e.g. compressed from x.sql to x.sql.gz. Migrations
+// are tracked by file name, so a renamed file would run a second time.
+type RenamedError struct {
+	// Renames maps the new file names to the names the migrations were
+	// applied as.
+	Renames map[string]string
+}
+
+func (e *RenamedError) Error() string {
+	names := make([]string, 0, len(e.Renames))
+	for name := range e.Renames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	renames := make([]string, len(names))
+	for i, name := range names {
+		renames[i] = fmt.Sprintf("%s (applied as %s)", name, e.Renames[name])
+	}
+	return fmt.Sprintf("%d applied migrations were renamed and would run again: %s (restore their original names)",
+		len(renames), strings.Join(renames, ", "))
+}
+
+// checksumMismatches compares applied records with the migration files.
+// Records without a checksum (applied before checksums were recorded) and
+// missing files are skipped.
+func checksumMismatches(records []tracker.Record, migrationFiles []*validator.MigrationFile) []ChecksumMismatch {
+	files := make(map[string]*validator.MigrationFile, len(migrationFiles))
+	for _, migration := range migrationFiles {
+		files[migration.Name] = migration
+	}
+
+	mismatches := []ChecksumMismatch{}
+	for _, record := range records {
+		file, ok := files[record.Name]
+		if !ok || record.Checksum == "" || file.MatchesChecksum(record.Checksum) {
+			continue
+		}
+		mismatches = append(mismatches, ChecksumMismatch{
+			Name:     record.Name,
+			Recorded: record.Checksum,
+			Current:  file.Checksum(),
+		})
+	}
+
+	return mismatches
+}
+
+// verifyChecksums fails when applied migration files were modified.
+func (m *Migrator) verifyChecksums(ctx context.Context, run *runState) error {
+	if !m.verifyChecksumsEnabled {
+		return nil
+	}
+
+	if err := validator.LoadAll(ctx, run.files); err != nil {
+		return fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	if mismatches := checksumMismatches(run.records, run.files); len(mismatches) > 0 {
+		return &ChecksumError{Mismatches: mismatches}
+	}
+
+	m.log.Infof("✓ Checksums verified for %d applied migrations", len(run.records))
+	return nil
+}
+
+// checkRenames fails when pending migrations only differ from applied ones by
+// their extension.
+func checkRenames(run *runState) error {
+	applied := make(map[string]string, len(run.applied))
+	for name := range run.applied {
+		applied[validator.TrimExtension(name)] = name
+	}
+
+	renames := map[string]string{}
+	for _, migration := range run.pending {
+		if name, ok := applied[validator.TrimExtension(migration.Name)]; ok {
+			renames[migration.Name] = name
+		}
+	}
+
+	if len(renames) > 0 {
+		return &RenamedError{Renames: renames}
+	}
+	return nil
+}
+
+// checkOrder fails when pending migrations would run out of order.
+func (m *Migrator) checkOrder(run *runState) error {
+	if !m.disallowOutOfOrder || len(run.pending) == 0 {
+		return nil
+	}
+
+	// Files are applied in name order, so the latest applied is the largest name
+	latest := ""
+	for _, record := range run.records {
+		if record.Name > latest {
+			latest = record.Name
+		}
+	}
+
+	var outOfOrder []string
+	for _, migration := range run.pending {
+		if migration.Name < latest {
+			outOfOrder = append(outOfOrder, migration.Name)
+		}
+	}
+
+	if len(outOfOrder) > 0 {
+		return &OutOfOrderError{Migrations: outOfOrder, LatestApplied: latest}
+	}
+	return nil
+}
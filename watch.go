@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hasirciogluhq/migrator/internal/validator"
+)
+
+// Watch applies migrations as they are saved, for local development. It
+// migrates once at start, then checks the migrations directory every
+// Options.WatchInterval and runs Migrate, shadow testing included, whenever
+// the files changed and stayed unchanged for a whole interval, so
+// half-written files are left alone while the editor saves.
+//
+// A failing run is logged and retried once the files change again. Applied
+// migrations are never re-applied: editing one only logs a warning, since
+// the change needs a new migration. Watch returns nil when ctx is
+// cancelled:
+//
+//	ctx, stop := migrator.SignalContext(context.Background())
+//	defer stop()
+//	m.Watch(ctx)
+//
+// It is meant for a developer's own database, not for deployments.
+func (m *Migrator) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(m.watchInterval)
+	defer ticker.Stop()
+
+	m.log.Infof("⏳ Watching %s for migration changes...", m.migrationsPath)
+
+	var applied, previous, lastErr string
+	for started := false; ; started = true {
+		fingerprint, err := m.watchFingerprint(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// Reported once until it changes, not on every tick
+			if err.Error() != lastErr {
+				m.log.Warnf("Could not read migrations: %v", err)
+			}
+			lastErr = err.Error()
+		} else {
+			lastErr = ""
+			if !started || (fingerprint != applied && fingerprint == previous) {
+				applied = fingerprint
+				m.watchMigrate(ctx)
+			}
+		}
+		previous = fingerprint
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchFingerprint returns a string that changes whenever a migration file
+// is added, removed or edited.
+func (m *Migrator) watchFingerprint(ctx context.Context) (string, error) {
+	files, err := m.validator.ListMigrationFiles(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := validator.LoadAll(ctx, files); err != nil {
+		return "", err
+	}
+
+	var fingerprint strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&fingerprint, "%s %s\n", file.Name, file.Checksum())
+	}
+	return fingerprint.String(), nil
+}
+
+// watchMigrate runs Migrate for Watch, logging the outcome instead of
+// returning it.
+func (m *Migrator) watchMigrate(ctx context.Context) {
+	result, err := m.MigrateWithResult(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		m.log.Warnf("Migration failed, fix it and save again: %v", err)
+		return
+	}
+	if len(result.Applied) > 0 {
+		m.log.Infof("✅ Applied %s", strings.Join(result.AppliedNames(), ", "))
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		m.log.Debugf("  Could not check for modified migrations: %v", err)
+		return
+	}
+	for _, mismatch := range status.ChecksumMismatches {
+		m.log.Warnf("%s was modified after being applied; the change only takes effect in a new migration", mismatch.Name)
+	}
+	m.log.Infof("⏳ Waiting for changes...")
+}
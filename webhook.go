@@ -0,0 +1,140 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier POSTs each event as a JSON document to a URL.
+//
+// When Secret is set, the request carries an X-Migrator-Signature header of the
+// form "sha256=<hex>" containing the HMAC-SHA256 of the body, so receivers can
+// verify the payload came from the migrator.
+type WebhookNotifier struct {
+	// URL is the endpoint receiving the events.
+	URL string
+
+	// Secret is the HMAC key used to sign payloads. Optional.
+	Secret string
+
+	// MaxRetries is how many times a failed delivery is retried.
+	// Network errors, 429 and 5xx responses are retried. Defaults to 3; a
+	// negative value disables retries.
+	MaxRetries int
+
+	// RetryDelay is the initial delay between retries, doubled after each attempt.
+	// Defaults to 1 second.
+	RetryDelay time.Duration
+
+	// Client is the HTTP client used for delivery. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier for the given URL and signing secret.
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Secret: secret,
+	}
+}
+
+// Notify implements Notifier.
+func (w *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	return postWithRetry(ctx, w.client(), w.maxRetries(), w.retryDelay(), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Migrator-Event", string(event.Type))
+		if w.Secret != "" {
+			req.Header.Set("X-Migrator-Signature", "sha256="+signPayload(w.Secret, body))
+		}
+		return req, nil
+	})
+}
+
+func (w *WebhookNotifier) client() *http.Client {
+	if w.Client != nil {
+		return w.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (w *WebhookNotifier) maxRetries() int {
+	switch {
+	case w.MaxRetries < 0:
+		return 0
+	case w.MaxRetries > 0:
+		return w.MaxRetries
+	}
+	return 3
+}
+
+func (w *WebhookNotifier) retryDelay() time.Duration {
+	if w.RetryDelay > 0 {
+		return w.RetryDelay
+	}
+	return time.Second
+}
+
+// signPayload returns the hex-encoded HMAC-SHA256 of body using secret.
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWithRetry sends the request built by newRequest, retrying transient failures
+// with exponential backoff.
+func postWithRetry(ctx context.Context, client *http.Client, maxRetries int, delay time.Duration, newRequest func() (*http.Request, error)) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("delivery cancelled after %d attempts: %w", attempt, lastErr)
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return fmt.Errorf("failed to build request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+
+		lastErr = fmt.Errorf("unexpected response status %s", resp.Status)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return lastErr
+		}
+	}
+
+	return fmt.Errorf("delivery failed after %d attempts: %w", maxRetries+1, lastErr)
+}